@@ -0,0 +1,89 @@
+package lexgo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Accept checks if the next rune in the stream is one of the runes in valid.
+// If it is the rune is read and buffered, and true is returned. Otherwise the
+// stream is left as it was and false is returned.
+//
+// If an error is encountered false is returned, and the error will be returned
+// (and Emit()'d) by the next call to ReadRune() or PeekRune()
+func (l *Lexer) Accept(valid string) bool {
+	r, err := l.peekRune()
+	if err != nil || !strings.ContainsRune(valid, r) {
+		return false
+	}
+	l.ReadRune()
+	l.BufferRune(r)
+	return true
+}
+
+// AcceptRun reads and buffers runes for as long as they are in valid, and
+// returns the number of runes accepted. Follows the same error semantics as
+// Accept()
+func (l *Lexer) AcceptRun(valid string) int {
+	var n int
+	for l.Accept(valid) {
+		n++
+	}
+	return n
+}
+
+// AcceptWhile reads and buffers runes for as long as pred returns true for
+// them, and returns the number of runes accepted. Follows the same error
+// semantics as Accept(), so anything buffered can still be Emit()'d before the
+// error (including io.EOF) is
+func (l *Lexer) AcceptWhile(pred func(rune) bool) int {
+	var n int
+	for {
+		r, err := l.peekRune()
+		if err != nil || !pred(r) {
+			return n
+		}
+		l.ReadRune()
+		l.BufferRune(r)
+		n++
+	}
+}
+
+// TryPeekRune is like PeekRune, except that an error encountered is not
+// emitted straight away. Instead false is returned, and the error will be
+// returned (and Emit()'d) by the next call to ReadRune() or PeekRune(). This
+// allows a LexerFunc to Emit() whatever it has buffered before the error
+// Token, e.g. when reaching the end of the stream mid-Token
+func (l *Lexer) TryPeekRune() (rune, bool) {
+	r, err := l.peekRune()
+	return r, err == nil
+}
+
+// ReadN reads and buffers exactly n runes (bytes, in BinaryMode), returning the
+// number actually read. This is useful for formats where a textual header
+// dictates the length of the payload which follows, e.g. netstrings ("3:foo,")
+// or RESP bulk strings.
+//
+// If the stream ends before n runes have been read then io.ErrUnexpectedEOF is
+// emitted (wrapped in a TemplateError if WithStateErrorTemplate applies) and
+// returned, otherwise errors follow the same semantics as ReadRune().
+func (l *Lexer) ReadN(n int) (int, error) {
+	for i := 0; i < n; i++ {
+		if _, err := l.peekRune(); err == io.EOF {
+			l.heldErr = nil
+			expected := fmt.Sprintf("%d more runes", n-i)
+			if l.binary {
+				expected = fmt.Sprintf("%d more bytes", n-i)
+			}
+			l.EmitErr(l.templated(nil, expected, "end of input", io.ErrUnexpectedEOF))
+			return i, io.ErrUnexpectedEOF
+		}
+		r, _, err := l.ReadRune()
+		if err != nil {
+			return i, err
+		}
+		l.BufferRune(r)
+	}
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package lexgo
+
+import (
+	"unsafe"
+)
+
+// arenaChunkSize is the size of each block of memory an Arena allocates values
+// out of, and arenaSlabSize is the number of Tokens it allocates at a time
+const (
+	arenaChunkSize = 64 * 1024
+	arenaSlabSize  = 1024
+)
+
+// Arena is a region of memory out of which a Lexer can allocate all of its
+// Tokens and their values, so that they can all be freed in a single step once
+// they're no longer needed. This can greatly reduce garbage collector pressure
+// when lexing a large number of inputs, each of whose Tokens is thrown away
+// after that input has been parsed.
+//
+// An Arena is not safe to use from multiple go-routines at once, but may be
+// shared by multiple Lexers used one after the other.
+type Arena struct {
+	chunks [][]byte
+	slabs  [][]Token
+
+	// index of the chunk/slab currently being allocated from
+	chunk, slab int
+}
+
+// NewArena returns a new, empty Arena
+func NewArena() *Arena {
+	return new(Arena)
+}
+
+// WithArena causes the Lexer to allocate the values of the Tokens it emits, as
+// well as the Tokens returned from Next(), out of the given Arena. Tokens and
+// values allocated this way must not be used after the Arena's Free or Reset
+// methods are called. Any which need to be kept around longer should be copied
+// out, e.g. using strings.Clone on the value.
+//
+// Values which are transformed by NormalizeValues or FoldValues are not
+// allocated from the Arena.
+func WithArena(a *Arena) Option {
+	return func(l *Lexer) {
+		l.arena = a
+	}
+}
+
+// string returns a string with the contents of b, allocated from the Arena
+func (a *Arena) string(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	} else if len(b) > arenaChunkSize/4 {
+		// large values get their own allocation, so as not to waste the
+		// remainder of a chunk
+		return string(b)
+	}
+
+	if len(a.chunks) == 0 {
+		a.chunks = append(a.chunks, make([]byte, 0, arenaChunkSize))
+	}
+	if c := a.chunks[a.chunk]; cap(c)-len(c) < len(b) {
+		if a.chunk++; a.chunk == len(a.chunks) {
+			a.chunks = append(a.chunks, make([]byte, 0, arenaChunkSize))
+		}
+	}
+
+	c := a.chunks[a.chunk]
+	start := len(c)
+	c = append(c, b...)
+	a.chunks[a.chunk] = c
+	return unsafe.String(&c[start], len(b))
+}
+
+// token returns a pointer to a Token with the given value, allocated from the
+// Arena
+func (a *Arena) token(t Token) *Token {
+	if len(a.slabs) == 0 {
+		a.slabs = append(a.slabs, make([]Token, 0, arenaSlabSize))
+	}
+	if s := a.slabs[a.slab]; len(s) == cap(s) {
+		if a.slab++; a.slab == len(a.slabs) {
+			a.slabs = append(a.slabs, make([]Token, 0, arenaSlabSize))
+		}
+	}
+
+	s := append(a.slabs[a.slab], t)
+	a.slabs[a.slab] = s
+	return &s[len(s)-1]
+}
+
+// Reset makes all memory in the Arena available to be allocated from again,
+// without freeing it. Any Tokens or values previously allocated from the Arena
+// must not be used after Reset is called, as their memory will be overwritten
+func (a *Arena) Reset() {
+	for i := range a.chunks {
+		a.chunks[i] = a.chunks[i][:0]
+	}
+	for i := range a.slabs {
+		clear(a.slabs[i])
+		a.slabs[i] = a.slabs[i][:0]
+	}
+	a.chunk, a.slab = 0, 0
+}
+
+// Free releases all memory held by the Arena to the garbage collector in one
+// step. The Arena may continue to be used afterwards, but any Tokens or values
+// previously allocated from it must not be
+func (a *Arena) Free() {
+	*a = Arena{}
+}
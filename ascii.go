@@ -0,0 +1,52 @@
+package lexgo
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// WithASCIIFastPath causes the Lexer to read the stream a byte at a time, only
+// falling back to full utf8 decoding when a byte which isn't ASCII (>= 0x80) is
+// encountered. This substantially speeds up lexing of sources which are mostly
+// ASCII, such as program code and logs, and has no effect on the runes
+// returned. It has no effect if the io.Reader given to NewLexer is an
+// io.RuneScanner which isn't also an io.ByteScanner.
+func WithASCIIFastPath() Option {
+	return func(l *Lexer) {
+		l.ascii = true
+	}
+}
+
+// AcceptByte is like Accept, except that valid must consist only of ASCII
+// characters. The check is done on the next byte in the stream, without any
+// utf8 decoding, which makes it cheaper than Accept for ASCII-only character
+// sets. In BinaryMode valid may contain any bytes.
+func (l *Lexer) AcceptByte(valid string) bool {
+	l.commitRead()
+	if l.heldErr != nil {
+		return false
+	} else if l.bs == nil || l.cont != "" || l.ahead.n > 0 {
+		return l.Accept(valid)
+	}
+	b, err := l.bs.ReadByte()
+	if err != nil {
+		l.heldErr = err
+		return false
+	} else if (b >= utf8.RuneSelf && !l.binary) || strings.IndexByte(valid, b) < 0 {
+		l.bs.UnreadByte()
+		return false
+	}
+	l.lastByte = true
+	l.advance(rune(b), 1)
+	l.BufferRune(rune(b))
+	return true
+}
+
+// AcceptByteRun is like AcceptRun, but with the semantics of AcceptByte
+func (l *Lexer) AcceptByteRun(valid string) int {
+	var n int
+	for l.AcceptByte(valid) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,187 @@
+//go:build !tinygo
+
+package lexgo
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrTimeout is emitted as an Err Token when a read from the underlying
+// io.Reader takes longer than the duration given to WithReadTimeout
+var ErrTimeout = errors.New("read timed out")
+
+// WithReadTimeout causes the Lexer to give up on a read from the underlying
+// io.Reader if it hasn't returned within the given duration. When this
+// happens ErrTimeout is returned from ReadRune() (and Emit()'d), as with any
+// other read error. This is important when lexing interactive or network input,
+// where a stalled peer would otherwise cause Next() to block forever.
+//
+// The read which timed out is not abandoned, its data will be returned to the
+// next read the Lexer does, so a LexerFunc may continue on after an ErrTimeout
+// if it so chooses. A timeout is never returned part way through a multi-byte
+// rune, the Lexer instead continues waiting for the rest of it, so that a
+// stall in the middle of a rune isn't mistaken for invalid utf8.
+//
+// If the Lexer is abandoned before its stream ends then Stop should be called,
+// so that the go-routine reading from the io.Reader can exit.
+//
+// Since the underlying io.Reader is read from in a separate go-routine when
+// using this option, the io.Reader given to NewLexer will not be used directly
+// even if it's an io.RuneScanner.
+func WithReadTimeout(d time.Duration) Option {
+	return func(l *Lexer) {
+		l.readTimeout = d
+	}
+}
+
+// WithIdleFunc causes the given function to be called whenever the Lexer has
+// been waiting on the underlying io.Reader for input for the given duration,
+// and again each time that duration passes while it continues waiting. This is
+// useful for REPLs and other interactive frontends, which may want to prompt
+// the user, flush partial state, or keep connections alive when input stalls.
+//
+// fn is called from within Next(), on the same go-routine, and so must not call
+// any methods on the Lexer itself. As with WithReadTimeout, the io.Reader given
+// to NewLexer will not be used directly when using this option.
+func WithIdleFunc(d time.Duration, fn func()) Option {
+	return func(l *Lexer) {
+		l.idle, l.idleFn = d, fn
+	}
+}
+
+type readResult struct {
+	b   []byte
+	err error
+}
+
+// asyncReader performs the reads on its inner io.Reader in a separate
+// go-routine, so that a Read on it can give up waiting after a timeout, or do
+// other things while it waits
+type asyncReader struct {
+	r       io.Reader
+	timeout time.Duration
+	idle    time.Duration
+	idleFn  func()
+
+	startOnce sync.Once
+	ch        chan readResult
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+
+	// what's left over of the last readResult, if the Read it was for didn't
+	// have room for it all
+	rest    []byte
+	restErr error
+}
+
+func newAsyncReader(r io.Reader, l *Lexer) *asyncReader {
+	return &asyncReader{
+		r:       r,
+		timeout: l.readTimeout,
+		idle:    l.idle,
+		idleFn:  l.idleFn,
+		ch:      make(chan readResult),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// stop causes spin to exit the next time it would send a readResult. It's safe
+// to call on a nil asyncReader
+func (tr *asyncReader) stop() {
+	if tr == nil {
+		return
+	}
+	tr.stopOnce.Do(func() { close(tr.stopCh) })
+}
+
+func (tr *asyncReader) spin() {
+	for {
+		b := make([]byte, 4096)
+		n, err := tr.r.Read(b)
+		select {
+		case tr.ch <- readResult{b: b[:n], err: err}:
+		case <-tr.stopCh:
+			return
+		}
+		if err != nil {
+			close(tr.ch)
+			return
+		}
+	}
+}
+
+func (tr *asyncReader) Read(p []byte) (int, error) {
+	tr.startOnce.Do(func() { go tr.spin() })
+
+	avail := tr.avail()
+	for avail == 0 && tr.restErr == nil {
+		if err := tr.wait(); err != nil {
+			return 0, err
+		}
+		avail = tr.avail()
+	}
+
+	n := copy(p, tr.rest[:avail])
+	tr.rest = tr.rest[n:]
+	if len(tr.rest) == 0 && tr.restErr != nil {
+		err := tr.restErr
+		tr.restErr = nil
+		return n, err
+	}
+	return n, nil
+}
+
+// avail returns how many bytes of rest may be returned from Read. Trailing
+// bytes which only make up part of a rune are held back until the rest of the
+// rune has been read, unless the read ended with an error
+func (tr *asyncReader) avail() int {
+	if tr.restErr != nil {
+		return len(tr.rest)
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(tr.rest); i++ {
+		tail := tr.rest[len(tr.rest)-i:]
+		if !utf8.RuneStart(tail[0]) {
+			continue
+		} else if !utf8.FullRune(tail) {
+			return len(tr.rest) - i
+		}
+		break
+	}
+	return len(tr.rest)
+}
+
+// wait blocks until the next readResult is available and adds it to rest
+func (tr *asyncReader) wait() error {
+	var timeoutCh, idleCh <-chan time.Time
+	if tr.timeout > 0 {
+		timer := time.NewTimer(tr.timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+	if tr.idle > 0 {
+		ticker := time.NewTicker(tr.idle)
+		defer ticker.Stop()
+		idleCh = ticker.C
+	}
+
+	for {
+		select {
+		case res, ok := <-tr.ch:
+			if !ok {
+				return io.EOF
+			}
+			tr.rest, tr.restErr = append(tr.rest, res.b...), res.err
+			return nil
+		case <-tr.stopCh:
+			return io.EOF
+		case <-timeoutCh:
+			return ErrTimeout
+		case <-idleCh:
+			tr.idleFn()
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build tinygo
+
+package lexgo
+
+import "io"
+
+// asyncReader needs a go-routine, so isn't available under TinyGo. Neither are
+// WithReadTimeout and WithIdleFunc, which use it, so one is never created.
+type asyncReader struct {
+	io.Reader
+}
+
+func newAsyncReader(r io.Reader, l *Lexer) *asyncReader {
+	return &asyncReader{Reader: r}
+}
+
+func (tr *asyncReader) stop() {}
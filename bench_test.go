@@ -0,0 +1,94 @@
+package lexgo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// benchInput is a few KB of space separated words, representative of the
+// sort of input most lexers spend their time on
+var benchInput = strings.Repeat("foo bar_baz 1234 héllo wörld ", 200)
+
+// lexBenchWords emits each run of non-space characters as a Token, discarding
+// the spaces between them
+func lexBenchWords(l *Lexer) LexerFunc {
+	for {
+		r, _, err := l.ReadRune()
+		if err != nil {
+			return nil
+		} else if unicode.IsSpace(r) {
+			if len(l.outbuf) > 0 {
+				l.Emit(UserDefined)
+				return lexBenchWords
+			}
+			continue
+		}
+		l.BufferRune(r)
+	}
+}
+
+func BenchmarkEmit(b *testing.B) {
+	b.SetBytes(int64(len(benchInput)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(strings.NewReader(benchInput), lexBenchWords)
+		for {
+			tok := l.NextToken()
+			if tok.Err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkBufferRune(b *testing.B) {
+	l := NewLexer(strings.NewReader(""), nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, r := range "héllo" {
+			l.BufferRune(r)
+		}
+		l.resetBuffer()
+	}
+}
+
+// The following two benchmarks compare the approach Lexer used to take for
+// accumulating Token data, using a bytes.Buffer, against the reused []byte it
+// uses now
+
+var benchSink string
+
+func BenchmarkAccumulateBytesBuffer(b *testing.B) {
+	var buf bytes.Buffer
+	b.SetBytes(int64(len(benchInput)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, r := range benchInput {
+			if r != ' ' {
+				buf.WriteRune(r)
+				continue
+			}
+			benchSink = buf.String()
+			buf.Reset()
+		}
+	}
+}
+
+func BenchmarkAccumulateByteSlice(b *testing.B) {
+	var buf []byte
+	b.SetBytes(int64(len(benchInput)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, r := range benchInput {
+			if r != ' ' {
+				buf = utf8.AppendRune(buf, r)
+				continue
+			}
+			benchSink = string(buf)
+			buf = buf[:0]
+		}
+	}
+}
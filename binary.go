@@ -0,0 +1,61 @@
+package lexgo
+
+import (
+	"errors"
+)
+
+// ErrNotBinary is returned by the byte-oriented reading methods (ReadByte,
+// PeekByte, etc...) when the Lexer isn't in BinaryMode
+var ErrNotBinary = errors.New("lexgo: byte-oriented read used outside of BinaryMode")
+
+// BinaryMode causes the Lexer to work on raw bytes rather than utf8 characters.
+// Every byte is read as its own rune, with a value from 0 to 255, and no utf8
+// validation is done, so formats which mix text with arbitrary binary data
+// (protocol frames, some log formats, etc...) can be lexed without tripping
+// over ErrInvalidUTF8. Columns are counted in bytes.
+//
+// Runes in the range 0 to 255 given to BufferRune are buffered as a single
+// byte, so the Val of each Token is the raw bytes which were read. The
+// byte-oriented methods (ReadByte, UnreadByte, PeekByte and BufferByte) may
+// be used in place of their rune counterparts for clarity.
+func BinaryMode() Option {
+	return func(l *Lexer) {
+		l.binary = true
+	}
+}
+
+// ReadByte is the BinaryMode equivalent of ReadRune, returning the next byte in
+// the stream. Error semantics are the same as for ReadRune. If the Lexer isn't
+// in BinaryMode ErrNotBinary is returned.
+//
+// Along with UnreadByte this makes Lexer an io.ByteScanner.
+func (l *Lexer) ReadByte() (byte, error) {
+	if !l.binary {
+		return 0, ErrNotBinary
+	}
+	r, _, err := l.ReadRune()
+	return byte(r), err
+}
+
+// UnreadByte is the BinaryMode equivalent of UnreadRune
+func (l *Lexer) UnreadByte() error {
+	if !l.binary {
+		return ErrNotBinary
+	}
+	return l.UnreadRune()
+}
+
+// PeekByte is the BinaryMode equivalent of PeekRune
+func (l *Lexer) PeekByte() (byte, error) {
+	if !l.binary {
+		return 0, ErrNotBinary
+	}
+	r, err := l.PeekRune()
+	return byte(r), err
+}
+
+// BufferByte appends the given byte to the output buffer. It is intended for
+// BinaryMode, outside of which it is equivalent to BufferRune(rune(b)).
+func (l *Lexer) BufferByte(b byte) {
+	l.BufferRune(rune(b))
+}
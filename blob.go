@@ -0,0 +1,65 @@
+package lexgo
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const hexDigits = "0123456789abcdefABCDEF"
+
+// The alphabets used by AcceptBase64, not including padding
+const (
+	Base64StdAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	Base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
+// AcceptHex reads and buffers a run of hex digits, of either case, and returns
+// the number of digits accepted. Follows the same error semantics as Accept()
+func (l *Lexer) AcceptHex() int {
+	return l.AcceptByteRun(hexDigits)
+}
+
+// AcceptBase64 reads and buffers a run of characters from the given base64
+// alphabet (e.g. Base64StdAlphabet), followed by as many '=' padding
+// characters as are needed to make the total length a multiple of four. It
+// returns the number of characters accepted, including padding. Padding is
+// only accepted where it's valid, so a '=' following a run which doesn't need
+// it is left unread. Follows the same error semantics as Accept()
+func (l *Lexer) AcceptBase64(alphabet string) int {
+	n := l.AcceptByteRun(alphabet)
+	if n == 0 {
+		return 0
+	}
+	if pad := (4 - n%4) % 4; pad <= 2 {
+		for ; pad > 0 && l.AcceptByte("="); pad-- {
+			n++
+		}
+	}
+	return n
+}
+
+// EmitHex is like Emit, except that the buffered data is first decoded as hex,
+// with the decoded bytes being set as the Token's Meta field. If the data
+// isn't valid hex then an error is emitted instead, positioned at the start of
+// the Token.
+func (l *Lexer) EmitHex(t TokenType) {
+	b, err := hex.DecodeString(string(l.outbuf))
+	l.emitBlob(t, b, err)
+}
+
+// EmitBase64 is like EmitHex, but decodes the buffered data using the given
+// base64 encoding, e.g. base64.StdEncoding
+func (l *Lexer) EmitBase64(t TokenType, enc *base64.Encoding) {
+	b, err := enc.DecodeString(string(l.outbuf))
+	l.emitBlob(t, b, err)
+}
+
+func (l *Lexer) emitBlob(t TokenType, b []byte, err error) {
+	if err != nil {
+		row, col := l.reportPos(l.row, l.col)
+		l.EmitErr(&PosError{Row: row, Col: col, Offset: l.off, Err: err})
+		l.resetBuffer()
+		return
+	}
+	l.emit(t, b)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"unicode"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// TokenTypes produced by the built-in lexers
+const (
+	Word lexgo.TokenType = lexgo.UserDefined + iota
+	Number
+	String
+	Punct
+	Space
+	Newline
+)
+
+var typeNames = map[lexgo.TokenType]string{
+	Word:    "Word",
+	Number:  "Number",
+	String:  "String",
+	Punct:   "Punct",
+	Space:   "Space",
+	Newline: "Newline",
+}
+
+func typeName(t lexgo.TokenType) string {
+	return typeNames[t]
+}
+
+// The built-in lexers are registered alongside any imported ones, so they can
+// all be selected using the -lexer flag
+func init() {
+	lexgo.Register(lexgo.Registration{
+		Name: "generic",
+		New: func(r io.Reader) lexgo.Tokenizer {
+			return lexgo.NewLexer(r, lexGeneric)
+		},
+		TypeName: typeName,
+	})
+	lexgo.Register(lexgo.Registration{
+		Name: "fields",
+		New: func(r io.Reader) lexgo.Tokenizer {
+			return lexgo.NewLexer(r, lexFields)
+		},
+		TypeName: typeName,
+	})
+}
+
+// lexGeneric is a lexer which does a reasonable job of splitting up most
+// programming languages and text formats: words, numbers, double or single
+// quoted strings, runs of whitespace, newlines and individual punctuation
+func lexGeneric(l *lexgo.Lexer) lexgo.LexerFunc {
+	r, _, err := l.ReadRune()
+	if err != nil {
+		return nil
+	}
+	l.BufferRune(r)
+
+	switch {
+	case l.IsNewline(r):
+		l.Emit(Newline)
+	case unicode.IsSpace(r):
+		l.AcceptWhile(func(r rune) bool { return unicode.IsSpace(r) && !l.IsNewline(r) })
+		l.Emit(Space)
+	case unicode.IsDigit(r):
+		l.AcceptWhile(func(r rune) bool { return r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) })
+		l.Emit(Number)
+	case r == '_' || unicode.IsLetter(r):
+		l.AcceptWhile(func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) })
+		l.Emit(Word)
+	case r == '"' || r == '\'':
+		return lexString(r)
+	default:
+		l.Emit(Punct)
+	}
+	return lexGeneric
+}
+
+// lexString returns a LexerFunc which reads the rest of a string delimited by
+// the given quote, whose opening quote has already been buffered. Backslash
+// escapes the following rune. A string which isn't terminated by the end of
+// its line is emitted as is.
+func lexString(quote rune) lexgo.LexerFunc {
+	return func(l *lexgo.Lexer) lexgo.LexerFunc {
+		for escaped := false; ; {
+			r, ok := l.TryPeekRune()
+			if !ok || (l.IsNewline(r) && !escaped) {
+				l.Emit(String)
+				return lexGeneric
+			}
+			l.ReadRune()
+			l.BufferRune(r)
+			if r == quote && !escaped {
+				l.Emit(String)
+				return lexGeneric
+			}
+			escaped = r == '\\' && !escaped
+		}
+	}
+}
+
+// lexFields is a lexer which splits the input into whitespace separated fields,
+// discarding the whitespace
+func lexFields(l *lexgo.Lexer) lexgo.LexerFunc {
+	l.SkipWhitespace()
+	if _, err := l.PeekRune(); err != nil {
+		return nil
+	}
+	l.AcceptWhile(func(r rune) bool { return !unicode.IsSpace(r) })
+	l.Emit(Word)
+	return lexFields
+}
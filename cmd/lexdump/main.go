@@ -0,0 +1,249 @@
+// Command lexdump lexes files using one of lexgo's registered lexers and prints
+// the resulting Token streams, one Token per line. It is intended as a
+// debugging aid when writing lexers, and as an example of a tool built on
+// lexgo.
+//
+// Usage:
+//
+//	lexdump [-lexer NAME] [-stats] FILE
+//	lexdump diff [-lexer NAME] [-lexer2 NAME] [-positions] FILE [FILE2]
+//	lexdump serve [-addr ADDR]
+//
+// If -lexer isn't given the lexer is chosen based on the file's name, using
+// the generic lexer if no registered lexer handles it. Files compressed using
+// gzip, zstd or bzip2 are decompressed first, see lexcompress. With -stats a
+// table of statistics about the file's Tokens is printed instead of the Tokens
+// themselves.
+//
+// The diff subcommand lexes two files, or one file using two different lexers,
+// and prints an aligned, Token-level diff of the two streams.
+//
+// The serve subcommand serves lexhttp's Visualizer, for interactively
+// lexing input in a browser using any of the available lexers.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mediocregopher/lexgo"
+	"github.com/mediocregopher/lexgo/lexcompress"
+	_ "github.com/mediocregopher/lexgo/lexers/markdown"
+	"github.com/mediocregopher/lexgo/lexhttp"
+)
+
+func usage() {
+	var names []string
+	for _, reg := range lexgo.Registered() {
+		names = append(names, reg.Name)
+	}
+	fmt.Fprintf(os.Stderr, `Usage:
+	lexdump [-lexer NAME] [-stats] FILE
+	lexdump diff [-lexer NAME] [-lexer2 NAME] [-positions] FILE [FILE2]
+	lexdump serve [-addr ADDR]
+
+Available lexers: %s
+`, strings.Join(names, ", "))
+	os.Exit(2)
+}
+
+func main() {
+	args := os.Args[1:]
+	var err error
+	if len(args) > 0 && args[0] == "diff" {
+		err = diffCmd(args[1:])
+	} else if len(args) > 0 && args[0] == "serve" {
+		err = serveCmd(args[1:])
+	} else {
+		err = dumpCmd(args)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lexdump: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// getLexer returns the registered lexer with the given name or, if name is
+// empty, the one for the given file
+func getLexer(name, path string) lexgo.Registration {
+	if name == "" {
+		if reg, ok := lexgo.LookupFilename(lexcompress.TrimExt(path)); ok {
+			return reg
+		}
+		name = "generic"
+	}
+	reg, ok := lexgo.Lookup(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "lexdump: unknown lexer %q\n", name)
+		usage()
+	}
+	return reg
+}
+
+// lexFile returns all Tokens lexed from the given file, including any
+// Recoverable Err Tokens and the final Err Token if it isn't io.EOF
+func lexFile(path string, newFn func(io.Reader) lexgo.Tokenizer) ([]lexgo.Token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := lexcompress.Wrap(newFn)(f)
+	var toks []lexgo.Token
+	for {
+		tok := t.Next()
+		if tok.Err != io.EOF {
+			toks = append(toks, tok.Copy())
+		}
+		done := tok.EndsStream()
+		tok.Release()
+		if done {
+			return toks, nil
+		}
+	}
+}
+
+// formatToken renders the Token as its type and value, and optionally its
+// position
+func formatToken(tok *lexgo.Token, reg lexgo.Registration, positions bool) string {
+	var name string
+	switch {
+	case tok.TokenType == lexgo.Err:
+		name = "error"
+	case tok.TokenType == lexgo.Warning:
+		name = "warning"
+	case reg.TypeName != nil:
+		name = reg.TypeName(tok.TokenType)
+	}
+	if name == "" {
+		name = fmt.Sprint(int(tok.TokenType))
+	}
+	val := tok.Val
+	if tok.Err != nil {
+		val = tok.Err.Error()
+	} else if tok.Warn != nil {
+		val = tok.Warn.Error()
+	}
+	s := fmt.Sprintf("%s %q", name, val)
+	if positions {
+		s = fmt.Sprintf("%d:%d %s", tok.Row, tok.Col, s)
+	}
+	return s
+}
+
+func dumpCmd(args []string) error {
+	fs := flag.NewFlagSet("lexdump", flag.ExitOnError)
+	fs.Usage = usage
+	lexer := fs.String("lexer", "", "lexer to use (default based on the file name)")
+	stats := fs.Bool("stats", false, "print statistics about the Tokens rather than the Tokens")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	reg := getLexer(*lexer, fs.Arg(0))
+	if *stats {
+		return statsCmd(fs.Arg(0), reg)
+	}
+	toks, err := lexFile(fs.Arg(0), reg.New)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for i := range toks {
+		fmt.Fprintln(w, formatToken(&toks[i], reg, true))
+	}
+	return w.Flush()
+}
+
+func statsCmd(path string, reg lexgo.Registration) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var s lexgo.Stats
+	s.Collect(lexcompress.Wrap(reg.New)(f))
+	return s.WriteTable(os.Stdout, reg.TypeName)
+}
+
+func diffCmd(args []string) error {
+	fs := flag.NewFlagSet("lexdump diff", flag.ExitOnError)
+	fs.Usage = usage
+	lexer := fs.String("lexer", "", "lexer to use for the first file (default based on the file name)")
+	lexer2 := fs.String("lexer2", "", "lexer to use for the second file (default the same as -lexer)")
+	positions := fs.Bool("positions", false, "consider Token positions when comparing")
+	fs.Parse(args)
+
+	pathA, pathB := fs.Arg(0), fs.Arg(1)
+	switch fs.NArg() {
+	case 1:
+		pathB = pathA
+	case 2:
+	default:
+		usage()
+	}
+	regA := getLexer(*lexer, pathA)
+	regB := regA
+	if *lexer2 != "" {
+		regB = getLexer(*lexer2, pathB)
+	}
+
+	a, err := lexFile(pathA, regA.New)
+	if err != nil {
+		return err
+	}
+	b, err := lexFile(pathB, regB.New)
+	if err != nil {
+		return err
+	}
+
+	edits := lexgo.DiffTokens(a, b, lexgo.DiffOptions{IgnorePositions: !*positions})
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(w, "--- %s (%s)\n+++ %s (%s)\n", pathA, regA.Name, pathB, regB.Name)
+	var changed bool
+	for _, e := range edits {
+		switch e.Op {
+		case lexgo.DiffEqual:
+			fmt.Fprintf(w, "  %-8s %s\n", pos(&a[e.A]), formatToken(&a[e.A], regA, *positions))
+		case lexgo.DiffDelete, lexgo.DiffChange:
+			fmt.Fprintf(w, "- %-8s %s\n", pos(&a[e.A]), formatToken(&a[e.A], regA, *positions))
+		}
+		switch e.Op {
+		case lexgo.DiffInsert, lexgo.DiffChange:
+			fmt.Fprintf(w, "+ %-8s %s\n", pos(&b[e.B]), formatToken(&b[e.B], regB, *positions))
+		}
+		changed = changed || e.Op != lexgo.DiffEqual
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	} else if changed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func serveCmd(args []string) error {
+	fs := flag.NewFlagSet("lexdump serve", flag.ExitOnError)
+	fs.Usage = usage
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		usage()
+	}
+
+	fmt.Fprintf(os.Stderr, "serving on http://%s/\n", *addr)
+	return http.ListenAndServe(*addr, &lexhttp.Visualizer{})
+}
+
+func pos(tok *lexgo.Token) string {
+	return fmt.Sprintf("%d:%d", tok.Row, tok.Col)
+}
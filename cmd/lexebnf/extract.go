@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/exp/ebnf"
+)
+
+// known gives patterns for lexical productions which grammars commonly
+// describe in prose rather than define, as the Go spec does
+var known = map[string]string{
+	"newline":        `\n`,
+	"unicode_char":   `[^\n]`,
+	"unicode_letter": `\pL`,
+	"unicode_digit":  `\p{Nd}`,
+}
+
+// punct names common operators and punctuation, for the rules of literals
+// which aren't words. Others are named after their characters
+var punct = map[string]string{
+	"(": "LPAREN", ")": "RPAREN", "[": "LBRACK", "]": "RBRACK",
+	"{": "LBRACE", "}": "RBRACE", ",": "COMMA", ";": "SEMICOLON",
+	":": "COLON", ".": "PERIOD", "+": "ADD", "-": "SUB", "*": "MUL",
+	"/": "QUO", "%": "REM", "&": "AND", "|": "OR", "^": "XOR",
+	"<": "LSS", ">": "GTR", "=": "ASSIGN", "!": "NOT", "~": "TILDE",
+	"?": "QUESTION", "@": "AT", "#": "HASH", "$": "DOLLAR",
+	`\`: "BACKSLASH", "'": "QUOTE", `"`: "DQUOTE", "`": "BACKQUOTE",
+	"_": "UNDERSCORE",
+
+	"==": "EQL", "!=": "NEQ", "<=": "LEQ", ">=": "GEQ", "&&": "LAND",
+	"||": "LOR", "<<": "SHL", ">>": "SHR", "++": "INC", "--": "DEC",
+	":=": "DEFINE", "...": "ELLIPSIS", "->": "ARROW", "=>": "FATARROW",
+	"::": "SCOPE",
+}
+
+// extractor finds the terminals of a grammar and converts them to rules
+type extractor struct {
+	grammar  ebnf.Grammar
+	warnings []string
+
+	// converting holds the lexical productions currently being converted, to
+	// catch recursion
+	converting map[string]bool
+}
+
+// isLexical matches the definition of lexical productions used by the ebnf
+// package, those whose names start with a lower case letter
+func isLexical(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return !unicode.IsUpper(r)
+}
+
+// byPos returns the productions in the order they're declared
+func byPos(prods []*ebnf.Production) []*ebnf.Production {
+	sort.Slice(prods, func(i, j int) bool {
+		return prods[i].Pos().Offset < prods[j].Pos().Offset
+	})
+	return prods
+}
+
+// extract returns the rules for the terminals of the grammar: the lexical
+// productions used by its syntactic productions, and the literals those
+// productions contain. If start is given only the syntactic productions
+// reachable from it are considered.
+func (e *extractor) extract(start string) ([]rule, error) {
+	var syntactic, lexical []*ebnf.Production
+	for _, prod := range e.grammar {
+		if isLexical(prod.Name.String) {
+			lexical = append(lexical, prod)
+		} else {
+			syntactic = append(syntactic, prod)
+		}
+	}
+	syntactic, lexical = byPos(syntactic), byPos(lexical)
+
+	if start != "" {
+		prod, ok := e.grammar[start]
+		if !ok {
+			return nil, fmt.Errorf("no start production %q", start)
+		} else if isLexical(start) {
+			return nil, fmt.Errorf("start production %q is lexical", start)
+		}
+		syntactic = []*ebnf.Production{prod}
+	}
+
+	var terminals []*ebnf.Name
+	var literals []*ebnf.Token
+	seen := map[string]bool{}
+	for i := 0; i < len(syntactic); i++ {
+		walk(syntactic[i].Expr, func(x ebnf.Expression) {
+			switch x := x.(type) {
+			case *ebnf.Name:
+				if seen[x.String] {
+					return
+				}
+				seen[x.String] = true
+				if isLexical(x.String) {
+					terminals = append(terminals, x)
+				} else if prod, ok := e.grammar[x.String]; start != "" && ok {
+					syntactic = append(syntactic, prod)
+				}
+			case *ebnf.Token:
+				if !seen[`"`+x.String] {
+					seen[`"`+x.String] = true
+					literals = append(literals, x)
+				}
+			}
+		})
+	}
+
+	// A grammar with only lexical productions is taken to be the grammar of
+	// the tokens themselves, whose terminals are those which nothing else
+	// uses
+	if len(syntactic) == 0 {
+		used := map[string]bool{}
+		for _, prod := range lexical {
+			walk(prod.Expr, func(x ebnf.Expression) {
+				if x, ok := x.(*ebnf.Name); ok && x.String != prod.Name.String {
+					used[x.String] = true
+				}
+			})
+		}
+		for _, prod := range lexical {
+			if !used[prod.Name.String] {
+				terminals = append(terminals, prod.Name)
+			}
+		}
+	}
+
+	rules := []rule{{Name: "WS", Pattern: `\s+`, Skip: true}}
+	names := map[string]bool{"WS": true}
+	full := make([]*regexp.Regexp, len(terminals))
+	for i, name := range terminals {
+		e.converting = map[string]bool{}
+		pattern, _, err := e.name(name)
+		if err == nil {
+			full[i], err = regexp.Compile(`^(?:` + pattern + `)$`)
+		}
+		if err != nil {
+			e.warnings = append(e.warnings, err.Error())
+			pattern = ""
+		}
+		rules = append(rules, rule{Name: name.String, Pattern: pattern})
+		names[name.String] = true
+	}
+
+	// Literals which are words are made Keywords of the first terminal which
+	// matches them, e.g. an identifier. Any others get rules of their own,
+	// which take precedence over any terminal which also matches them
+	for _, lit := range literals {
+		matchedBy := -1
+		for i, re := range full {
+			if re != nil && re.MatchString(lit.String) {
+				matchedBy = i
+				break
+			}
+		}
+
+		name := literalName(lit.String)
+		for base, n := name, 2; names[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		names[name] = true
+
+		if matchedBy >= 0 && isWord(lit.String) {
+			r := &rules[matchedBy+1]
+			if r.Keywords == nil {
+				r.Keywords = map[string][]string{}
+			}
+			r.Keywords[name] = []string{lit.String}
+			continue
+		}
+
+		r := rule{Name: name, Pattern: regexp.QuoteMeta(lit.String)}
+		if matchedBy >= 0 {
+			r.Priority = 1
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// walk calls fn for every expression within x, including x itself
+func walk(x ebnf.Expression, fn func(ebnf.Expression)) {
+	if x == nil {
+		return
+	}
+	fn(x)
+	switch x := x.(type) {
+	case ebnf.Alternative:
+		for _, x := range x {
+			walk(x, fn)
+		}
+	case ebnf.Sequence:
+		for _, x := range x {
+			walk(x, fn)
+		}
+	case *ebnf.Group:
+		walk(x.Body, fn)
+	case *ebnf.Option:
+		walk(x.Body, fn)
+	case *ebnf.Repetition:
+		walk(x.Body, fn)
+	}
+}
+
+// Precedences of the regexps returned by convert, from loosest to tightest
+const (
+	precAlt = iota
+	precConcat
+	precAtom
+)
+
+// convert converts a lexical expression to a regular expression, returning
+// its precedence so that it's only wrapped in a group where needed
+func (e *extractor) convert(x ebnf.Expression) (string, int, error) {
+	switch x := x.(type) {
+	case *ebnf.Token:
+		switch utf8.RuneCountInString(x.String) {
+		case 0:
+			return `(?:)`, precAtom, nil
+		case 1:
+			return regexp.QuoteMeta(x.String), precAtom, nil
+		}
+		return regexp.QuoteMeta(x.String), precConcat, nil
+
+	case *ebnf.Range:
+		lo, err := char(x.Begin)
+		if err != nil {
+			return "", 0, err
+		}
+		hi, err := char(x.End)
+		if err != nil {
+			return "", 0, err
+		}
+		return "[" + classChar(lo) + "-" + classChar(hi) + "]", precAtom, nil
+
+	case ebnf.Alternative:
+		parts := make([]string, len(x))
+		for i, x := range x {
+			s, _, err := e.convert(x)
+			if err != nil {
+				return "", 0, err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, "|"), precAlt, nil
+
+	case ebnf.Sequence:
+		var b strings.Builder
+		for _, x := range x {
+			s, prec, err := e.convert(x)
+			if err != nil {
+				return "", 0, err
+			} else if prec < precConcat {
+				s = "(?:" + s + ")"
+			}
+			b.WriteString(s)
+		}
+		return b.String(), precConcat, nil
+
+	case *ebnf.Group:
+		return e.convert(x.Body)
+
+	case *ebnf.Option:
+		s, err := e.atom(x.Body)
+		return s + "?", precConcat, err
+
+	case *ebnf.Repetition:
+		s, err := e.atom(x.Body)
+		return s + "*", precConcat, err
+
+	case *ebnf.Name:
+		return e.name(x)
+
+	case *ebnf.Bad:
+		return "", 0, fmt.Errorf("%s: %s", x.Pos(), x.Error)
+	}
+	return "", 0, fmt.Errorf("%s: unexpected expression %T", x.Pos(), x)
+}
+
+// atom is like convert, but wraps the result in a group if it would otherwise
+// bind more loosely than a postfix operator
+func (e *extractor) atom(x ebnf.Expression) (string, error) {
+	s, prec, err := e.convert(x)
+	if prec < precAtom {
+		s = "(?:" + s + ")"
+	}
+	return s, err
+}
+
+// name converts a reference to a lexical production by converting the
+// production itself
+func (e *extractor) name(x *ebnf.Name) (string, int, error) {
+	prod, defined := e.grammar[x.String]
+	if !defined || prod.Expr == nil {
+		if pattern, ok := known[x.String]; ok {
+			return pattern, precAtom, nil
+		} else if !defined {
+			return "", 0, fmt.Errorf("%s: %s isn't defined", x.Pos(), x.String)
+		}
+		return "", 0, fmt.Errorf("%s: %s has no expression", prod.Pos(), x.String)
+	} else if !isLexical(x.String) {
+		return "", 0, fmt.Errorf("%s: lexical production uses %s, which isn't lexical", x.Pos(), x.String)
+	} else if e.converting[x.String] {
+		return "", 0, fmt.Errorf("%s: %s is recursive, which can't be converted to a regexp", prod.Pos(), x.String)
+	}
+
+	e.converting[x.String] = true
+	defer delete(e.converting, x.String)
+	return e.convert(prod.Expr)
+}
+
+func char(tok *ebnf.Token) (rune, error) {
+	if utf8.RuneCountInString(tok.String) != 1 {
+		return 0, fmt.Errorf("%s: range bound %q isn't a single character", tok.Pos(), tok.String)
+	}
+	r, _ := utf8.DecodeRuneInString(tok.String)
+	return r, nil
+}
+
+// classChar returns the rune in a form which can be used in a character class
+func classChar(r rune) string {
+	if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return string(r)
+	}
+	return fmt.Sprintf(`\x{%x}`, r)
+}
+
+func isWord(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// literalName returns the name of the rule or Keyword for a literal, e.g. IF
+// for "if" and LPAREN for "("
+func literalName(lit string) string {
+	if name, ok := punct[lit]; ok {
+		return name
+	} else if isWord(lit) {
+		return strings.ToUpper(lit)
+	}
+
+	parts := make([]string, 0, len(lit))
+	for _, r := range lit {
+		name, ok := punct[string(r)]
+		if !ok {
+			return "LIT"
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, "_")
+}
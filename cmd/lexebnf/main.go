@@ -0,0 +1,211 @@
+// Command lexebnf jump-starts writing a lexer for a language which is already
+// specified in EBNF. It reads a grammar, in the EBNF dialect used by the Go
+// spec (see golang.org/x/exp/ebnf), extracts its terminals, and writes a
+// skeleton lexspec file with a rule for each of them, along with optionally
+// a Go file declaring a TokenType constant for each rule.
+//
+// Usage:
+//
+//	lexebnf [-start NAME] [-o FILE] [-go FILE] [-pkg NAME] GRAMMAR
+//
+// The terminals are the lexical productions (those whose names start with a
+// lower case letter) used by the grammar's other productions, or only those
+// reachable from -start if it's given, along with the literals those
+// productions contain. A grammar with only lexical productions is taken to
+// describe just the tokens, and its terminals are the productions which no
+// others use.
+//
+// Each lexical production is converted to a regular expression, inlining the
+// productions it uses. newline, unicode_char, unicode_letter and
+// unicode_digit, which the Go spec describes in prose, are given suitable
+// patterns. Productions which can't be converted, e.g. recursive ones, are
+// reported and given an empty pattern, which never matches, to be filled in by
+// hand. Literals which are words, and which a terminal such as an identifier
+// matches, become Keywords of that terminal's rule. Other literals get rules
+// of their own. A rule skipping whitespace is also added, as EBNF grammars
+// rarely specify it.
+//
+// The spec file is written to -o, as JSON if its name ends in .json and YAML
+// otherwise, or to stdout as YAML. The TokenType constants match those which
+// lexspec assigns the rules, as long as the rules keep their order.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mediocregopher/lexgo"
+	"github.com/mediocregopher/lexgo/lexrule"
+	"github.com/mediocregopher/lexgo/lexspec"
+	"golang.org/x/exp/ebnf"
+	"gopkg.in/yaml.v3"
+)
+
+type rule struct {
+	Name     string              `json:"name" yaml:"name"`
+	Pattern  string              `json:"pattern" yaml:"pattern"`
+	Skip     bool                `json:"skip,omitempty" yaml:"skip,omitempty"`
+	Priority int                 `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Keywords map[string][]string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+}
+
+type file struct {
+	MaximalMunch bool   `json:"maximal_munch" yaml:"maximal_munch"`
+	Rules        []rule `json:"rules" yaml:"rules"`
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+	lexebnf [-start NAME] [-o FILE] [-go FILE] [-pkg NAME] GRAMMAR
+
+`)
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	start := flag.String("start", "", "only consider productions reachable from this one")
+	out := flag.String("o", "", "file to write the spec to (default stdout)")
+	goOut := flag.String("go", "", "file to write TokenType constants to")
+	pkg := flag.String("pkg", "lexer", "package name for the -go file")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+	}
+
+	if err := run(flag.Arg(0), *start, *out, *goOut, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "lexebnf: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, start, out, goOut, pkg string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	grammar, err := ebnf.Parse(path, f)
+	if err != nil {
+		return err
+	}
+
+	e := &extractor{grammar: grammar}
+	rules, err := e.extract(start)
+	if err != nil {
+		return err
+	}
+	for _, w := range e.warnings {
+		fmt.Fprintf(os.Stderr, "lexebnf: warning: %s\n", w)
+	}
+
+	// The spec is parsed back using lexspec, both to check it and to find out
+	// the TokenTypes lexspec assigns
+	var b []byte
+	var spec lexrule.Spec
+	if strings.HasSuffix(out, ".json") {
+		if b, err = json.MarshalIndent(file{MaximalMunch: true, Rules: rules}, "", "  "); err == nil {
+			b = append(b, '\n')
+			spec, err = lexspec.ParseJSON(b)
+		}
+	} else {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err = enc.Encode(file{MaximalMunch: true, Rules: rules}); err == nil {
+			b = buf.Bytes()
+			spec, err = lexspec.ParseYAML(b)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		if _, err := os.Stdout.Write(b); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(out, b, 0644); err != nil {
+		return err
+	}
+
+	if goOut == "" {
+		return nil
+	}
+	src, err := constants(spec, pkg, filepath.Base(path), filepath.Base(out))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(goOut, src, 0644)
+}
+
+// constants returns the source of a Go file declaring a TokenType constant for
+// each of the Spec's types, named after the rule or Keyword they belong to
+func constants(spec lexrule.Spec, pkg, grammar, out string) ([]byte, error) {
+	types := spec.Types()
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return types[names[i]] < types[names[j]]
+	})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\nimport %q\n\n", pkg, "github.com/mediocregopher/lexgo")
+	if out == "" || out == "." {
+		out = "the spec"
+	}
+	fmt.Fprintf(&b, "// TokenTypes of the rules in %s, which lexebnf generated from %s\n", out, grammar)
+	b.WriteString("const (\n")
+	seen := map[string]bool{}
+	for i, name := range names {
+		ident := goName(name)
+		for base, n := ident, 2; seen[ident]; n++ {
+			ident = fmt.Sprintf("%s%d", base, n)
+		}
+		seen[ident] = true
+
+		if i == 0 {
+			fmt.Fprintf(&b, "%s lexgo.TokenType = lexgo.UserDefined + iota // %s\n", ident, name)
+		} else if types[name] != lexgo.UserDefined+lexgo.TokenType(i) {
+			return nil, fmt.Errorf("rule %q has unexpected TokenType %d", name, types[name])
+		} else {
+			fmt.Fprintf(&b, "%s // %s\n", ident, name)
+		}
+	}
+	b.WriteString(")\n")
+	return format.Source(b.Bytes())
+}
+
+// goName converts a rule name to an exported Go identifier, e.g. int_lit to
+// IntLit and LPAREN to Lparen
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if strings.ToUpper(part) == part {
+			part = strings.ToLower(part)
+		}
+		r, n := utf8.DecodeRuneInString(part)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(part[n:])
+	}
+	s := b.String()
+	if r, _ := utf8.DecodeRuneInString(s); !unicode.IsLetter(r) {
+		s = "T" + s
+	}
+	return s
+}
@@ -0,0 +1,379 @@
+// Command lexstep is an interactive debugger for lexers built using lexgo. It
+// lexes a file using either a registered lexer or a lexspec file, and lets the
+// lexer's state transitions be stepped through one at a time, with the
+// Lexer's buffer and position inspected along the way, and breakpoints set on
+// TokenTypes.
+//
+// Usage:
+//
+//	lexstep [-lexer NAME | -spec FILE] FILE
+//
+// If neither -lexer nor -spec is given the lexer is chosen based on the file's
+// name, as by lexdump. Commands are then read from stdin:
+//
+//	step [N]          run the next N states (default 1), showing each
+//	next              run states until one emits a Token
+//	continue          run states until a breakpoint is hit, or the stream ends
+//	break [TYPE...]   break when a Token of any of the TokenTypes is emitted,
+//	                  or list the breakpoints if none are given
+//	delete [TYPE...]  delete the breakpoints, or all of them if none are given
+//	print             show the next state, the buffer and the position
+//	tokens            list the Tokens emitted so far
+//	help              list the commands
+//	quit              exit
+//
+// Each command may be abbreviated to its first letter, and an empty line
+// repeats the previous command. TokenTypes are given by name, as the lexer
+// names them, or by number.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mediocregopher/lexgo"
+	_ "github.com/mediocregopher/lexgo/lexers/markdown"
+	"github.com/mediocregopher/lexgo/lexspec"
+)
+
+func usage() {
+	var names []string
+	for _, reg := range lexgo.Registered() {
+		names = append(names, reg.Name)
+	}
+	fmt.Fprintf(os.Stderr, `Usage:
+	lexstep [-lexer NAME | -spec FILE] FILE
+
+Available lexers: %s
+`, strings.Join(names, ", "))
+	os.Exit(2)
+}
+
+// wrapper is implemented by Tokenizers which wrap a Lexer, such as the
+// markdown lexer
+type wrapper interface {
+	Lexer() *lexgo.Lexer
+}
+
+// load returns a Lexer for the input, and a function naming its TokenTypes
+func load(lexer, spec, path string, r io.Reader) (*lexgo.Lexer, func(lexgo.TokenType) string, error) {
+	if spec != "" {
+		def, err := lexspec.LoadDef(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		names := map[lexgo.TokenType]string{}
+		for name, t := range def.Spec().Types() {
+			names[t] = name
+		}
+		return def.New(r), func(t lexgo.TokenType) string { return names[t] }, nil
+	}
+
+	reg, ok := lexgo.LookupFilename(path)
+	if lexer != "" {
+		reg, ok = lexgo.Lookup(lexer)
+	}
+	if !ok && lexer != "" {
+		return nil, nil, fmt.Errorf("unknown lexer %q", lexer)
+	} else if !ok {
+		return nil, nil, fmt.Errorf("no lexer registered for %q, use -lexer or -spec", path)
+	}
+
+	switch tz := reg.New(r).(type) {
+	case *lexgo.Lexer:
+		return tz, reg.TypeName, nil
+	case wrapper:
+		return tz.Lexer(), reg.TypeName, nil
+	default:
+		return nil, nil, fmt.Errorf("lexer %q isn't a lexgo.Lexer, so can't be stepped through", reg.Name)
+	}
+}
+
+func main() {
+	fs := flag.NewFlagSet("lexstep", flag.ExitOnError)
+	fs.Usage = usage
+	lexer := fs.String("lexer", "", "registered lexer to use (default based on the file name)")
+	spec := fs.String("spec", "", "lexspec file to build the lexer from")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 1 || (*lexer != "" && *spec != "") {
+		usage()
+	}
+
+	input, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lexstep: %s\n", err)
+		os.Exit(1)
+	}
+	l, typeName, err := load(*lexer, *spec, fs.Arg(0), strings.NewReader(string(input)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lexstep: %s\n", err)
+		os.Exit(1)
+	}
+
+	s := &session{
+		l:        l,
+		input:    string(input),
+		typeName: typeName,
+		breaks:   map[lexgo.TokenType]bool{},
+		w:        bufio.NewWriter(os.Stdout),
+	}
+	s.repl(os.Stdin)
+}
+
+type session struct {
+	l        *lexgo.Lexer
+	input    string
+	typeName func(lexgo.TokenType) string
+	breaks   map[lexgo.TokenType]bool
+	w        *bufio.Writer
+
+	// toks are all Tokens emitted so far, and done is set once the stream has
+	// ended
+	toks []lexgo.Token
+	done bool
+}
+
+func (s *session) repl(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	var last []string
+	for {
+		fmt.Fprint(s.w, "(lexstep) ")
+		s.w.Flush()
+		if !sc.Scan() {
+			fmt.Fprintln(s.w)
+			s.w.Flush()
+			return
+		}
+
+		args := strings.Fields(sc.Text())
+		if len(args) == 0 {
+			args = last
+		}
+		if len(args) == 0 {
+			continue
+		}
+		last = args
+
+		if quit := s.command(args[0], args[1:]); quit {
+			s.w.Flush()
+			return
+		}
+	}
+}
+
+// command runs the command, returning true if lexstep should exit
+func (s *session) command(cmd string, args []string) bool {
+	switch cmd {
+	case "s", "step":
+		n := 1
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				fmt.Fprintf(s.w, "invalid number of steps %q\n", args[0])
+				return false
+			}
+		}
+		for i := 0; i < n && !s.ended(); i++ {
+			s.show(s.step())
+		}
+	case "n", "next":
+		for !s.ended() {
+			if res := s.step(); len(res.Tokens) > 0 {
+				s.show(res)
+				break
+			}
+		}
+	case "c", "continue":
+		for !s.ended() {
+			if res := s.step(); s.hitBreak(res) || s.done {
+				s.show(res)
+				break
+			}
+		}
+	case "b", "break":
+		if len(args) == 0 {
+			s.listBreaks()
+		}
+		for _, arg := range args {
+			if t, ok := s.parseType(arg); ok {
+				s.breaks[t] = true
+			}
+		}
+	case "d", "delete":
+		if len(args) == 0 {
+			s.breaks = map[lexgo.TokenType]bool{}
+		}
+		for _, arg := range args {
+			if t, ok := s.parseType(arg); ok {
+				delete(s.breaks, t)
+			}
+		}
+	case "p", "print":
+		s.print()
+	case "t", "tokens":
+		for i := range s.toks {
+			fmt.Fprintf(s.w, "%5d %s\n", i+1, s.formatToken(&s.toks[i]))
+		}
+	case "h", "help":
+		fmt.Fprint(s.w, help)
+	case "q", "quit":
+		return true
+	default:
+		fmt.Fprintf(s.w, "unknown command %q, try help\n", cmd)
+	}
+	return false
+}
+
+const help = `step [N]          (s) run the next N states, default 1
+next              (n) run states until one emits a Token
+continue          (c) run states until a breakpoint is hit
+break [TYPE...]   (b) break on Tokens of the TokenTypes, or list breakpoints
+delete [TYPE...]  (d) delete breakpoints, or all of them
+print             (p) show the next state, the buffer and the position
+tokens            (t) list the Tokens emitted so far
+quit              (q) exit
+`
+
+// ended returns whether the stream has ended, saying so if it has
+func (s *session) ended() bool {
+	if s.done {
+		fmt.Fprintln(s.w, "the stream has ended")
+	}
+	return s.done
+}
+
+// step runs the next state, recording the Tokens it emits
+func (s *session) step() lexgo.StepResult {
+	res, ok := s.l.Step()
+	s.toks = append(s.toks, res.Tokens...)
+	s.done = !ok
+	for i := range res.Tokens {
+		s.done = s.done || res.Tokens[i].EndsStream()
+	}
+	return res
+}
+
+func (s *session) hitBreak(res lexgo.StepResult) bool {
+	for _, tok := range res.Tokens {
+		if s.breaks[tok.TokenType] {
+			return true
+		}
+	}
+	return false
+}
+
+// show prints the state transition and the Tokens it emitted
+func (s *session) show(res lexgo.StepResult) {
+	switch {
+	case res.State == "":
+		fmt.Fprintln(s.w, "no state left to run")
+	case res.Next == "":
+		fmt.Fprintf(s.w, "%s -> end\n", res.State)
+	default:
+		fmt.Fprintf(s.w, "%s -> %s\n", res.State, res.Next)
+	}
+	for i := range res.Tokens {
+		fmt.Fprintf(s.w, "    %s\n", s.formatToken(&res.Tokens[i]))
+	}
+}
+
+func (s *session) print() {
+	state := s.l.State()
+	if state == "" {
+		state = "none"
+	}
+	fmt.Fprintf(s.w, "state:  %s\n", state)
+
+	if buf := s.l.BufferString(); buf == "" {
+		fmt.Fprintln(s.w, "buffer: empty")
+	} else if row, col, off, ok := s.l.BufferPos(); ok {
+		fmt.Fprintf(s.w, "buffer: %q from %d:%d (offset %d)\n", buf, row, col, off)
+	} else {
+		fmt.Fprintf(s.w, "buffer: %q\n", buf)
+	}
+
+	row, col, off := s.l.NextPos()
+	fmt.Fprintf(s.w, "next:   %d:%d (offset %d)\n", row, col, off)
+	lines := strings.Split(s.input, "\n")
+	if row < 1 || row > len(lines) {
+		return
+	}
+
+	// mark the next rune, keeping tabs so that the marker lines up
+	var marker strings.Builder
+	var i int
+	for _, r := range lines[row-1] {
+		if i++; i >= col {
+			break
+		} else if r == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	fmt.Fprintf(s.w, "    %s\n    %s^\n", lines[row-1], marker.String())
+}
+
+func (s *session) formatToken(tok *lexgo.Token) string {
+	val := tok.Val
+	if tok.Err != nil {
+		val = tok.Err.Error()
+	} else if tok.Warn != nil {
+		val = tok.Warn.Error()
+	}
+	return fmt.Sprintf("%d:%d %s %q", tok.Row, tok.Col, s.name(tok.TokenType), val)
+}
+
+func (s *session) name(t lexgo.TokenType) string {
+	var name string
+	switch {
+	case t == lexgo.Err:
+		name = "error"
+	case t == lexgo.Warning:
+		name = "warning"
+	case s.typeName != nil:
+		name = s.typeName(t)
+	}
+	if name == "" {
+		name = fmt.Sprint(int(t))
+	}
+	return name
+}
+
+// maxNamed bounds the TokenTypes searched when looking one up by name, as the
+// lexer's TypeName only goes one way
+const maxNamed = 4096
+
+func (s *session) parseType(arg string) (lexgo.TokenType, bool) {
+	if n, err := strconv.Atoi(arg); err == nil {
+		return lexgo.TokenType(n), true
+	}
+	for t := lexgo.Warning; t < lexgo.UserDefined+maxNamed; t++ {
+		if s.name(t) == arg {
+			return t, true
+		}
+	}
+	fmt.Fprintf(s.w, "unknown TokenType %q\n", arg)
+	return 0, false
+}
+
+func (s *session) listBreaks() {
+	if len(s.breaks) == 0 {
+		fmt.Fprintln(s.w, "no breakpoints")
+		return
+	}
+	types := make([]lexgo.TokenType, 0, len(s.breaks))
+	for t := range s.breaks {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, t := range types {
+		fmt.Fprintf(s.w, "break on %s\n", s.name(t))
+	}
+}
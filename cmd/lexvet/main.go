@@ -0,0 +1,18 @@
+// Command lexvet reports common mistakes in code using lexgo, such as
+// LexerFuncs which loop without consuming input. See the lexvet package for
+// the full list.
+//
+// Usage:
+//
+//	lexvet PACKAGES
+//	go vet -vettool=$(which lexvet) PACKAGES
+package main
+
+import (
+	"github.com/mediocregopher/lexgo/lexvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(lexvet.Analyzer)
+}
@@ -0,0 +1,79 @@
+package lexgo
+
+// Then returns a LexerFunc which runs a until it's done, i.e. until a, or one
+// of the LexerFuncs it leads to, returns nil. It then continues on with b. This
+// allows small helper states which end by returning nil to be reused across
+// lexers, without each having to be told where to go once it's done.
+//
+// If an Err Token which ends the stream was emitted by the time a is done then
+// b is not run.
+func Then(a, b LexerFunc) LexerFunc {
+	return func(l *Lexer) LexerFunc {
+		next := a(l)
+		if next != nil {
+			return Then(next, b)
+		} else if l.ended() {
+			return nil
+		}
+		return b
+	}
+}
+
+// Or returns a LexerFunc which tries each of the given LexerFuncs in turn,
+// continuing on with the first one which doesn't decline. A LexerFunc declines
+// by returning nil without having read any input, buffered anything or
+// emitted any Tokens, e.g. after peeking at the next rune and seeing something
+// it doesn't handle. If every LexerFunc declines then so does the returned one.
+//
+// A LexerFunc must make its decision to decline using only peeking (see
+// PeekRuneN), or put back whatever it read using Backup before declining.
+func Or(fns ...LexerFunc) LexerFunc {
+	return func(l *Lexer) LexerFunc {
+		for _, fn := range fns {
+			before := l.progress()
+			if next := fn(l); next != nil || l.progress() != before {
+				return next
+			}
+		}
+		return nil
+	}
+}
+
+// Loop returns a LexerFunc which runs fn until it's done (see Then) over and
+// over, for as long as the next rune in the stream isn't one which until
+// returns true for. Once it is, or the stream has ended, Loop continues on with
+// next, which will see that rune as the next one in the stream (or the error
+// which ended it).
+//
+// fn must read something each time it is run, otherwise Loop will never end.
+func Loop(fn LexerFunc, until func(rune) bool, next LexerFunc) LexerFunc {
+	var loop LexerFunc
+	loop = func(l *Lexer) LexerFunc {
+		if r, err := l.peekRune(); err != nil || until(r) {
+			return next
+		}
+		return Then(fn, loop)(l)
+	}
+	return loop
+}
+
+// progress describes how far along the Lexer is, in a way which changes
+// whenever input is read, buffered or emitted
+type progress struct {
+	inputOff, outbuf, queue int
+}
+
+func (l *Lexer) progress() progress {
+	return progress{l.inputOff, len(l.outbuf), len(l.queue)}
+}
+
+// ended returns whether an Err Token which ends the stream is waiting in the
+// queue
+func (l *Lexer) ended() bool {
+	for i := l.queueHead; i < len(l.queue); i++ {
+		if l.queue[i].EndsStream() {
+			return true
+		}
+	}
+	return false
+}
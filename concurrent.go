@@ -0,0 +1,75 @@
+//go:build !tinygo
+
+package lexgo
+
+import (
+	"sync"
+)
+
+// concurrent holds the state used by C and Stop. stopL protects stopCh, which
+// is set by C and may be read by Stop from another go-routine
+type concurrent struct {
+	once     sync.Once
+	ch       chan *Token
+	stopOnce sync.Once
+	stopL    sync.Mutex
+	stopCh   chan struct{}
+}
+
+// C puts the Lexer into concurrent mode, where it runs in its own go-routine
+// and sends each Token it produces on the returned channel. This allows
+// consumers to select over Tokens alongside timers, context cancellation, and
+// other channels. The channel is closed after the first Err Token which ends
+// the stream (including io.EOF) is sent on it, Recoverable Err Tokens are sent
+// like any other Token.
+//
+// Multiple calls to C return the same channel. Once C has been called Next, and
+// anything else which reads from the Lexer, must not be used. If the consumer
+// stops reading from the channel before it is closed Stop should be called, so
+// the go-routine can exit.
+func (l *Lexer) C() <-chan *Token {
+	l.conc.once.Do(func() {
+		l.conc.ch = make(chan *Token, 1)
+		l.conc.stopL.Lock()
+		l.conc.stopCh = make(chan struct{})
+		l.conc.stopL.Unlock()
+		go l.spin()
+	})
+	return l.conc.ch
+}
+
+func (l *Lexer) spin() {
+	defer close(l.conc.ch)
+	for {
+		tok := l.Next()
+		select {
+		case l.conc.ch <- tok:
+		case <-l.conc.stopCh:
+			tok.Release()
+			return
+		}
+		if tok.EndsStream() {
+			return
+		}
+	}
+}
+
+// Stop causes the go-routine started by C to exit the next time it would send a
+// Token, and close the channel. It is only necessary to call if the consumer
+// abandons the channel before it is closed. Stop has no effect if C hasn't
+// been called, and it's safe to call multiple times.
+//
+// Stop also causes the go-routine reading from the underlying io.Reader, when
+// using WithReadTimeout or WithIdleFunc, to exit once its current read
+// returns. This happens automatically once the stream has ended, so Stop only
+// needs to be called for it if the Lexer is abandoned part way through.
+func (l *Lexer) Stop() {
+	l.async.stop()
+	l.conc.stopL.Lock()
+	stopCh := l.conc.stopCh
+	l.conc.stopL.Unlock()
+	if stopCh == nil {
+		return
+	}
+	l.conc.stopOnce.Do(func() { close(stopCh) })
+}
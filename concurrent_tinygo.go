@@ -0,0 +1,10 @@
+//go:build tinygo
+
+package lexgo
+
+// concurrent is empty under TinyGo, where C isn't available
+type concurrent struct{}
+
+// Stop has no effect under TinyGo, where the Lexer never starts any
+// go-routines. It exists so that code calling it still compiles.
+func (l *Lexer) Stop() {}
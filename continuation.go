@@ -0,0 +1,63 @@
+package lexgo
+
+import (
+	"unicode/utf8"
+)
+
+// LineContinuation causes the Lexer to treat the given sequence, e.g. "\\\n"
+// for make, shell or the C preprocessor, as a line continuation. Whenever the
+// sequence appears in the stream it is silently skipped, so that runes on
+// either side of it are read one after the other as if the line simply
+// continued, and Tokens aren't broken up by it.
+//
+// Position tracking similarly treats the continued line as a single line: the
+// runes of the sequence are never considered to be line breaks, so rows only
+// advance at a line break which isn't part of a continuation. Byte offsets
+// still reflect the true position in the stream.
+//
+// The Lexer will always wrap the io.Reader given to NewLexer in a
+// bufio.Reader when this option is used, unless it already is one. seq must
+// not be longer than the bufio.Reader's buffer.
+func LineContinuation(seq string) Option {
+	return func(l *Lexer) {
+		l.cont = seq
+	}
+}
+
+// skipContinuations discards any line continuation sequences at the head of
+// the stream, advancing the position accordingly
+func (l *Lexer) skipContinuations() {
+	l.skipBytes(l.takeContinuations())
+}
+
+// takeContinuations discards any line continuation sequences at the head of
+// the stream and returns them, without advancing the position
+func (l *Lexer) takeContinuations() []byte {
+	if l.cont == "" || l.br == nil {
+		return nil
+	}
+	var skipped []byte
+	for {
+		b, _ := l.br.Peek(len(l.cont))
+		if string(b) != l.cont {
+			return skipped
+		}
+		skipped = append(skipped, b...)
+		l.br.Discard(len(b))
+	}
+}
+
+// skipBytes advances the position past the given bytes, which were discarded
+// from the head of the stream, without considering any of them to be line
+// breaks
+func (l *Lexer) skipBytes(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	l.observeBytes(b)
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		l.moveNL(r, size, false)
+		b = b[size:]
+	}
+}
@@ -0,0 +1,108 @@
+package lexgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Severity describes how serious a Diagnostic is
+type Severity int
+
+// All Severity values, from most to least serious. These match the severities
+// used by the Language Server Protocol
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+	SeverityHint
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Diagnostic is a structured description of a lexical finding, intended to be
+// easily consumed by linters and IDE integrations. Diagnostic implements error,
+// and so may be passed to EmitErr or EmitWarning like any other error, though
+// EmitDiagnostic will choose between them based on Severity.
+type Diagnostic struct {
+	// Code is a stable, machine-usable identifier for the kind of finding,
+	// e.g. "E012" or "unterminated-string". It may be empty
+	Code string
+
+	Severity Severity
+	Message  string
+
+	// The range of the input the Diagnostic applies to. The end is exclusive
+	Row, Col, Offset          int
+	EndRow, EndCol, EndOffset int
+
+	// Suggestion is an optional replacement for the text in the range which
+	// would resolve the Diagnostic
+	Suggestion string
+}
+
+func (d *Diagnostic) Error() string {
+	sev := d.Severity.String()
+	if d.Code != "" {
+		sev += "[" + d.Code + "]"
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", d.Row, d.Col, sev, d.Message)
+}
+
+// NewDiagnostic returns a Diagnostic with the given properties, whose range
+// spans from the start of whatever is currently buffered (or the rune most
+// recently read, if nothing is) up to the rune which will be read next
+func (l *Lexer) NewDiagnostic(sev Severity, code, format string, args ...interface{}) *Diagnostic {
+	d := &Diagnostic{
+		Code:     code,
+		Severity: sev,
+		Message:  fmt.Sprintf(format, args...),
+		Offset:   l.absOff,
+	}
+	d.Row, d.Col = l.reportPos(l.cur.Row, l.cur.Col)
+	if l.row >= 0 && l.col >= 0 {
+		d.Row, d.Col = l.reportPos(l.row, l.col)
+		d.Offset = l.off
+	}
+	d.EndRow, d.EndCol, d.EndOffset = l.nextPos()
+	return d
+}
+
+// EmitDiagnostic emits the given Diagnostic. If its Severity is SeverityError
+// this is equivalent to EmitErr(d), otherwise it is equivalent to
+// EmitWarning(d)
+func (l *Lexer) EmitDiagnostic(d *Diagnostic) {
+	if d.Severity == SeverityError {
+		l.EmitErr(d)
+	} else {
+		l.EmitWarning(d)
+	}
+}
+
+// errPos returns the position which should be used for an Err or Warning Token
+// with the given error. This is the position of the rune most recently read,
+// unless err is or wraps a *PosError or *Diagnostic, in which case it is the
+// position of that.
+func (l *Lexer) errPos(err error) (int, int, int) {
+	var posErr *PosError
+	var diag *Diagnostic
+	if errors.As(err, &posErr) {
+		return posErr.Row, posErr.Col, posErr.Offset
+	} else if errors.As(err, &diag) {
+		return diag.Row, diag.Col, diag.Offset
+	}
+	row, col := l.reportPos(l.cur.Row, l.cur.Col)
+	return row, col, l.absOff
+}
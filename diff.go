@@ -0,0 +1,127 @@
+package lexgo
+
+// DiffOp describes a single step of a DiffEdit
+type DiffOp int
+
+// All DiffOp values
+const (
+	// The Tokens are the same in both streams
+	DiffEqual DiffOp = iota
+
+	// The Token only exists in the first stream
+	DiffDelete
+
+	// The Token only exists in the second stream
+	DiffInsert
+
+	// The Token in the first stream was replaced by the one in the second
+	DiffChange
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffEqual:
+		return "="
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	case DiffChange:
+		return "~"
+	default:
+		return "?"
+	}
+}
+
+// DiffEdit is a single step in the alignment of two Token streams, as returned
+// by DiffTokens. A and B are the indices of the Tokens in the first and second
+// streams the step applies to. A is -1 for DiffInsert, and B is -1 for
+// DiffDelete
+type DiffEdit struct {
+	Op   DiffOp
+	A, B int
+}
+
+// DiffOptions are used to configure DiffTokens. The zero value compares Tokens
+// by their TokenType, Val, position and error message.
+type DiffOptions struct {
+	// If set, Row, Col and Offset are not compared
+	IgnorePositions bool
+
+	// If set, Tokens for which this returns true (e.g. whitespace and
+	// comments) are left out of the comparison altogether, and won't appear
+	// in the returned DiffEdits
+	Trivia func(*Token) bool
+}
+
+// equal returns whether the two Tokens are considered equal
+func (o DiffOptions) equal(a, b *Token) bool {
+	if a.TokenType != b.TokenType || a.Val != b.Val {
+		return false
+	} else if !o.IgnorePositions &&
+		(a.Row != b.Row || a.Col != b.Col || a.Offset != b.Offset) {
+		return false
+	}
+	return errString(a.Err) == errString(b.Err) &&
+		errString(a.Warn) == errString(b.Warn)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// DiffTokens aligns the two Token streams, returning the shortest sequence of
+// DiffEdits which turns a into b, in order. A deletion directly followed by an
+// insertion is reported as a DiffChange instead. If the two streams are equal
+// then every DiffEdit will be DiffEqual.
+func DiffTokens(a, b []Token, opts DiffOptions) []DiffEdit {
+	ai, bi := diffIndices(a, opts), diffIndices(b, opts)
+	edits := myers(len(ai), len(bi), func(i, j int) bool {
+		return opts.equal(&a[ai[i]], &b[bi[j]])
+	})
+
+	res := make([]DiffEdit, 0, len(edits))
+	for i := 0; i < len(edits); {
+		if edits[i].op == DiffEqual {
+			res = append(res, DiffEdit{DiffEqual, ai[edits[i].a], bi[edits[i].b]})
+			i++
+			continue
+		}
+
+		// gather the run of deletions and insertions, and pair them up as
+		// changes as far as possible
+		var dels, ins []int
+		for ; i < len(edits) && edits[i].op != DiffEqual; i++ {
+			if edits[i].op == DiffDelete {
+				dels = append(dels, ai[edits[i].a])
+			} else {
+				ins = append(ins, bi[edits[i].b])
+			}
+		}
+		for len(dels) > 0 && len(ins) > 0 {
+			res = append(res, DiffEdit{DiffChange, dels[0], ins[0]})
+			dels, ins = dels[1:], ins[1:]
+		}
+		for _, d := range dels {
+			res = append(res, DiffEdit{DiffDelete, d, -1})
+		}
+		for _, in := range ins {
+			res = append(res, DiffEdit{DiffInsert, -1, in})
+		}
+	}
+	return res
+}
+
+// diffIndices returns the indices of the Tokens in toks which aren't trivia
+func diffIndices(toks []Token, opts DiffOptions) []int {
+	is := make([]int, 0, len(toks))
+	for i := range toks {
+		if opts.Trivia == nil || !opts.Trivia(&toks[i]) {
+			is = append(is, i)
+		}
+	}
+	return is
+}
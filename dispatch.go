@@ -0,0 +1,82 @@
+package lexgo
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DispatchCase is a single entry of a DispatchTable which applies to any rune
+// Pred returns true for
+type DispatchCase struct {
+	Pred func(rune) bool
+	Func LexerFunc
+}
+
+// DispatchTable describes the canonical top-level state of most lexers: skip
+// any whitespace, look at the next rune, and decide which LexerFunc should
+// handle what's coming based on it. Its Lex method is a LexerFunc which does
+// exactly that, replacing the long if/else chains such a state otherwise
+// consists of.
+//
+// The LexerFuncs in the table are usually going to return the table's Lex
+// method once they're done, which the go compiler will consider an
+// initialization cycle if the table is a package-level variable. To get around
+// this the table can be populated in an init function, or in a lexer's
+// constructor.
+type DispatchTable struct {
+	// Runes maps specific runes to the LexerFunc which handles them. This is
+	// checked before Cases
+	Runes map[rune]LexerFunc
+
+	// Cases are checked in order, the first whose Pred returns true for the
+	// rune is used. This is useful for classes of runes, e.g. unicode.IsDigit
+	Cases []DispatchCase
+
+	// Default is used for any runes which nothing else matched. If nil, an
+	// error is emitted for such runes, and lexing ends
+	Default LexerFunc
+
+	// If set, whitespace isn't skipped before dispatching
+	KeepWhitespace bool
+}
+
+// Lex is a LexerFunc which skips any whitespace (unless KeepWhitespace is set),
+// peeks at the next rune, and returns the LexerFunc the table gives for it. The
+// rune is not read, so the returned LexerFunc will see it as the next rune in
+// the stream. Returns nil once the stream is finished.
+func (t *DispatchTable) Lex(l *Lexer) LexerFunc {
+	if !t.KeepWhitespace {
+		l.SkipWhitespace()
+	}
+	r, err := l.PeekRune()
+	if err != nil {
+		return nil
+	}
+
+	if fn, ok := t.Runes[r]; ok {
+		return fn
+	}
+	for _, c := range t.Cases {
+		if c.Pred(r) {
+			return c.Func
+		}
+	}
+	if t.Default != nil {
+		return t.Default
+	}
+
+	row, col, off := l.nextPos()
+	l.unexpected(r, row, col, off)
+	return nil
+}
+
+// unexpected emits an error for r, found at the given position, not being a
+// rune which the current LexerFunc can handle
+func (l *Lexer) unexpected(r rune, row, col, off int) {
+	l.EmitErr(&PosError{
+		Row:    row,
+		Col:    col,
+		Offset: off,
+		Err:    l.templated(nil, "", strconv.QuoteRune(r), fmt.Errorf("unexpected character %q", r)),
+	})
+}
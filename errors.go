@@ -0,0 +1,185 @@
+package lexgo
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// PosError wraps an error with the position in the stream at which it
+// occurred
+type PosError struct {
+	Row, Col, Offset int
+	Err              error
+}
+
+func (e *PosError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Row, e.Col, e.Err)
+}
+
+// Unwrap returns the wrapped error
+func (e *PosError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidUTF8Error is returned, wrapped in a PosError, when an invalid utf8
+// character is read. errors.Is(err, ErrInvalidUTF8) will return true for it
+type InvalidUTF8Error struct {
+	// The raw byte(s) which could not be decoded. This is the byte at which
+	// decoding failed, along with any continuation bytes following it which
+	// would have made up the same character. Looking at these is usually
+	// enough to tell whether the input is in some other encoding (e.g.
+	// Latin-1), truncated, or binary.
+	//
+	// This will be nil if the io.Reader given to NewLexer was an
+	// io.RuneScanner which doesn't also implement io.ByteScanner. If it isn't
+	// a bufio.Reader only the first byte will be included.
+	Bytes []byte
+
+	// The byte offset of the first of Bytes in the stream
+	Offset int
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	if len(e.Bytes) == 0 {
+		return fmt.Sprintf("%s at byte offset %d", ErrInvalidUTF8, e.Offset)
+	}
+	return fmt.Sprintf("%s (%#x) at byte offset %d", ErrInvalidUTF8, e.Bytes, e.Offset)
+}
+
+// Unwrap returns ErrInvalidUTF8
+func (e *InvalidUTF8Error) Unwrap() error {
+	return ErrInvalidUTF8
+}
+
+// Truncated returns whether Bytes is the beginning of a valid multi-byte
+// character which was cut short, as opposed to a sequence which could never be
+// valid utf8.
+func (e *InvalidUTF8Error) Truncated() bool {
+	n := seqLen(e.Bytes)
+	if n <= 1 || len(e.Bytes) >= n {
+		return false
+	}
+	// The range allowed for the second byte depends on the first, but one of
+	// the two ends of the continuation range is always allowed
+	for _, fill := range []string{"\x80\x80\x80", "\xbf\xbf\xbf"} {
+		b := append(append([]byte(nil), e.Bytes...), fill[:n-len(e.Bytes)]...)
+		if utf8.Valid(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidBytes returns the raw bytes which couldn't be decoded, if err is or
+// wraps an InvalidUTF8Error, or nil otherwise.
+func InvalidBytes(err error) []byte {
+	var invErr *InvalidUTF8Error
+	if errors.As(err, &invErr) {
+		return invErr.Bytes
+	}
+	return nil
+}
+
+// seqLen returns the number of bytes which the character starting at b would
+// be made up of, going by its first byte. This is always at least 1 for a
+// non-empty b.
+func seqLen(b []byte) int {
+	if len(b) == 0 {
+		return 0
+	}
+	n := 1
+	switch c := b[0]; {
+	case c >= 0xc2 && c <= 0xdf:
+		n = 2
+	case c >= 0xe0 && c <= 0xef:
+		n = 3
+	case c >= 0xf0 && c <= 0xf4:
+		n = 4
+	}
+	return n
+}
+
+// invalidLen returns how many bytes at the start of b make up an invalid
+// character, i.e. its first byte and any continuation bytes following it which
+// belong to the same character.
+func invalidLen(b []byte) int {
+	n, i := seqLen(b), 1
+	for i < n && i < len(b) && b[i]&0xc0 == 0x80 {
+		i++
+	}
+	return i
+}
+
+// nextPos returns the position of the rune which would be read next, assuming
+// it isn't a line break
+func (l *Lexer) nextPos() (int, int, int) {
+	if l.noPos {
+		return 0, 0, 0
+	}
+	row, col := l.reportPos(l.cur.Row, l.cur.NextCol)
+	return row, col, l.nextOff
+}
+
+// invalidBytes is called directly after a rune is read which turns out to be
+// invalid utf8. It returns the raw bytes of the invalid character, if they can
+// be known, only the first of which will have been consumed
+func (l *Lexer) invalidBytes() []byte {
+	if l.bs == nil || l.r.UnreadRune() != nil {
+		return nil
+	} else if l.br != nil {
+		peek, _ := l.br.Peek(utf8.UTFMax)
+		if len(peek) > 0 {
+			b := append([]byte(nil), peek[:invalidLen(peek)]...)
+			l.br.Discard(1)
+			return b
+		}
+	}
+	if b, err := l.bs.ReadByte(); err == nil {
+		return []byte{b}
+	}
+	return nil
+}
+
+// invalidUTF8Err returns the error which should be returned for an invalid
+// utf8 character made up of the given bytes, which is the next in the stream
+func (l *Lexer) invalidUTF8Err(b []byte) error {
+	row, col, off := l.nextPos()
+	return &PosError{Row: row, Col: col, Offset: off, Err: &InvalidUTF8Error{
+		Bytes:  b,
+		Offset: off,
+	}}
+}
+
+// defaultPartialLimit is the default for WithPartialLimit
+const defaultPartialLimit = 64
+
+// WithPartialLimit sets the maximum number of bytes of buffered text which will
+// be attached to an Err Token as its Partial field. Text longer than this is
+// truncated, keeping its beginning. The default is 64. A limit of zero causes
+// Partial to never be set.
+func WithPartialLimit(n int) Option {
+	return func(l *Lexer) {
+		l.partialLimit = n
+	}
+}
+
+// partial returns the Token to use as the Partial field of an Err Token being
+// emitted, or nil if nothing is being buffered
+func (l *Lexer) partial() *Token {
+	if len(l.outbuf) == 0 || l.partialLimit <= 0 {
+		return nil
+	}
+	b := l.outbuf
+	if len(b) > l.partialLimit {
+		b = b[:l.partialLimit]
+		// don't cut a rune in half
+		for len(b) > 0 && !utf8.RuneStart(l.outbuf[len(b)]) {
+			b = b[:len(b)-1]
+		}
+	}
+	val := string(b)
+	t := &Token{Val: val, Raw: val, Offset: l.off}
+	t.Row, t.Col = l.reportPos(l.row, l.col)
+	return t
+}
@@ -29,7 +29,8 @@ func NewLispLexer(r io.Reader) *LispLexer {
 }
 
 // We expose Next(), but we don't want to expose anything else from Lexer since
-// it's all only used internally
+// it's all only used internally. Having Next() also means LispLexer implements
+// lexgo.Tokenizer, so it can be passed into anything which wants one
 func (l *LispLexer) Next() *lexgo.Token {
 	return l.lexer.Next()
 }
@@ -38,7 +39,7 @@ func (l *LispLexer) Next() *lexgo.Token {
 // read in runes, ignoring whitespace and deciding what to do with anything else
 // it encounters
 func lexWhitespace(lexer *lexgo.Lexer) lexgo.LexerFunc {
-	r, err := lexer.ReadRune()
+	r, _, err := lexer.ReadRune()
 	if err != nil {
 		// Errors from ReadRune() and PeekRune() should not be emitted, those
 		// methods will do that for us. We return nil to indicate that the lexer
@@ -70,7 +71,7 @@ func lexWhitespace(lexer *lexgo.Lexer) lexgo.LexerFunc {
 // lexComment will read, not buffering anything it reads so as to simply throw
 // it away, until it sees a newline.
 func lexComment(lexer *lexgo.Lexer) lexgo.LexerFunc {
-	r, err := lexer.ReadRune()
+	r, _, err := lexer.ReadRune()
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,33 @@
+//go:build !tinygo
+
+package lexgo
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// funcPC returns the entry point of the function behind the LexerFunc, which
+// identifies the state regardless of which closure it is
+func funcPC(fn LexerFunc) uintptr {
+	return reflect.ValueOf(fn).Pointer()
+}
+
+// stateFuncPC is like funcPC, but for StateFuncs
+func stateFuncPC(fn StateFunc) uintptr {
+	return reflect.ValueOf(fn).Pointer()
+}
+
+// pcName returns the name of the function at pc, without the package path
+func pcName(pc uintptr) string {
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return "unknown"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,27 @@
+//go:build tinygo
+
+package lexgo
+
+import (
+	"strconv"
+	"unsafe"
+)
+
+// funcPC returns the entry point of the function behind the LexerFunc, which
+// identifies the state regardless of which closure it is. TinyGo represents a
+// func value as a context pointer followed by the function pointer, which is
+// read directly rather than using reflect.
+func funcPC(fn LexerFunc) uintptr {
+	return (*[2]uintptr)(unsafe.Pointer(&fn))[1]
+}
+
+// stateFuncPC is like funcPC, but for StateFuncs
+func stateFuncPC(fn StateFunc) uintptr {
+	return (*[2]uintptr)(unsafe.Pointer(&fn))[1]
+}
+
+// pcName returns a name for the function at pc. TinyGo doesn't keep function
+// names around at runtime, so the address is used instead
+func pcName(pc uintptr) string {
+	return "func@0x" + strconv.FormatUint(uint64(pc), 16)
+}
@@ -0,0 +1,97 @@
+package lexgo
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// OnEmit registers a hook which is called with every Token passed to Emit(),
+// just before it is made available to Next(). The hook may modify the Token
+// (e.g. promoting identifiers to keywords, or decoding values), annotate it
+// using its Meta field, or veto it altogether by returning false, in which case
+// the Token is dropped. This allows such logic to live in one place, rather
+// than being scattered across every LexerFunc.
+//
+// Hooks are called after any value transformations (e.g. NormalizeValues) have
+// been done. If OnEmit is given multiple times the hooks are called in the
+// order given, stopping at the first to return false. Err Tokens are not passed
+// to hooks.
+func OnEmit(fn func(*Token) bool) Option {
+	return func(l *Lexer) {
+		l.onEmit = append(l.onEmit, fn)
+	}
+}
+
+// OnRead registers a hook which is called with the raw bytes of everything the
+// Lexer consumes from its input, in the order consumed, including anything
+// which is never buffered into a Token (skipped whitespace, line
+// continuations, invalid utf8 skipped by ResumeAfterError, etc...). This can be
+// used to checksum or mirror the exact input the Lexer processed.
+//
+// The hook may be called with anything from a single rune's worth of bytes to a
+// larger chunk, and must not hold onto the slice after returning. A rune which
+// is read and then given back using UnreadRune is only reported once. The bytes
+// of invalid utf8 characters can only be reported if they are known, see
+// InvalidUTF8Error. If OnRead is given multiple times the hooks are called in
+// the order given.
+func OnRead(fn func([]byte)) Option {
+	return func(l *Lexer) {
+		l.onRead = append(l.onRead, fn)
+	}
+}
+
+// observe reports the given rune, whose encoded size is size, as having been
+// consumed to the OnRead hooks. If it was read by ReadRune then reporting is
+// held off until it's known that UnreadRune won't be called for it
+func (l *Lexer) observe(r rune, size int) {
+	if len(l.onRead) == 0 {
+		return
+	} else if l.canUnread {
+		l.readPending, l.pendingRune = true, r
+		return
+	}
+	l.flushRead()
+	l.readBuf = utf8.AppendRune(l.readBuf[:0], r)
+	l.observeBytes(l.readBuf)
+}
+
+// observeBytes reports the given bytes as having been consumed to the OnRead
+// hooks
+func (l *Lexer) observeBytes(b []byte) {
+	for _, fn := range l.onRead {
+		fn(b)
+	}
+}
+
+// flushRead reports the rune most recently read by ReadRune to the OnRead
+// hooks, if it's still pending
+func (l *Lexer) flushRead() {
+	if !l.readPending {
+		return
+	}
+	l.readPending = false
+	l.readBuf = utf8.AppendRune(l.readBuf[:0], l.pendingRune)
+	l.observeBytes(l.readBuf)
+}
+
+// commitRead is called prior to any reading other than by ReadRune. It
+// indicates that UnreadRune may no longer be called for the rune previously
+// read by ReadRune
+func (l *Lexer) commitRead() {
+	l.canUnread = false
+	if l.readPending {
+		l.flushRead()
+	}
+}
+
+// observeInvalid reports the bytes of an invalid utf8 character, as given by
+// the error returned for it, to the OnRead hooks
+func (l *Lexer) observeInvalid(err error) {
+	if len(l.onRead) == 0 {
+		return
+	}
+	var invErr *InvalidUTF8Error
+	if errors.As(err, &invErr) && len(invErr.Bytes) > 0 {
+		l.observeBytes(invErr.Bytes)
+	}
+}
@@ -0,0 +1,64 @@
+package lexgo
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IsIdentStart returns whether r may begin an identifier, according to the
+// ID_Start property of Unicode Standard Annex #31. This is what most modern
+// languages base their identifier syntax on. Note that '_' is not included;
+// see AcceptIdent for adding extra characters.
+//
+// The character properties defined by UAX #31 are derived from the general
+// categories in the unicode package, so XID_Start and ID_Start only differ
+// for a handful of compatibility characters.
+func IsIdentStart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+	}
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// IsIdentContinue returns whether r may appear in an identifier after its first
+// rune, according to the ID_Continue property of Unicode Standard Annex #31.
+// This includes everything IsIdentStart does, as well as digits, combining
+// marks and connector punctuation such as '_'.
+func IsIdentContinue(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' ||
+			'0' <= r && r <= '9' || r == '_'
+	}
+	return unicode.In(r,
+		unicode.L, unicode.Nl, unicode.Other_ID_Start,
+		unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue,
+	) && !unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// AcceptIdent reads and buffers an identifier, whose first rune satisfies
+// IsIdentStart and whose remaining runes satisfy IsIdentContinue. Any runes in
+// extra (e.g. "_" or "_$") are additionally allowed anywhere in the
+// identifier, including at the start. It returns the number of runes
+// accepted, which will be zero if the next rune can't start an identifier.
+// Follows the same error semantics as Accept().
+func (l *Lexer) AcceptIdent(extra string) int {
+	var n int
+	for {
+		r, err := l.peekRune()
+		if err != nil {
+			return n
+		}
+		ok := IsIdentContinue(r)
+		if n == 0 {
+			ok = IsIdentStart(r)
+		}
+		if !ok && !strings.ContainsRune(extra, r) {
+			return n
+		}
+		l.ReadRune()
+		l.BufferRune(r)
+		n++
+	}
+}
@@ -0,0 +1,51 @@
+package lexgo
+
+// WithInterning causes the Lexer to keep a table of every distinct Token value
+// it has emitted, so that Tokens with identical values (keywords, common
+// identifiers, punctuation, etc...) all share a single string allocation. This
+// is a large win when lexing big inputs in which the same values appear over
+// and over, at the cost of the table growing with the number of distinct
+// values seen.
+func WithInterning() Option {
+	return func(l *Lexer) {
+		l.intern = map[string]string{}
+	}
+}
+
+// internBytes returns the string form of b, taking it from the intern table if
+// it's been seen before and adding it if not. If the Lexer isn't interning
+// then this simply allocates a new string
+func (l *Lexer) internBytes(b []byte) string {
+	if l.intern == nil {
+		return l.newString(b)
+	}
+	// the compiler optimizes this map lookup to not allocate for the
+	// conversion
+	if s, ok := l.intern[string(b)]; ok {
+		return s
+	}
+	s := l.newString(b)
+	l.intern[s] = s
+	return s
+}
+
+// newString allocates a string with the contents of b, using the Lexer's Arena
+// if it has one
+func (l *Lexer) newString(b []byte) string {
+	if l.arena != nil {
+		return l.arena.string(b)
+	}
+	return string(b)
+}
+
+// internString is like internBytes, but for a string which has already been
+// allocated
+func (l *Lexer) internString(s string) string {
+	if l.intern == nil {
+		return s
+	} else if is, ok := l.intern[s]; ok {
+		return is
+	}
+	l.intern[s] = s
+	return s
+}
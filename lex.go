@@ -3,20 +3,31 @@
 //
 // It will be helpful to look at the (well documented) example included in this
 // repo in order to really understand how to use this package
+//
+// When built using TinyGo (i.e. with the tinygo build tag) the parts of the
+// package which need go-routines, reflection or runtime/pprof are left out,
+// so that lexers can run on wasm and embedded targets: C, TokenMux, TokenTee,
+// WithReadTimeout, WithIdleFunc and WithProfileLabels. Everything else works
+// the same, except that states are named by their address rather than their
+// function's name in Validator reports, StallErrors and StepResults.
 package lexgo
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
-var (
-	errInvalidUTF8 = errors.New("invalid utf8 character")
-)
+// ErrInvalidUTF8 is the underlying error of any error caused by an invalid utf8
+// character being read. Those errors will be a *PosError wrapping an
+// *InvalidUTF8Error, so errors.Is should be used to check for this
+var ErrInvalidUTF8 = errors.New("invalid utf8 character")
 
 // Enumerator type for different types of tokens. You have to define the actual
 // enumerations yourself
@@ -32,16 +43,59 @@ const (
 	UserDefined
 )
 
+// Warning represents a warning about suspicious, but valid, input, see
+// EmitWarning. Warning Tokens are only ever returned by Lexers using the
+// EmitWarnings option. It is negative so that it stays clear of the user
+// defined range starting at UserDefined.
+const Warning TokenType = -1
+
 // Token represents a single set of characters of the given type. It also
-// includes the row/column the characters started on
+// includes the row/column and byte offset the characters started on
 type Token struct {
 	TokenType
 	Val      string
 	Row, Col int
+	Offset   int
+
+	// Raw is the exact text the Token was made from. It is the same as Val
+	// unless the Lexer was configured to transform values, e.g. using
+	// NormalizeValues or FoldValues
+	Raw string
+
+	// Meta may be used to attach arbitrary extra data to a Token, for example
+	// by an OnEmit hook. The Lexer itself only sets it for Tokens emitted by
+	// helpers which document doing so, e.g. EmitHex
+	Meta interface{}
 
 	// If TokenType == Err this will contain the error being sent back.
-	// Otherwise it will always be nil
+	// Otherwise it will always be nil. For Err Tokens Row/Col/Offset are the
+	// position of the rune most recently read when the error was emitted
 	Err error
+
+	// If TokenType == Warning this will contain the warning, otherwise it will
+	// always be nil. Err is nil on Warning Tokens, so they don't end the stream
+	Warn error
+
+	// Partial is set on Err Tokens which were emitted while a Token was in the
+	// middle of being buffered, e.g. an unterminated string. It holds the
+	// text which had been buffered so far and the position it started at,
+	// which is useful context for error messages. Only its Val, Raw, Row, Col
+	// and Offset fields are set, and Val and Raw may have been truncated (see
+	// WithPartialLimit)
+	Partial *Token
+
+	// Recoverable is set on Err Tokens which don't end the stream, i.e. those
+	// emitted under ResumeAfterError (see EmitRecoverableErr). Consumers
+	// should keep calling Next after one of these
+	Recoverable bool
+
+	// Synthetic is set on Tokens emitted by EmitSynthetic, which stand in for
+	// something implied by the input rather than spelled out in it, e.g. an
+	// automatically inserted semicolon. Their Raw is always empty
+	Synthetic bool
+
+	// set if the Token was taken from tokenPool, see Release
+	pooled bool
 }
 
 // Returns a nice string representation of the token
@@ -49,6 +103,8 @@ func (t *Token) String() string {
 	var s string
 	if t.Err != nil {
 		s = t.Err.Error()
+	} else if t.Warn != nil {
+		s = t.Warn.Error()
 	} else {
 		s = t.Val
 	}
@@ -61,112 +117,557 @@ func (t *Token) String() string {
 type LexerFunc func(*Lexer) LexerFunc
 
 type Lexer struct {
-	r      *bufio.Reader
-	outbuf *bytes.Buffer
-	ch     chan *Token
+	// r is what runes are read from. If r is also an io.ByteScanner then bs is
+	// set to it, and if it is a *bufio.Reader then br is set to it, otherwise
+	// those are nil
+	r  io.RuneScanner
+	bs io.ByteScanner
+	br *bufio.Reader
+
+	outbuf []byte
 	state  LexerFunc
 
-	// row/col the current token being buffered started out. Will be -1 if it
-	// hasn't started yet
-	row, col int
+	// held for the duration of a NextToken call
+	nextL sync.Mutex
+
+	// Tokens which have been Emit()'d but not yet returned by Next(). Tokens
+	// are read off starting at queueHead, and once they've all been read the
+	// slice is truncated so its memory can be reused
+	queue     []Token
+	queueHead int
+
+	// set by WithQueueSize
+	queueSize int
+
+	// set by WithTokenPool
+	pool bool
+
+	// set by WithInterning, nil otherwise
+	intern map[string]string
+
+	// set by WithArena, nil otherwise
+	arena *Arena
+
+	// set by NormalizeValues, nil otherwise
+	normalizer Normalizer
+
+	// set by FoldValues
+	fold bool
+
+	// set by OnEmit
+	onEmit []func(*Token) bool
+
+	// set by WithValidator. trail holds the states which have been started
+	// since the Lexer's progress was last trailAt
+	validator *Validator
+	trail     []uintptr
+	trailAt   progress
+
+	// set by WithStateProfile
+	stateProf *StateProfile
+
+	// set by NewStateLexer. stateID is the StateID of the state to be run
+	// next, and stepOne is set by Step so that only one state is run at a time
+	stateTable *StateTable
+	stateID    StateID
+	stepOne    bool
+
+	// set by WithStallLimit. stalled is the number of consecutive states which
+	// have been run without making progress
+	stallLimit, stalled int
+
+	// set by WithMaxSteps
+	maxSteps int
+
+	// set by WithPartialLimit
+	partialLimit int
+
+	// set by EmitWarnings
+	warnings bool
+
+	// set by WithErrorTemplate and WithStateErrorTemplate, the latter keyed by
+	// funcPC
+	typeTemplates  map[TokenType]string
+	stateTemplates map[uintptr]string
+
+	// set by OnRead. readPending indicates that pendingRune was read by
+	// ReadRune, but hasn't been passed to the hooks yet in case UnreadRune is
+	// called. readBuf is used for encoding runes to pass to the hooks
+	onRead      []func([]byte)
+	readPending bool
+	pendingRune rune
+	readBuf     []byte
+
+	// used by C
+	conc concurrent
+
+	// set by WithASCIIFastPath. lastByte indicates that the most recent rune
+	// was read using the fast path, and so must be unread as a byte
+	ascii, lastByte bool
+
+	// set by BinaryMode
+	binary bool
+
+	// set by NoPositions
+	noPos bool
+
+	// set by UnicodeNewlines
+	uniNL bool
+
+	// set by WithPositionBase, added to rows and columns as they are reported
+	rowAdj, colAdj int
+
+	// set by SetStartPosition
+	source string
+
+	// set by MarkSynthetic, sorted by start offset. inputOff is the true offset
+	// in the input of the next rune to be read
+	synth    []offRange
+	inputOff int
 
-	// row/col of the rune most recently read. These are never reset (except
-	// col, when a newline is reached)
-	absRow, absCol int
+	// set by WithPositionTracker, RuneColumns otherwise
+	tracker PositionTracker
+
+	// set by RetainLines
+	lines *lineWindow
+
+	// set by LineContinuation
+	cont string
+
+	// set by WithProfileLabels. profStates holds the context labeled for each
+	// state run so far, keyed by funcPC
+	profCtx    context.Context
+	profStates map[uintptr]context.Context
+
+	// set by WithReadTimeout and WithIdleFunc
+	readTimeout time.Duration
+	idle        time.Duration
+	idleFn      func()
+	async       *asyncReader
+
+	// set by ReadRune, and unset by any other reading, to indicate that
+	// UnreadRune may be called, and what the position should be reset to if
+	// it is
+	canUnread bool
+	unread    readMark
+
+	// set by ResumeAfterError
+	resume bool
+
+	// an error encountered by peekRune, which will be returned by the next
+	// read instead of actually reading
+	heldErr error
+
+	// runes which have been read off r ahead of the current position by
+	// lookahead, or put back by Backup. lastAhead indicates that the most
+	// recent rune was taken from here, and lastRead is what to put back if
+	// it's unread. aheadBytes is used by peekAheadBytes
+	ahead      aheadRing
+	lastAhead  bool
+	lastRead   aheadRune
+	aheadBytes []byte
+
+	// set by WithBackupLimit. behind holds the runes most recently consumed
+	backupLimit int
+	behind      pastRing
+
+	// the rune most recently consumed and its encoded size, see LastRune
+	lastRune  rune
+	lastWidth int
+
+	// row/col/offset the current token being buffered started out. row and
+	// col will be -1 if it hasn't started yet
+	row, col, off int
+
+	// row/col of the rune most recently read, as maintained by tracker, and
+	// offset of the rune most recently read. nextOff is the offset of the rune
+	// which will be read next
+	cur             Cursor
+	absOff, nextOff int
 }
 
 // NewLexer constructs a new Lexer struct and returns it. r is internally
-// wrapped with a bufio.Reader, unless it already is one. firstFunc is the
-// LexerFunc which should be run on the first invocation of Next()
-func NewLexer(r io.Reader, firstFunc LexerFunc) *Lexer {
-	var br *bufio.Reader
-	var ok bool
-	if br, ok = r.(*bufio.Reader); !ok {
-		br = bufio.NewReader(r)
+// wrapped with a bufio.Reader, unless it is already an io.RuneScanner (e.g. a
+// bufio.Reader or strings.Reader), in which case it is used directly. firstFunc
+// is the LexerFunc which should be run on the first invocation of Next(). Any
+// given Options are applied to the Lexer before it is returned
+func NewLexer(r io.Reader, firstFunc LexerFunc, opts ...Option) *Lexer {
+	l := Lexer{
+		queueSize:    1,
+		partialLimit: defaultPartialLimit,
+		outbuf:       make([]byte, 0, 1024),
+		state:        firstFunc,
+		row:          -1,
+		col:          -1,
+		tracker:      RuneColumns(),
+		stallLimit:   defaultStallLimit,
+		backupLimit:  defaultBackupLimit,
+		cur:          startCursor,
 	}
 
-	l := Lexer{
-		r:      br,
-		ch:     make(chan *Token, 1),
-		outbuf: bytes.NewBuffer(make([]byte, 0, 1024)),
-		state:  firstFunc,
-		row:    -1,
-		col:    -1,
-		absRow: 1,
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	l.queue = make([]Token, 0, l.queueSize)
+	if l.backupLimit > 0 {
+		l.behind.buf = make([]pastRune, l.backupLimit)
+	}
+
+	if l.lines != nil {
+		l.lines.ra, _ = r.(io.ReaderAt)
+		l.lines.reset(l.cur)
 	}
 
+	if l.readTimeout > 0 || l.idle > 0 {
+		l.async = newAsyncReader(r, &l)
+		r = l.async
+	}
+
+	rs, ok := r.(io.RuneScanner)
+	_, isBS := r.(io.ByteScanner)
+	if _, isBR := r.(*bufio.Reader); !ok || (l.cont != "" && !isBR) || (l.binary && !isBS) {
+		rs = bufio.NewReader(r)
+	}
+	l.r = rs
+	l.bs, _ = rs.(io.ByteScanner)
+	l.br, _ = rs.(*bufio.Reader)
+
 	return &l
 }
 
-// Returns the next Token Emit()'d
+// Returns the next Token Emit()'d. It is safe to call Next() from multiple
+// go-routines at once, each Token will only be returned to one of them. The
+// other methods on Lexer, which are intended to be used from within
+// LexerFuncs, are not safe to call concurrently with Next()
 func (l *Lexer) Next() *Token {
-	for {
-		select {
-		case t := <-l.ch:
-			return t
-		default:
-			if l.state == nil {
-				l.EmitErr(io.EOF)
+	return l.tokenPtr(l.NextToken())
+}
+
+// NextToken is like Next, but returns the Token by value rather than by
+// pointer. This saves a heap allocation per Token for consumers which don't
+// need to hold onto Tokens long-term. Like Next(), it is safe to call from
+// multiple go-routines
+func (l *Lexer) NextToken() Token {
+	l.nextL.Lock()
+	defer l.nextL.Unlock()
+	for steps := 0; ; steps++ {
+		if l.queueHead < len(l.queue) {
+			t := l.queue[l.queueHead]
+			l.queue[l.queueHead] = Token{}
+			if l.queueHead++; l.queueHead == len(l.queue) {
+				l.queue, l.queueHead = l.queue[:0], 0
 			}
-			l.state = l.state(l)
+			return t
+		} else if l.state == nil {
+			l.commitRead()
+			l.EmitErr(io.EOF)
+			continue
+		} else if l.maxSteps > 0 && steps >= l.maxSteps {
+			l.EmitErr(ErrMaxSteps)
+			l.endState()
+			continue
+		}
+		if l.state = l.step(l.state); l.state == nil {
+			l.endState()
 		}
 	}
 }
 
+// endState marks the Lexer as having no more states to run. Since nothing more
+// will be read it also lets the async reader's go-routine exit, if there is one
+func (l *Lexer) endState() {
+	l.state = nil
+	l.async.stop()
+}
+
+// NextInto is like NextToken, but fills in the given caller-owned Token rather
+// than returning one, so that hot loops can reuse a single Token value and
+// avoid per-Token allocations altogether:
+//
+//	var tok lexgo.Token
+//	for l.NextInto(&tok) {
+//		...
+//	}
+//	if tok.Err != io.EOF {
+//		...
+//	}
+//
+// It returns false if the Token filled in is an Err Token which ends the
+// stream. Recoverable Err Tokens are filled in and true is returned, so the
+// loop body should check tok.Err if ResumeAfterError is used.
+func (l *Lexer) NextInto(tok *Token) bool {
+	*tok = l.NextToken()
+	return !tok.EndsStream()
+}
+
 // Declares that the data buffered thusfar constitutes a Token. This will emit
-// that Token to the next call of Next() and reset the buffer
+// that Token to the next call of Next() and reset the buffer. A LexerFunc may
+// Emit() any number of Tokens in a single invocation, they will all be
+// returned by Next() in order before the next LexerFunc is run
 func (l *Lexer) Emit(t TokenType) {
-	str := l.outbuf.String()
-	l.ch <- &Token{
+	l.emit(t, nil)
+}
+
+// emit is Emit, but with the Meta field of the Token set to the given value
+// before it's passed to any hooks
+func (l *Lexer) emit(t TokenType, meta interface{}) {
+	raw := l.internBytes(l.outbuf)
+	tok := Token{
 		TokenType: t,
-		Val:       str,
-		Row:       l.row,
-		Col:       l.col,
+		Val:       raw,
+		Raw:       raw,
+		Offset:    l.off,
+		Meta:      meta,
+	}
+	tok.Row, tok.Col = l.reportPos(l.row, l.col)
+	if l.normalizer != nil {
+		tok.Val = l.internString(l.normalizer.String(tok.Val))
+	}
+	if l.fold {
+		tok.Val = l.internString(FoldCase(tok.Val))
+	}
+	for _, fn := range l.onEmit {
+		if !fn(&tok) {
+			l.resetBuffer()
+			return
+		}
+	}
+	l.queue = append(l.queue, tok)
+	l.resetBuffer()
+}
+
+// resetBuffer clears the output buffer in preparation for the next Token
+func (l *Lexer) resetBuffer() {
+	l.outbuf = l.outbuf[:0]
+	if !l.noPos {
+		l.row, l.col = -1, -1
 	}
-	l.outbuf.Reset()
-	l.row, l.col = -1, -1
 }
 
 // Used to Emit() and error which has occured. This will not affect the output
-// buffer. It is not necessary to call on errors returned from ReadRune() or
-// PeekRune()
+// buffer. The Token's position will be that of the rune most recently read,
+// unless err is or wraps a *PosError or *Diagnostic, in which case it will be
+// the position of that. If anything is currently buffered it is attached to
+// the Token as its Partial field. It is not necessary to call on errors
+// returned from ReadRune() or PeekRune()
 func (l *Lexer) EmitErr(err error) {
-	l.ch <- &Token{
+	row, col, off := l.errPos(err)
+	l.queue = append(l.queue, Token{
 		TokenType: Err,
+		Row:       row,
+		Col:       col,
+		Offset:    off,
 		Err:       err,
-	}
+		Partial:   l.partial(),
+	})
 }
 
-// Returns the next rune in the byte stream. If an error is returned it will
-// have already been Emit()'d as an Err Token, but further handling can be done
-// if necessary
-func (l *Lexer) ReadRune() (rune, error) {
-	r, err := l.readRune()
+// Returns the next rune in the byte stream, along with its size in bytes. If an
+// error is returned it will have already been Emit()'d as an Err Token, but
+// further handling can be done if necessary.
+//
+// Along with UnreadRune this makes Lexer an io.RuneScanner, so generic
+// utilities which consume runes can operate directly on the Lexer's
+// position-tracked stream
+func (l *Lexer) ReadRune() (rune, int, error) {
+	r, size, err := l.readRune()
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-
-	if r == '\n' {
-		l.absRow++
-		l.absCol = 0
+	l.unread = l.mark()
+	l.canUnread = true
+	if l.lastAhead && l.lastRead.replay {
+		l.replay(r, size)
 	} else {
-		l.absCol++
+		l.advance(r, size)
 	}
+	return r, size, nil
+}
 
-	return r, nil
+// UnreadRune causes the rune most recently returned from ReadRune() to be
+// returned again by the next read, and moves the Lexer's position back
+// accordingly. As with io.RuneScanner, it can only be called directly after a
+// ReadRune(), with no other reading (including peeking) in between. It does
+// not affect the output buffer
+func (l *Lexer) UnreadRune() error {
+	if !l.canUnread {
+		return bufio.ErrInvalidUnreadRune
+	}
+	replayed := l.lastAhead && l.lastRead.replay
+	if err := l.unreadRune(); err != nil {
+		return err
+	}
+	l.setMark(l.unread)
+	l.canUnread, l.readPending = false, false
+	if l.behind.n > 0 {
+		l.behind.n--
+	}
+	if l.lines != nil && !replayed {
+		l.lines.unread()
+	}
+	return nil
+}
+
+var _ io.RuneScanner = new(Lexer)
+
+// LastRune returns the rune most recently consumed, whether by ReadRune or by
+// helpers like AcceptRun and SkipWhile, or 0 if nothing has been consumed yet.
+// UnreadRune and Backup move this back along with the position. This saves
+// LexerFuncs from having to keep track of what they last read themselves, e.g.
+// to check for a word boundary or to quote the offending character in an
+// error.
+func (l *Lexer) LastRune() rune {
+	return l.lastRune
+}
+
+// LastWidth returns the encoded size in bytes of the rune returned by
+// LastRune, or 0 if nothing has been consumed yet, which tells apart a NUL
+// rune having been read
+func (l *Lexer) LastWidth() int {
+	return l.lastWidth
+}
+
+// advance updates the absolute position of the Lexer to account for r, whose
+// encoded size is the given size, having been read, and reports it to any
+// OnRead hooks
+func (l *Lexer) advance(r rune, size int) {
+	if l.backupLimit > 0 {
+		l.behind.push(pastRune{r: r, size: size, before: l.mark()})
+	}
+	l.observe(r, size)
+	l.move(r, size)
+	l.lastRune, l.lastWidth = r, size
+}
+
+// replay is advance for a rune which was put back by Backup. Its position is
+// restored rather than worked out again, and it isn't reported to any OnRead
+// hooks a second time
+func (l *Lexer) replay(r rune, size int) {
+	if l.backupLimit > 0 {
+		l.behind.push(pastRune{r: r, size: size, before: l.mark()})
+	}
+	l.setMark(l.lastRead.after)
 }
 
-func (l *Lexer) readRune() (rune, error) {
-	r, i, err := l.r.ReadRune()
+// move is like advance, but only updates the position, without reporting r to
+// any OnRead hooks
+func (l *Lexer) move(r rune, size int) {
+	l.moveNL(r, size, l.IsNewline(r))
+}
+
+// moveNL is like move, but with whether r is to be considered a line break
+// given explicitly
+func (l *Lexer) moveNL(r rune, size int, newline bool) {
+	if l.lines != nil {
+		l.lines.add(r, size, newline)
+	}
+	// skipSynthetic is always called, even with noPos, so that the true input
+	// offset stays correct regardless of when ranges get marked
+	if l.skipSynthetic(size) || l.noPos {
+		return
+	}
+	l.absOff = l.nextOff
+	l.nextOff += size
+	l.cur = l.tracker.Advance(l.cur, r, size, newline)
+}
+
+// readRune reads the next rune off the reader, emitting any error encountered
+func (l *Lexer) readRune() (rune, int, error) {
+	r, size, err := l.decodeRune()
 	if err != nil {
 		l.EmitErr(err)
-		return 0, err
-	} else if r == unicode.ReplacementChar && i == 1 {
-		l.EmitErr(errInvalidUTF8)
-		return 0, errInvalidUTF8
+		return 0, 0, err
 	}
+	return r, size, nil
+}
 
-	return r, nil
+// decodeRune reads the next rune off the reader, returning but not emitting
+// any error encountered
+func (l *Lexer) decodeRune() (rune, int, error) {
+	l.commitRead()
+	if err := l.heldErr; err != nil {
+		l.heldErr = nil
+		return 0, 0, err
+	}
+
+	l.lastAhead = false
+	if l.ahead.n > 0 {
+		return l.decodeAhead()
+	}
+
+	l.skipContinuations()
+
+	if l.binary {
+		b, err := l.bs.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		l.lastByte = true
+		return rune(b), 1, nil
+	} else if l.ascii && l.bs != nil {
+		b, err := l.bs.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		} else if b < utf8.RuneSelf {
+			l.lastByte = true
+			return rune(b), 1, nil
+		}
+		// We know this will succeed, since we just read the byte
+		l.bs.UnreadByte()
+	}
+
+	l.lastByte = false
+	r, size, err := l.r.ReadRune()
+	if err != nil {
+		return 0, 0, err
+	} else if r == unicode.ReplacementChar && size == 1 {
+		b := l.invalidBytes()
+		if l.resume {
+			size = l.discardInvalid(b)
+		}
+		return l.decodeInvalid(b, size)
+	}
+
+	return r, size, nil
+}
+
+// discardInvalid discards the rest of the invalid utf8 character made up of
+// the given bytes, only the first of which has been read, so that a truncated
+// or otherwise malformed multi-byte character only produces one error. It
+// returns the size of the character
+func (l *Lexer) discardInvalid(b []byte) int {
+	if len(b) <= 1 {
+		return 1
+	}
+	l.br.Discard(len(b) - 1)
+	return len(b)
+}
+
+// decodeInvalid is called by decodeRune when the next character in the stream
+// is invalid utf8, made up of the given bytes, and returns what decodeRune
+// should. With ResumeAfterError the character is skipped over
+func (l *Lexer) decodeInvalid(b []byte, size int) (rune, int, error) {
+	err := l.invalidUTF8Err(b)
+	if !l.resume {
+		return 0, 0, err
+	}
+	l.EmitRecoverableErr(err)
+	l.observeInvalid(err)
+	l.move(unicode.ReplacementChar, size)
+	return l.decodeRune()
+}
+
+// unreadRune undoes the most recent decodeRune call
+func (l *Lexer) unreadRune() error {
+	if l.lastAhead {
+		l.lastAhead = false
+		l.ahead.pushFront(l.lastRead)
+		return nil
+	} else if l.lastByte {
+		return l.bs.UnreadByte()
+	}
+	return l.r.UnreadRune()
 }
 
 // Returns the next rune which will appear in the byte stream without advancing
@@ -174,25 +675,58 @@ func (l *Lexer) readRune() (rune, error) {
 // the same rune over and over, instead of returning sequential runes in the
 // stream. Follows the same error semantics as ReadRune()
 func (l *Lexer) PeekRune() (rune, error) {
-	r, err := l.readRune()
+	r, _, err := l.readRune()
 	if err != nil {
 		// No need to emitErr here, ReadRune already did it
 		return 0, err
 	}
-	if err = l.r.UnreadRune(); err != nil {
+	if err = l.unreadRune(); err != nil {
 		l.EmitErr(err)
 		return 0, err
 	}
 	return r, nil
 }
 
+// peekRune is like PeekRune, except that an error encountered is not emitted.
+// It is instead held onto and returned (and emitted) by the next ReadRune or
+// PeekRune call, so that helpers can look ahead without getting in the way of
+// the LexerFunc's own error handling
+func (l *Lexer) peekRune() (rune, error) {
+	r, _, err := l.decodeRune()
+	if err != nil {
+		l.heldErr = err
+		return 0, err
+	}
+	if err = l.unreadRune(); err != nil {
+		l.heldErr = err
+		return 0, err
+	}
+	return r, nil
+}
+
+// BufferString returns the contents of the output buffer, i.e. everything which
+// has been buffered since the last Emit()
+func (l *Lexer) BufferString() string {
+	return string(l.outbuf)
+}
+
+// Ignore clears the output buffer without emitting it, for when what has been
+// buffered (whitespace, comments, etc...) isn't wanted as a Token
+func (l *Lexer) Ignore() {
+	l.resetBuffer()
+}
+
 // Appends the given rune to the output buffer. When a full Token has been
 // collected in this buffer Emit() can be used to emit that Token and clear the
 // buffer at the same time
 func (l *Lexer) BufferRune(r rune) {
-	l.outbuf.WriteRune(r)
+	if l.binary && r <= 0xff {
+		l.outbuf = append(l.outbuf, byte(r))
+	} else {
+		l.outbuf = utf8.AppendRune(l.outbuf, r)
+	}
 
 	if l.row < 0 && l.col < 0 {
-		l.row, l.col = l.absRow, l.absCol
+		l.row, l.col, l.off = l.cur.Row, l.cur.Col, l.absOff
 	}
 }
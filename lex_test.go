@@ -0,0 +1,74 @@
+package lexgo
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestNextConcurrent calls Next, NextToken and NextInto from many go-routines
+// at once, and checks that every Token is delivered to exactly one of them. It
+// is most useful when run with -race.
+func TestNextConcurrent(t *testing.T) {
+	const n = 10000
+	i := 0
+	var fn LexerFunc
+	fn = func(l *Lexer) LexerFunc {
+		if i == n {
+			return nil
+		}
+		l.outbuf = strconv.AppendInt(l.outbuf, int64(i), 10)
+		l.Emit(UserDefined)
+		i++
+		return fn
+	}
+	l := NewLexer(nil, fn)
+
+	var mu sync.Mutex
+	seen := make([]int, n)
+	record := func(tok Token) bool {
+		if tok.Err != nil {
+			return false
+		}
+		v, err := strconv.Atoi(tok.Val)
+		if err != nil {
+			t.Errorf("unexpected Token %q", tok.Val)
+			return false
+		}
+		mu.Lock()
+		seen[v]++
+		mu.Unlock()
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for {
+				var ok bool
+				switch g % 3 {
+				case 0:
+					ok = record(*l.Next())
+				case 1:
+					ok = record(l.NextToken())
+				default:
+					var tok Token
+					l.NextInto(&tok)
+					ok = record(tok)
+				}
+				if !ok {
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for v, c := range seen {
+		if c != 1 {
+			t.Errorf("Token %d delivered %d times", v, c)
+		}
+	}
+}
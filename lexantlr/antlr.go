@@ -0,0 +1,891 @@
+// Package lexantlr imports ANTLR 4 lexer grammars as lexrule Specs, so that
+// existing grammars can be used to bootstrap lexgo based tokenizers.
+//
+// Lexer rules, fragments and modes are supported, along with string literals,
+// ranges ('a'..'z'), sets ([a-z]), wildcards, negation (~), grouping, and
+// optional, repeated and non-greedy elements. Of the lexer commands, skip,
+// type, pushMode, popMode and mode are supported, and channel is ignored, so
+// that Tokens on other channels are emitted like any other. Embedded actions,
+// options, tokens and channels blocks are ignored. Parser rules in combined
+// grammars are ignored, along with any literals they use implicitly. Semantic
+// predicates, the more command, and grammar imports aren't supported.
+//
+// The resulting Spec uses MaximalMunch, as ANTLR does, and each mode other than
+// the default one becomes a mode of the same name. mode(X) switches mode by
+// popping the current one, if it isn't the default mode, and pushing X, so
+// mixing it with pushMode and popMode may not behave the same as in ANTLR.
+// Rules using non-greedy elements are Lazy. Each distinct rule name (or name
+// given to type) is given its own TokenType, starting at lexgo.UserDefined, in
+// the order the names first appear.
+package lexantlr
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mediocregopher/lexgo"
+	"github.com/mediocregopher/lexgo/lexrule"
+)
+
+// Parse reads an ANTLR 4 lexer grammar from r and converts it to a Spec
+func Parse(r io.Reader) (lexrule.Spec, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return lexrule.Spec{}, err
+	}
+	toks, err := scan(string(src))
+	if err != nil {
+		return lexrule.Spec{}, err
+	}
+
+	p := &parser{toks: toks, rules: map[string]*grammarRule{}}
+	if err := p.parseGrammar(); err != nil {
+		return lexrule.Spec{}, err
+	}
+	return p.spec()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// scanning
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tIdent
+	tString // the contents of a '' literal, still escaped
+	tSet    // the contents of a [] set, still escaped
+	tAction // a {} block, including its braces
+	tPunct
+)
+
+type tok struct {
+	kind tokKind
+	val  string
+	line int
+}
+
+func scan(src string) ([]tok, error) {
+	var toks []tok
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			toks = append(toks, tok{tIdent, src[start:i], line})
+
+		case c == '\'' || c == '[':
+			end, kind := byte('\''), tString
+			if c == '[' {
+				end, kind = ']', tSet
+			}
+			start := i + 1
+			for i++; i < len(src) && src[i] != end; i++ {
+				if src[i] == '\\' {
+					i++
+				} else if src[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated %c", line, c)
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated %c", line, c)
+			}
+			toks = append(toks, tok{kind, src[start:i], line})
+			i++
+
+		case c == '{':
+			start, startLine, depth := i, line, 0
+			for ; i < len(src); i++ {
+				if src[i] == '{' {
+					depth++
+				} else if src[i] == '}' {
+					if depth--; depth == 0 {
+						break
+					}
+				} else if src[i] == '\n' {
+					line++
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated {", startLine)
+			}
+			i++
+			toks = append(toks, tok{tAction, src[start:i], startLine})
+
+		case strings.HasPrefix(src[i:], ".."), strings.HasPrefix(src[i:], "->"),
+			strings.HasPrefix(src[i:], "::"):
+			toks = append(toks, tok{tPunct, src[i : i+2], line})
+			i += 2
+		case strings.ContainsRune(":;|()*+?~.,=#@<>", rune(c)):
+			toks = append(toks, tok{tPunct, src[i : i+1], line})
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	return append(toks, tok{tEOF, "", line}), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// parsing
+
+type nodeKind int
+
+const (
+	nLit nodeKind = iota
+	nRange
+	nSet
+	nAny
+	nNot
+	nAlt
+	nSeq
+	nRef
+	nRepeat
+)
+
+// node is an element of a rule's definition. val holds the literal (still
+// escaped), set contents or rule name, depending on kind
+type node struct {
+	kind   nodeKind
+	val    string
+	lo, hi string
+	kids   []*node
+	op     string
+	line   int
+}
+
+// command is a lexer command, e.g. pushMode(STR)
+type command struct {
+	name, arg string
+}
+
+// alt is one of a rule's top-level alternatives, along with its commands
+type alt struct {
+	n    *node
+	cmds []command
+}
+
+type grammarRule struct {
+	name     string
+	mode     string
+	fragment bool
+	alts     []alt
+	line     int
+}
+
+type parser struct {
+	toks []tok
+	pos  int
+
+	order []*grammarRule
+	rules map[string]*grammarRule
+	modes []string
+}
+
+func (p *parser) peek() tok { return p.toks[p.pos] }
+
+func (p *parser) next() tok {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(val string) bool {
+	t := p.peek()
+	return (t.kind == tPunct || t.kind == tIdent) && t.val == val
+}
+
+func errorf(t tok, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(val string) error {
+	if t := p.next(); t.val != val || (t.kind != tPunct && t.kind != tIdent) {
+		return errorf(t, "expected %q, got %q", val, t.val)
+	}
+	return nil
+}
+
+func (p *parser) ident() (tok, error) {
+	t := p.next()
+	if t.kind != tIdent {
+		return t, errorf(t, "expected name, got %q", t.val)
+	}
+	return t, nil
+}
+
+func (p *parser) parseGrammar() error {
+	if p.is("lexer") || p.is("parser") {
+		if p.next().val == "parser" {
+			return errorf(p.peek(), "parser grammars contain no lexer rules")
+		}
+	}
+	if err := p.expect("grammar"); err != nil {
+		return err
+	} else if _, err := p.ident(); err != nil {
+		return err
+	} else if err := p.expect(";"); err != nil {
+		return err
+	}
+
+	var mode string
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tEOF:
+			return nil
+
+		case t.kind == tIdent && (t.val == "options" || t.val == "tokens" || t.val == "channels"):
+			p.next()
+			if p.next().kind != tAction {
+				return errorf(t, "expected { after %s", t.val)
+			}
+
+		case t.kind == tPunct && t.val == "@":
+			for p.next().kind != tAction {
+				if p.peek().kind == tEOF {
+					return errorf(t, "expected { after @")
+				}
+			}
+
+		case t.kind == tIdent && t.val == "import":
+			return errorf(t, "grammar imports aren't supported")
+
+		case t.kind == tIdent && t.val == "mode":
+			p.next()
+			name, err := p.ident()
+			if err != nil {
+				return err
+			} else if err := p.expect(";"); err != nil {
+				return err
+			}
+			mode = name.val
+			p.modes = append(p.modes, mode)
+
+		case t.kind == tIdent:
+			if err := p.parseRule(mode); err != nil {
+				return err
+			}
+
+		default:
+			return errorf(t, "unexpected %q", t.val)
+		}
+	}
+}
+
+func (p *parser) parseRule(mode string) error {
+	r := &grammarRule{mode: mode, line: p.peek().line}
+	if p.is("fragment") {
+		p.next()
+		r.fragment = true
+	}
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	r.name = name.val
+
+	// Parser rules start with a lower case letter, and are skipped
+	if first, _ := utf8.DecodeRuneInString(r.name); !unicode.IsUpper(first) {
+		for depth := 0; ; {
+			t := p.next()
+			switch {
+			case t.kind == tEOF:
+				return errorf(name, "unterminated rule %q", r.name)
+			case t.kind == tPunct && t.val == "(":
+				depth++
+			case t.kind == tPunct && t.val == ")":
+				depth--
+			case t.kind == tPunct && t.val == ";" && depth == 0:
+				return nil
+			}
+		}
+	}
+
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+	for {
+		n, err := p.parseSeq()
+		if err != nil {
+			return err
+		}
+		a := alt{n: n}
+		if p.is("->") {
+			p.next()
+			if a.cmds, err = p.parseCommands(); err != nil {
+				return err
+			}
+		}
+		r.alts = append(r.alts, a)
+		if !p.is("|") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(";"); err != nil {
+		return err
+	}
+
+	if _, ok := p.rules[r.name]; ok {
+		return errorf(name, "rule %q defined twice", r.name)
+	}
+	p.rules[r.name] = r
+	p.order = append(p.order, r)
+	return nil
+}
+
+func (p *parser) parseCommands() ([]command, error) {
+	var cmds []command
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		cmd := command{name: name.val}
+		if p.is("(") {
+			p.next()
+			arg, err := p.ident()
+			if err != nil {
+				return nil, err
+			} else if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			cmd.arg = arg.val
+		}
+		cmds = append(cmds, cmd)
+		if !p.is(",") {
+			return cmds, nil
+		}
+		p.next()
+	}
+}
+
+// parseAlts parses alternatives within parentheses
+func (p *parser) parseAlts() (*node, error) {
+	n := &node{kind: nAlt, line: p.peek().line}
+	for {
+		kid, err := p.parseSeq()
+		if err != nil {
+			return nil, err
+		} else if p.is("->") {
+			return nil, errorf(p.peek(), "commands within parentheses aren't supported")
+		}
+		n.kids = append(n.kids, kid)
+		if !p.is("|") {
+			return n, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseSeq() (*node, error) {
+	n := &node{kind: nSeq, line: p.peek().line}
+	for {
+		if p.is("|") || p.is(")") || p.is(";") || p.is("->") {
+			return n, nil
+		}
+		kid, err := p.parseElement()
+		if err != nil {
+			return nil, err
+		} else if kid != nil {
+			n.kids = append(n.kids, kid)
+		}
+	}
+}
+
+func (p *parser) parseElement() (*node, error) {
+	t := p.peek()
+	if t.kind == tAction {
+		p.next()
+		if p.is("?") {
+			return nil, errorf(t, "semantic predicates aren't supported")
+		}
+		return nil, nil
+	}
+
+	n, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	if p.is("*") || p.is("+") || p.is("?") {
+		n = &node{kind: nRepeat, op: p.next().val, kids: []*node{n}, line: t.line}
+		if p.is("?") {
+			n.op += p.next().val
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseAtom() (*node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tString:
+		if p.is("..") {
+			p.next()
+			hi := p.next()
+			if hi.kind != tString {
+				return nil, errorf(hi, "expected literal after ..")
+			}
+			return &node{kind: nRange, lo: t.val, hi: hi.val, line: t.line}, nil
+		}
+		return &node{kind: nLit, val: t.val, line: t.line}, nil
+	case t.kind == tSet:
+		return &node{kind: nSet, val: t.val, line: t.line}, nil
+	case t.kind == tIdent:
+		return &node{kind: nRef, val: t.val, line: t.line}, nil
+	case t.kind == tPunct && t.val == ".":
+		return &node{kind: nAny, line: t.line}, nil
+	case t.kind == tPunct && t.val == "~":
+		kid, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nNot, kids: []*node{kid}, line: t.line}, nil
+	case t.kind == tPunct && t.val == "(":
+		n, err := p.parseAlts()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	default:
+		return nil, errorf(t, "unexpected %q", t.val)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// conversion
+
+// converter translates rules into regexp syntax
+type converter struct {
+	rules map[string]*grammarRule
+
+	// inProgress holds the rules currently being converted, to catch
+	// recursion
+	inProgress map[string]bool
+
+	// lazy is set once a non-greedy element has been converted
+	lazy bool
+}
+
+func (c *converter) regex(n *node) (string, error) {
+	switch n.kind {
+	case nLit:
+		s, err := unescape(n.val)
+		return regexp.QuoteMeta(s), err
+
+	case nRange, nSet, nNot:
+		body, err := c.class(n)
+		if err != nil {
+			return "", err
+		}
+		return "[" + body + "]", nil
+
+	case nAny:
+		return "(?s:.)", nil
+
+	case nAlt:
+		parts := make([]string, len(n.kids))
+		for i, kid := range n.kids {
+			var err error
+			if parts[i], err = c.regex(kid); err != nil {
+				return "", err
+			}
+		}
+		return "(?:" + strings.Join(parts, "|") + ")", nil
+
+	case nSeq:
+		var b strings.Builder
+		for _, kid := range n.kids {
+			s, err := c.regex(kid)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		}
+		return b.String(), nil
+
+	case nRef:
+		r, ok := c.rules[n.val]
+		if !ok {
+			return "", fmt.Errorf("line %d: unknown rule %q", n.line, n.val)
+		} else if c.inProgress[n.val] {
+			return "", fmt.Errorf("line %d: recursive rule %q isn't supported", n.line, n.val)
+		}
+		c.inProgress[n.val] = true
+		defer delete(c.inProgress, n.val)
+
+		alts := &node{kind: nAlt, line: n.line}
+		for _, a := range r.alts {
+			if len(a.cmds) > 0 {
+				return "", fmt.Errorf("line %d: rule %q has commands, and can't be referenced", n.line, n.val)
+			}
+			alts.kids = append(alts.kids, a.n)
+		}
+		return c.regex(alts)
+
+	case nRepeat:
+		s, err := c.regex(n.kids[0])
+		if err != nil {
+			return "", err
+		}
+		if strings.HasSuffix(n.op, "?") && len(n.op) == 2 {
+			c.lazy = true
+		}
+		return "(?:" + s + ")" + n.op, nil
+	}
+	panic(fmt.Sprintf("unknown node kind %d", n.kind))
+}
+
+// class returns the contents of a character class matching what n does, with
+// a leading ^ for nNot (whose contents are those of what it negates). Only
+// nodes which match a single character can be converted
+func (c *converter) class(n *node) (string, error) {
+	switch n.kind {
+	case nLit, nRange:
+		lo, hi := n.val, n.val
+		if n.kind == nRange {
+			lo, hi = n.lo, n.hi
+		}
+		loR, err := unescapeRune(lo)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", n.line, err)
+		}
+		hiR, err := unescapeRune(hi)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", n.line, err)
+		}
+		if loR == hiR {
+			return classRune(loR), nil
+		}
+		return classRune(loR) + "-" + classRune(hiR), nil
+
+	case nSet:
+		s, err := set(n.val)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", n.line, err)
+		}
+		return s, nil
+
+	case nNot:
+		s, err := c.class(n.kids[0])
+		if err != nil {
+			return "", err
+		} else if strings.HasPrefix(s, "^") {
+			return "", fmt.Errorf("line %d: double negation isn't supported", n.line)
+		}
+		return "^" + s, nil
+
+	case nAlt, nSeq:
+		if n.kind == nSeq && len(n.kids) != 1 {
+			break
+		}
+		var b strings.Builder
+		for _, kid := range n.kids {
+			s, err := c.class(kid)
+			if err != nil {
+				return "", err
+			} else if strings.HasPrefix(s, "^") {
+				return "", fmt.Errorf("line %d: negated sets can't be combined", n.line)
+			}
+			b.WriteString(s)
+		}
+		return b.String(), nil
+
+	case nRef:
+		r, ok := c.rules[n.val]
+		if !ok {
+			return "", fmt.Errorf("line %d: unknown rule %q", n.line, n.val)
+		} else if c.inProgress[n.val] {
+			return "", fmt.Errorf("line %d: recursive rule %q isn't supported", n.line, n.val)
+		}
+		c.inProgress[n.val] = true
+		defer delete(c.inProgress, n.val)
+
+		alts := &node{kind: nAlt, line: n.line}
+		for _, a := range r.alts {
+			alts.kids = append(alts.kids, a.n)
+		}
+		return c.class(alts)
+	}
+	return "", fmt.Errorf("line %d: ~ can only be applied to sets of single characters", n.line)
+}
+
+// classRune renders r for use within a character class
+func classRune(r rune) string {
+	switch {
+	case r == '\n':
+		return `\n`
+	case r == '\t':
+		return `\t`
+	case r == '\r':
+		return `\r`
+	case r < utf8.RuneSelf && (unicode.IsPunct(r) || unicode.IsSymbol(r)):
+		return `\` + string(r)
+	case unicode.IsPrint(r):
+		return string(r)
+	default:
+		return fmt.Sprintf(`\x{%x}`, r)
+	}
+}
+
+// set converts the contents of an ANTLR set to those of a character class
+func set(s string) (string, error) {
+	var b strings.Builder
+	var prev rune = -1
+	for i := 0; i < len(s); {
+		if s[i] == '-' && prev >= 0 && i+1 < len(s) {
+			r, n, err := setRune(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("-" + classRune(r))
+			i, prev = i+1+n, -1
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], `\p`) || strings.HasPrefix(s[i:], `\P`) {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated %s", s[i:i+2])
+			}
+			b.WriteString(s[i : i+end+1])
+			i, prev = i+end+1, -1
+			continue
+		}
+
+		r, n, err := setRune(s[i:])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(classRune(r))
+		i, prev = i+n, r
+	}
+	return b.String(), nil
+}
+
+// setRune decodes the possibly escaped rune at the start of s, returning the
+// number of bytes it took up
+func setRune(s string) (rune, int, error) {
+	if s[0] != '\\' {
+		r, n := utf8.DecodeRuneInString(s)
+		return r, n, nil
+	}
+	return escape(s)
+}
+
+// escape decodes the escape sequence at the start of s
+func escape(s string) (rune, int, error) {
+	if len(s) < 2 {
+		return 0, 0, fmt.Errorf("unterminated escape")
+	}
+	switch s[1] {
+	case 'n':
+		return '\n', 2, nil
+	case 't':
+		return '\t', 2, nil
+	case 'r':
+		return '\r', 2, nil
+	case 'b':
+		return '\b', 2, nil
+	case 'f':
+		return '\f', 2, nil
+	case 'u':
+		hex, n := s[2:], 0
+		if strings.HasPrefix(hex, "{") {
+			end := strings.IndexByte(hex, '}')
+			if end < 0 {
+				return 0, 0, fmt.Errorf("unterminated \\u{")
+			}
+			hex, n = hex[1:end], end+1
+		} else if len(hex) >= 4 {
+			hex, n = hex[:4], 4
+		}
+		r, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid escape \\u%s", hex)
+		}
+		return rune(r), 2 + n, nil
+	default:
+		r, n := utf8.DecodeRuneInString(s[1:])
+		return r, 1 + n, nil
+	}
+}
+
+// unescape returns the value of a literal
+func unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		r, n, err := escape(s[i:])
+		if err != nil {
+			return "", err
+		}
+		b.WriteRune(r)
+		i += n
+	}
+	return b.String(), nil
+}
+
+// unescapeRune returns the value of a literal which must be a single rune
+func unescapeRune(s string) (rune, error) {
+	v, err := unescape(s)
+	if err != nil {
+		return 0, err
+	} else if utf8.RuneCountInString(v) != 1 {
+		return 0, fmt.Errorf("'%s' isn't a single character", s)
+	}
+	r, _ := utf8.DecodeRuneInString(v)
+	return r, nil
+}
+
+func (p *parser) spec() (lexrule.Spec, error) {
+	spec := lexrule.Spec{MaximalMunch: true, Modes: map[string][]lexrule.Rule{}}
+	for _, mode := range p.modes {
+		spec.Modes[mode] = []lexrule.Rule{}
+	}
+	types := map[string]lexgo.TokenType{}
+
+	for _, r := range p.order {
+		if r.fragment {
+			continue
+		}
+
+		// Alternatives are converted separately, as each can have its own
+		// commands, but are merged into one rule wherever possible
+		var rules []lexrule.Rule
+		for i, a := range r.alts {
+			c := &converter{rules: p.rules, inProgress: map[string]bool{r.name: true}}
+			pattern, err := c.regex(a.n)
+			if err != nil {
+				return lexrule.Spec{}, err
+			}
+			rule := lexrule.Rule{Name: r.name, Pattern: pattern, Lazy: c.lazy}
+			for _, cmd := range a.cmds {
+				if err := p.command(&rule, r, cmd); err != nil {
+					return lexrule.Spec{}, err
+				}
+			}
+
+			if i > 0 && sameCommands(r.alts[i-1].cmds, a.cmds) {
+				last := &rules[len(rules)-1]
+				last.Pattern = last.Pattern + "|" + rule.Pattern
+				last.Lazy = last.Lazy || rule.Lazy
+				continue
+			}
+			rules = append(rules, rule)
+		}
+
+		for _, rule := range rules {
+			if !rule.Skip {
+				t, ok := types[rule.Name]
+				if !ok {
+					t = lexgo.UserDefined + lexgo.TokenType(len(types))
+					types[rule.Name] = t
+				}
+				rule.Type = t
+			}
+			if r.mode == "" {
+				spec.Rules = append(spec.Rules, rule)
+			} else {
+				spec.Modes[r.mode] = append(spec.Modes[r.mode], rule)
+			}
+		}
+	}
+	return spec, nil
+}
+
+func (p *parser) command(rule *lexrule.Rule, r *grammarRule, cmd command) error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("line %d: rule %q: %s", r.line, r.name, fmt.Sprintf(format, args...))
+	}
+	checkMode := func() error {
+		if cmd.arg == "DEFAULT_MODE" {
+			return nil
+		}
+		for _, mode := range p.modes {
+			if mode == cmd.arg {
+				return nil
+			}
+		}
+		return fail("unknown mode %q", cmd.arg)
+	}
+
+	switch cmd.name {
+	case "skip":
+		rule.Skip = true
+	case "channel":
+	case "type":
+		rule.Name = cmd.arg
+	case "popMode":
+		rule.Pop = true
+	case "pushMode":
+		if cmd.arg == "DEFAULT_MODE" {
+			return fail("pushMode(DEFAULT_MODE) isn't supported")
+		} else if err := checkMode(); err != nil {
+			return err
+		}
+		rule.Push = cmd.arg
+	case "mode":
+		if err := checkMode(); err != nil {
+			return err
+		}
+		switch {
+		case cmd.arg == r.mode || (cmd.arg == "DEFAULT_MODE" && r.mode == ""):
+		case cmd.arg == "DEFAULT_MODE":
+			rule.Pop = true
+		case r.mode == "":
+			rule.Push = cmd.arg
+		default:
+			rule.Pop, rule.Push = true, cmd.arg
+		}
+	default:
+		return fail("command %q isn't supported", cmd.name)
+	}
+	return nil
+}
+
+func sameCommands(a, b []command) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
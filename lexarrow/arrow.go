@@ -0,0 +1,117 @@
+// Package lexarrow exports lexgo token streams as Apache Arrow columnar data,
+// so that large volumes of lexer output can be analyzed using dataframe tooling
+// (pandas, polars, DuckDB, etc...) without any custom conversion step.
+package lexarrow
+
+import (
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/mediocregopher/lexgo"
+)
+
+// Schema is the schema of all records produced by this package. It has one row
+// per Token, with the columns:
+//
+//   - type: the Token's TokenType
+//   - value: the Token's Val
+//   - row, col, offset: the Token's position
+//   - error: the error of Err Tokens which didn't end the stream (see
+//     lexgo.ResumeAfterError), the warning of Warning Tokens, or null for all
+//     other Tokens
+var Schema = arrow.NewSchema([]arrow.Field{
+	{Name: "type", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "value", Type: arrow.BinaryTypes.String},
+	{Name: "row", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "col", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "offset", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "error", Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+// DefaultBatchSize is the number of Tokens Write puts in each record batch if
+// not told otherwise
+const DefaultBatchSize = 64 * 1024
+
+// Record returns a record containing the given Tokens, using Schema. The
+// caller is responsible for calling Release on it.
+func Record(toks []lexgo.Token) arrow.RecordBatch {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, Schema)
+	defer b.Release()
+	for i := range toks {
+		appendToken(b, &toks[i])
+	}
+	return b.NewRecordBatch()
+}
+
+func appendToken(b *array.RecordBuilder, tok *lexgo.Token) {
+	b.Field(0).(*array.Int32Builder).Append(int32(tok.TokenType))
+	b.Field(1).(*array.StringBuilder).Append(tok.Val)
+	b.Field(2).(*array.Int64Builder).Append(int64(tok.Row))
+	b.Field(3).(*array.Int64Builder).Append(int64(tok.Col))
+	b.Field(4).(*array.Int64Builder).Append(int64(tok.Offset))
+	if tok.Err != nil {
+		b.Field(5).(*array.StringBuilder).Append(tok.Err.Error())
+	} else if tok.Warn != nil {
+		b.Field(5).(*array.StringBuilder).Append(tok.Warn.Error())
+	} else {
+		b.Field(5).(*array.StringBuilder).AppendNull()
+	}
+}
+
+// Write reads Tokens from t until the stream ends, and writes them to w as an
+// Arrow IPC stream, in record batches of up to batchSize Tokens (or
+// DefaultBatchSize if batchSize is zero or less). Recoverable Err Tokens are
+// written like any other Token. The stream is always completed properly, and
+// if it ended with an error other than io.EOF that error is returned.
+func Write(w io.Writer, t lexgo.Tokenizer, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	iw := ipc.NewWriter(w, ipc.WithSchema(Schema))
+	b := array.NewRecordBuilder(memory.DefaultAllocator, Schema)
+	defer b.Release()
+
+	flush := func() error {
+		rec := b.NewRecordBatch()
+		defer rec.Release()
+		return iw.Write(rec)
+	}
+
+	var n int
+	var lexErr error
+	for {
+		tok := t.Next()
+		if tok.EndsStream() {
+			if tok.Err != io.EOF {
+				lexErr = tok.Err
+			}
+			tok.Release()
+			break
+		}
+		appendToken(b, tok)
+		tok.Release()
+
+		if n++; n == batchSize {
+			if err := flush(); err != nil {
+				iw.Close()
+				return err
+			}
+			n = 0
+		}
+	}
+
+	if n > 0 {
+		if err := flush(); err != nil {
+			iw.Close()
+			return err
+		}
+	}
+	if err := iw.Close(); err != nil {
+		return err
+	}
+	return lexErr
+}
@@ -0,0 +1,214 @@
+// Package lexcache implements persisting of lexed token streams, so that tools
+// which repeatedly lex the same inputs (build tools, indexers, etc...) can skip
+// re-lexing inputs which haven't changed.
+package lexcache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// Store describes a place where token streams can be saved and retrieved by
+// key
+type Store interface {
+	// Get returns the stream stored under the given key. If there is none
+	// then false is returned, with a nil error
+	Get(key string) ([]lexgo.Token, bool, error)
+
+	// Put stores the given stream under the given key, overwriting any which
+	// was there previously
+	Put(key string, toks []lexgo.Token) error
+}
+
+// Cache uses a Store to avoid lexing inputs whose token streams are already
+// known.
+type Cache struct {
+	Store Store
+}
+
+// Tokens returns the token stream stored under key in the Cache's Store. If
+// there isn't one then newTokenizer is called, its Tokenizer is read to
+// completion using lexgo.Tokens, and the resulting stream is stored under key
+// before being returned. If lexing fails the error is returned and nothing is
+// stored.
+//
+// It's up to the caller to pick keys which change when the input changes, see
+// FileKey. Keys should also incorporate some version of the lexer itself if it
+// may change between runs.
+func (c *Cache) Tokens(key string, newTokenizer func() (lexgo.Tokenizer, error)) ([]lexgo.Token, error) {
+	if toks, ok, err := c.Store.Get(key); err != nil {
+		return nil, err
+	} else if ok {
+		return toks, nil
+	}
+
+	t, err := newTokenizer()
+	if err != nil {
+		return nil, err
+	}
+
+	toks, err := lexgo.Tokens(t)
+	if err != nil {
+		return nil, err
+	} else if err := c.Store.Put(key, toks); err != nil {
+		return nil, err
+	}
+	return toks, nil
+}
+
+// FileKey returns a key identifying the current contents of the file at the
+// given path, based on its absolute path, size, and modification time
+func FileKey(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", abs, fi.Size(), fi.ModTime().UnixNano()), nil
+}
+
+// record is the serialized form of a Token. Err and Meta are not persisted, and
+// Warn is persisted only as its message
+type record struct {
+	Type             lexgo.TokenType
+	Val, Raw         string
+	Row, Col, Offset int
+	Warn             string
+	Synthetic        bool
+}
+
+// encodingVersion is written at the start of every encoded stream, and should
+// be incremented whenever record changes
+const encodingVersion = 3
+
+// Encode writes the given token stream to w, in a form Decode can read back.
+// The Err and Meta fields of the Tokens are not written, and the Warn field is
+// written only as its message
+func Encode(w io.Writer, toks []lexgo.Token) error {
+	recs := make([]record, len(toks))
+	for i, t := range toks {
+		recs[i] = record{
+			Type:      t.TokenType,
+			Val:       t.Val,
+			Raw:       t.Raw,
+			Row:       t.Row,
+			Col:       t.Col,
+			Offset:    t.Offset,
+			Synthetic: t.Synthetic,
+		}
+		if t.Warn != nil {
+			recs[i].Warn = t.Warn.Error()
+		}
+	}
+
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(encodingVersion); err != nil {
+		return err
+	}
+	return enc.Encode(recs)
+}
+
+// ErrVersion is returned by Decode when the stream being decoded was written by
+// an incompatible version of Encode
+var ErrVersion = errors.New("encoded token stream has unknown version")
+
+// Decode reads a token stream written by Encode
+func Decode(r io.Reader) ([]lexgo.Token, error) {
+	dec := gob.NewDecoder(r)
+	var version int
+	if err := dec.Decode(&version); err != nil {
+		return nil, err
+	} else if version != encodingVersion {
+		return nil, ErrVersion
+	}
+
+	var recs []record
+	if err := dec.Decode(&recs); err != nil {
+		return nil, err
+	}
+
+	toks := make([]lexgo.Token, len(recs))
+	for i, rec := range recs {
+		toks[i] = lexgo.Token{
+			TokenType: rec.Type,
+			Val:       rec.Val,
+			Raw:       rec.Raw,
+			Row:       rec.Row,
+			Col:       rec.Col,
+			Offset:    rec.Offset,
+			Synthetic: rec.Synthetic,
+		}
+		if rec.Warn != "" {
+			toks[i].Warn = errors.New(rec.Warn)
+		}
+	}
+	return toks, nil
+}
+
+// DirStore is a Store which keeps each token stream in its own file within a
+// directory. Keys are hashed to produce file names, so any string may be used
+// as a key
+type DirStore struct {
+	Dir string
+}
+
+var _ Store = DirStore{}
+
+func (s DirStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements the method for Store. Stored streams which were written by an
+// incompatible version of this package are treated as missing
+func (s DirStore) Get(key string) ([]lexgo.Token, bool, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	toks, err := Decode(f)
+	if err == ErrVersion {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return toks, true, nil
+}
+
+// Put implements the method for Store. The stream is written to a temporary
+// file which is then renamed into place, so that concurrent Gets never see a
+// partially written stream
+func (s DirStore) Put(key string, toks []lexgo.Token) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := Encode(f, toks); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
@@ -0,0 +1,105 @@
+package lexcache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sync"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// Memo wraps a lexer such that inputs are only lexed if an input with the same
+// content hasn't been lexed before, as determined by looking the input's hash
+// up in a Store. This is a drop-in speedup for services which frequently lex
+// the same snippets.
+type Memo struct {
+	// Store is where token streams are kept, keyed by content hash. LRUStore
+	// and DirStore are both suitable
+	Store Store
+
+	// New returns a Tokenizer for the given input, and is called on cache
+	// misses
+	New func(io.Reader) lexgo.Tokenizer
+
+	// Prefix is prepended to every key, and should be changed whenever the
+	// lexer returned by New changes in a way that affects its output
+	Prefix string
+}
+
+// Tokens returns the token stream for the given input, hashing it with sha256
+// to determine its key
+func (m *Memo) Tokens(input []byte) ([]lexgo.Token, error) {
+	sum := sha256.Sum256(input)
+	return m.TokensWithHash(hex.EncodeToString(sum[:]), bytes.NewReader(input))
+}
+
+// TokensWithHash is like Tokens, but uses a hash which the caller has already
+// computed for the input, so r is only read on a cache miss
+func (m *Memo) TokensWithHash(hash string, r io.Reader) ([]lexgo.Token, error) {
+	c := Cache{Store: m.Store}
+	return c.Tokens(m.Prefix+hash, func() (lexgo.Tokenizer, error) {
+		return m.New(r), nil
+	})
+}
+
+// LRUStore is an in-memory Store which holds at most some fixed number of
+// token streams, evicting the least recently used when it's full. It is safe
+// for concurrent use. Streams returned from Get are shared, and so must not be
+// modified
+type LRUStore struct {
+	size int
+
+	l     sync.Mutex
+	order *list.List // of *lruEntry, most recently used at the front
+	m     map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	toks []lexgo.Token
+}
+
+var _ Store = new(LRUStore)
+
+// NewLRUStore returns an LRUStore which will hold at most size token streams
+func NewLRUStore(size int) *LRUStore {
+	return &LRUStore{
+		size:  size,
+		order: list.New(),
+		m:     map[string]*list.Element{},
+	}
+}
+
+// Get implements the method for Store
+func (s *LRUStore) Get(key string) ([]lexgo.Token, bool, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	el, ok := s.m[key]
+	if !ok {
+		return nil, false, nil
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*lruEntry).toks, true, nil
+}
+
+// Put implements the method for Store
+func (s *LRUStore) Put(key string, toks []lexgo.Token) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if el, ok := s.m[key]; ok {
+		el.Value.(*lruEntry).toks = toks
+		s.order.MoveToFront(el)
+		return nil
+	}
+
+	s.m[key] = s.order.PushFront(&lruEntry{key: key, toks: toks})
+	for s.order.Len() > s.size {
+		el := s.order.Back()
+		s.order.Remove(el)
+		delete(s.m, el.Value.(*lruEntry).key)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package lexcompress implements transparent decompression of inputs to
+// lexers. The format of an input is detected from its first few bytes, so
+// lexers can be pointed at compressed log archives and the like without the
+// caller needing to know how, or whether, each was compressed.
+package lexcompress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mediocregopher/lexgo"
+)
+
+// Format is a compression format which can be detected and decompressed
+type Format int
+
+// The supported Formats. None is used for inputs which don't appear to be
+// compressed, and are passed through as-is.
+const (
+	None Format = iota
+	Gzip
+	Zstd
+	Bzip2
+)
+
+func (f Format) String() string {
+	switch f {
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Bzip2:
+		return "bzip2"
+	default:
+		return "none"
+	}
+}
+
+// magics holds the bytes which inputs in each Format start with
+var magics = []struct {
+	f     Format
+	magic []byte
+}{
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// detectLen is the number of bytes Detect needs to see to detect any Format,
+// that being the bzip2 header plus the magic of the block which follows it
+const detectLen = 10
+
+// isBzip2 returns whether b starts with a bzip2 header, "BZh" and the block
+// size digit, followed by the magic of either a compressed block or the end of
+// the stream. "BZh" alone is too short to rule out plain text.
+func isBzip2(b []byte) bool {
+	if len(b) < detectLen || !bytes.HasPrefix(b, []byte("BZh")) || b[3] < '1' || b[3] > '9' {
+		return false
+	}
+	block := b[4:detectLen]
+	return bytes.Equal(block, []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}) ||
+		bytes.Equal(block, []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90})
+}
+
+// exts holds the file extensions used for the Formats, see TrimExt
+var exts = []string{".gz", ".zst", ".zstd", ".bz2"}
+
+// Detect returns the Format of the input with the given first bytes, or None if
+// it doesn't start with the magic bytes of any of them. Ten bytes is enough to
+// detect any of the Formats.
+func Detect(b []byte) Format {
+	for _, m := range magics {
+		if bytes.HasPrefix(b, m.magic) {
+			return m.f
+		}
+	}
+	if isBzip2(b) {
+		return Bzip2
+	}
+	return None
+}
+
+// TrimExt returns the path with any extension used for compressed files (e.g.
+// ".gz") removed, so that the underlying file's name can be given to
+// lexgo.LookupFilename
+func TrimExt(path string) string {
+	for _, ext := range exts {
+		if strings.HasSuffix(path, ext) && len(path) > len(ext) {
+			return strings.TrimSuffix(path, ext)
+		}
+	}
+	return path
+}
+
+// NewReader detects the Format of r, and returns a reader of its decompressed
+// contents along with the Format. If r isn't compressed its contents are
+// returned as-is. Errors are only returned if r appears to be compressed but
+// its header can't be read.
+//
+// Closing the returned reader releases any resources held by the decompressor.
+// It doesn't close r.
+func NewReader(r io.Reader) (io.ReadCloser, Format, error) {
+	br := bufio.NewReader(r)
+	b, _ := br.Peek(detectLen)
+	switch f := Detect(b); f {
+	case Gzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, f, err
+		}
+		return zr, f, nil
+	case Zstd:
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, f, err
+		}
+		return zr.IOReadCloser(), f, nil
+	case Bzip2:
+		return io.NopCloser(bzip2.NewReader(br)), f, nil
+	default:
+		return io.NopCloser(br), f, nil
+	}
+}
+
+// autoCloser closes its reader once it has returned an error, including
+// io.EOF, for when the reader is handed off to something which won't close it
+type autoCloser struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (r *autoCloser) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if err != nil && !r.closed {
+		r.closed = true
+		r.Close()
+	}
+	return n, err
+}
+
+// errReader returns err from every Read
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// reader returns the decompressed contents of r for giving to a Lexer, which
+// never closes what it reads from. If decompression can't be started the
+// returned reader returns the error instead, so that it ends up emitted by the
+// Lexer like any other read error
+func reader(r io.Reader) io.Reader {
+	rc, _, err := NewReader(r)
+	if err != nil {
+		return errReader{err}
+	}
+	return &autoCloser{ReadCloser: rc}
+}
+
+// NewLexer is like lexgo.NewLexer, except that r is decompressed as by
+// NewReader first. Any error from decompression is emitted by the Lexer as an
+// Err Token (in the same way as an error from r itself would be).
+func NewLexer(r io.Reader, firstFunc lexgo.LexerFunc, opts ...lexgo.Option) *lexgo.Lexer {
+	return lexgo.NewLexer(reader(r), firstFunc, opts...)
+}
+
+// Wrap returns a function which is like newFn, except that its input is
+// decompressed as by NewReader first. This allows any registered lexer to
+// handle compressed inputs:
+//
+//	reg, _ := lexgo.LookupFilename(lexcompress.TrimExt(path))
+//	t := lexcompress.Wrap(reg.New)(f)
+func Wrap(newFn func(io.Reader) lexgo.Tokenizer) func(io.Reader) lexgo.Tokenizer {
+	return func(r io.Reader) lexgo.Tokenizer {
+		return newFn(reader(r))
+	}
+}
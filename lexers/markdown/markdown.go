@@ -0,0 +1,283 @@
+// Package markdown implements a streaming lexer for Markdown documents, built
+// using lexgo. It recognizes the most common block and inline constructs,
+// reporting each with its position in the source, which makes it suitable for
+// documentation tooling (linters, link checkers, etc...) which needs to point
+// back into the original document.
+//
+// Markdown is not a regular language, and this lexer doesn't attempt to
+// implement all of CommonMark. It does demonstrate the island grammar
+// technique, where the lexer switches between entirely different sets of
+// states depending on context: the contents of fenced code blocks are passed
+// through untouched, while everything else is broken up into inline tokens.
+//
+// Importing this package registers the lexer with lexgo.Register, under the
+// name "markdown".
+package markdown
+
+import (
+	"io"
+	"strings"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// The TokenTypes produced by Lexer
+const (
+	// The marker of an ATX heading, e.g. "##". The text of the heading
+	// follows as inline Tokens
+	Heading lexgo.TokenType = lexgo.UserDefined + iota
+
+	// A run of plain text
+	Text
+
+	// A run of emphasis delimiters, e.g. "*", "__" or "**"
+	Emphasis
+
+	// An inline code span, including its backticks
+	CodeSpan
+
+	// A line opening or closing a fenced code block, including the info
+	// string if any, e.g. "```go"
+	CodeFence
+
+	// A single line within a fenced code block, not including the newline
+	Code
+
+	// An inline link of the form [text](destination), see ParseLink
+	Link
+
+	// A line break
+	Newline
+)
+
+var typeNames = map[lexgo.TokenType]string{
+	Heading:   "Heading",
+	Text:      "Text",
+	Emphasis:  "Emphasis",
+	CodeSpan:  "CodeSpan",
+	CodeFence: "CodeFence",
+	Code:      "Code",
+	Link:      "Link",
+	Newline:   "Newline",
+}
+
+func init() {
+	lexgo.Register(lexgo.Registration{
+		Name:       "markdown",
+		Extensions: []string{".md", ".markdown"},
+		MIMETypes:  []string{"text/markdown"},
+		New:        func(r io.Reader) lexgo.Tokenizer { return New(r) },
+		TypeName:   TypeName,
+	})
+}
+
+// TypeName returns the name of the given TokenType, e.g. "Heading", or the
+// empty string if it isn't one produced by this package
+func TypeName(tt lexgo.TokenType) string {
+	return typeNames[tt]
+}
+
+// ParseLink splits the value of a Link Token into its text and destination
+func ParseLink(val string) (text, dest string) {
+	i := strings.LastIndex(val, "](")
+	if !strings.HasPrefix(val, "[") || !strings.HasSuffix(val, ")") || i < 0 {
+		return "", ""
+	}
+	return val[1:i], val[i+2 : len(val)-1]
+}
+
+// Lexer produces Tokens from a Markdown document. It implements
+// lexgo.Tokenizer
+type Lexer struct {
+	l *lexgo.Lexer
+
+	// the fence which opened the current fenced code block, e.g. "```", or
+	// empty if not in one
+	fence string
+}
+
+// New returns a Lexer which reads a Markdown document from r. Any given
+// lexgo.Options are applied to the underlying lexgo.Lexer.
+func New(r io.Reader, opts ...lexgo.Option) *Lexer {
+	m := new(Lexer)
+	m.l = lexgo.NewLexer(r, m.lexLineStart, opts...)
+	return m
+}
+
+// Next returns the next Token in the document
+func (m *Lexer) Next() *lexgo.Token {
+	return m.l.Next()
+}
+
+// Lexer returns the underlying lexgo.Lexer, e.g. for stepping through it using
+// lexstep. Reading Tokens from it is the same as reading them from m.
+func (m *Lexer) Lexer() *lexgo.Lexer {
+	return m.l
+}
+
+var _ lexgo.Tokenizer = new(Lexer)
+
+func notNewline(r rune) bool { return r != '\n' }
+
+// end is used once the stream has been found to be finished, after anything
+// buffered has been emitted. It reads the error which finished the stream so
+// that it gets emitted.
+func end(l *lexgo.Lexer) lexgo.LexerFunc {
+	l.PeekRune()
+	return nil
+}
+
+// lexNewline emits the newline which is next in the stream, if there is one,
+// and returns to the start of the line
+func (m *Lexer) lexNewline(l *lexgo.Lexer) lexgo.LexerFunc {
+	r, _, err := l.ReadRune()
+	if err != nil {
+		return nil
+	}
+	l.BufferRune(r)
+	l.Emit(Newline)
+	return m.lexLineStart
+}
+
+// lexLineStart determines what sort of block the line being started belongs
+// to
+func (m *Lexer) lexLineStart(l *lexgo.Lexer) lexgo.LexerFunc {
+	if _, err := l.PeekRune(); err != nil {
+		return nil
+	} else if m.fence != "" {
+		return m.lexCodeLine
+	}
+
+	// Block markers may be indented by up to three spaces. Any indentation is
+	// emitted as Text
+	for i := 0; i < 3 && l.Accept(" "); i++ {
+	}
+	m.emitText(l)
+
+	if n := l.AcceptRun("#"); n > 0 {
+		if r, ok := l.TryPeekRune(); n <= 6 && (!ok || r == ' ' || r == '\t' || r == '\n') {
+			l.Emit(Heading)
+		}
+		return m.lexInline
+	}
+
+	if n := l.AcceptRun("`"); n >= 3 {
+		return m.lexFence("`", n)
+	} else if n > 0 {
+		m.lexCodeSpanFrom(l, n)
+		return m.lexInline
+	} else if n := l.AcceptRun("~"); n >= 3 {
+		return m.lexFence("~", n)
+	}
+
+	return m.lexInline
+}
+
+// lexFence returns a LexerFunc which handles the rest of the line opening a
+// fenced code block, whose opening fence of n of the given rune has already
+// been buffered
+func (m *Lexer) lexFence(c string, n int) lexgo.LexerFunc {
+	return func(l *lexgo.Lexer) lexgo.LexerFunc {
+		m.fence = strings.Repeat(c, n)
+		l.AcceptWhile(notNewline)
+		l.Emit(CodeFence)
+		return m.lexNewline
+	}
+}
+
+// lexCodeLine handles a line within a fenced code block, which is either the
+// closing fence or a line of code
+func (m *Lexer) lexCodeLine(l *lexgo.Lexer) lexgo.LexerFunc {
+	l.AcceptWhile(notNewline)
+	line := strings.TrimLeft(l.BufferString(), " ")
+	if strings.HasPrefix(line, m.fence) && strings.Trim(line, m.fence[:1]+" \t") == "" {
+		m.fence = ""
+		l.Emit(CodeFence)
+	} else {
+		l.Emit(Code)
+	}
+	return m.lexNewline
+}
+
+// lexInline handles the inline content of a line, up to its end
+func (m *Lexer) lexInline(l *lexgo.Lexer) lexgo.LexerFunc {
+	for {
+		r, ok := l.TryPeekRune()
+		if !ok {
+			m.emitText(l)
+			return end
+		}
+
+		switch r {
+		case '\n':
+			m.emitText(l)
+			return m.lexNewline
+		case '\\':
+			// the escaped rune is always text
+			l.ReadRune()
+			l.BufferRune(r)
+			l.Accept(escapable)
+			continue
+		case '*', '_':
+			m.emitText(l)
+			l.AcceptRun(string(r))
+			l.Emit(Emphasis)
+			continue
+		case '`':
+			m.emitText(l)
+			m.lexCodeSpanFrom(l, l.AcceptRun("`"))
+			continue
+		case '[':
+			m.emitText(l)
+			m.lexLink(l)
+			continue
+		}
+
+		l.ReadRune()
+		l.BufferRune(r)
+	}
+}
+
+// escapable are the runes which may be escaped with a backslash
+const escapable = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// emitText emits whatever is buffered as Text, if anything is
+func (m *Lexer) emitText(l *lexgo.Lexer) {
+	if l.BufferString() != "" {
+		l.Emit(Text)
+	}
+}
+
+// lexCodeSpanFrom handles an inline code span, whose opening run of n
+// backticks has already been buffered. If the opening backticks aren't matched
+// by a closing run of the same length on the same line then they, and
+// everything following them, are emitted as Text
+func (m *Lexer) lexCodeSpanFrom(l *lexgo.Lexer, n int) {
+	for {
+		l.AcceptWhile(func(r rune) bool { return r != '`' && r != '\n' })
+		if r, ok := l.TryPeekRune(); !ok || r == '\n' {
+			l.Emit(Text)
+			return
+		} else if l.AcceptRun("`") == n {
+			l.Emit(CodeSpan)
+			return
+		}
+	}
+}
+
+// lexLink handles an inline link. If what follows the opening bracket isn't a
+// complete link on the same line then everything read is emitted as Text
+func (m *Lexer) lexLink(l *lexgo.Lexer) {
+	l.Accept("[")
+	l.AcceptWhile(func(r rune) bool { return r != ']' && r != '\n' })
+	if !l.Accept("]") || !l.Accept("(") {
+		l.Emit(Text)
+		return
+	}
+	l.AcceptWhile(func(r rune) bool { return r != ')' && r != '\n' })
+	if !l.Accept(")") {
+		l.Emit(Text)
+		return
+	}
+	l.Emit(Link)
+}
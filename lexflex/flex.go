@@ -0,0 +1,478 @@
+// Package lexflex imports flex (.l) lexer specifications as lexrule Specs,
+// easing the move of existing C tokenizers to Go.
+//
+// Only a useful subset of flex is understood:
+//
+//   - Name definitions, and %x and %s start condition declarations, in the
+//     definitions section. Code blocks and other options are ignored.
+//
+//   - Rules, optionally prefixed with start conditions (e.g. <STR>, <A,B> or
+//     <*>), whose patterns may use quoted strings, character classes,
+//     repetitions and {name} references to definitions. Trailing context
+//     (r/s), and the ^ and $ anchors, aren't supported. <<EOF>> rules are
+//     ignored.
+//
+//   - Actions, which can't be run, are instead searched for a return
+//     statement, whose value becomes the rule's name (e.g. "return IDENT;"
+//     or "return '+';"), and for BEGIN, which switches start condition.
+//     Rules whose actions don't return anything are Skip rules. Actions
+//     using REJECT, yymore or yyless aren't supported.
+//
+// The user code section is ignored. The resulting Spec uses MaximalMunch, as
+// flex does, and each start condition other than INITIAL becomes a mode of
+// the same name. Each distinct rule name is given its own TokenType, starting
+// at lexgo.UserDefined, in the order the names first appear.
+package lexflex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mediocregopher/lexgo"
+	"github.com/mediocregopher/lexgo/lexrule"
+)
+
+// Parse reads a flex specification from r and converts it to a Spec
+func Parse(r io.Reader) (lexrule.Spec, error) {
+	p := &parser{
+		sc:        bufio.NewScanner(r),
+		defs:      map[string]string{},
+		inclusive: map[string]bool{},
+		types:     map[string]lexgo.TokenType{},
+	}
+	if err := p.parseDefinitions(); err != nil {
+		return lexrule.Spec{}, err
+	} else if err := p.parseRules(); err != nil {
+		return lexrule.Spec{}, err
+	}
+	return p.spec, nil
+}
+
+type parser struct {
+	sc   *bufio.Scanner
+	line int
+
+	defs  map[string]string
+	conds []string
+
+	// inclusive holds the %s start conditions, which are also used by rules
+	// which don't give any start conditions
+	inclusive map[string]bool
+
+	types map[string]lexgo.TokenType
+	spec  lexrule.Spec
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) next() (string, bool) {
+	if !p.sc.Scan() {
+		return "", false
+	}
+	p.line++
+	return strings.TrimRight(p.sc.Text(), "\r"), true
+}
+
+// skipUntil skips lines up to and including one which, once trimmed, is end
+func (p *parser) skipUntil(end string) {
+	for {
+		line, ok := p.next()
+		if !ok || strings.TrimSpace(line) == end {
+			return
+		}
+	}
+}
+
+func (p *parser) parseDefinitions() error {
+	for {
+		line, ok := p.next()
+		if !ok {
+			return p.sc.Err()
+		}
+
+		switch fields := strings.Fields(line); {
+		case line == "%%":
+			return nil
+		case len(fields) == 0, unicode.IsSpace(rune(line[0])):
+			// indented lines are code
+		case line == "%{":
+			p.skipUntil("%}")
+		case strings.HasPrefix(line, "/*"):
+			if !strings.Contains(line, "*/") {
+				p.skipUntil("*/")
+			}
+		case fields[0] == "%x" || fields[0] == "%s":
+			for _, cond := range fields[1:] {
+				p.conds = append(p.conds, cond)
+				p.inclusive[cond] = fields[0] == "%s"
+			}
+		case line[0] == '%':
+			// other options don't affect the rules
+		default:
+			re, err := p.convert(strings.TrimSpace(line[len(fields[0]):]))
+			if err != nil {
+				return p.errorf("definition %q: %s", fields[0], err)
+			}
+			p.defs[fields[0]] = re
+		}
+	}
+}
+
+// rule is a rule as it's being parsed
+type rule struct {
+	line    int
+	conds   []string
+	pattern string
+}
+
+var (
+	returnRe = regexp.MustCompile(`\breturn\s*\(?\s*([A-Za-z_]\w*|'(?:\\.|[^'\\])+')`)
+	beginRe  = regexp.MustCompile(`\bBEGIN\s*\(?\s*([A-Za-z_]\w*)`)
+	badRe    = regexp.MustCompile(`\b(REJECT|yymore|yyless)\b`)
+)
+
+func (p *parser) parseRules() error {
+	p.spec.MaximalMunch = true
+	p.spec.Modes = map[string][]lexrule.Rule{}
+	for _, cond := range p.conds {
+		p.spec.Modes[cond] = []lexrule.Rule{}
+	}
+
+	// pending holds rules whose action was "|", which share the action of the
+	// next rule
+	var pending []rule
+	for {
+		line, ok := p.next()
+		if !ok {
+			break
+		} else if line == "%%" {
+			break
+		} else if strings.TrimSpace(line) == "" || unicode.IsSpace(rune(line[0])) {
+			continue
+		} else if line == "%{" {
+			p.skipUntil("%}")
+			continue
+		} else if strings.HasPrefix(line, "/*") {
+			if !strings.Contains(line, "*/") {
+				p.skipUntil("*/")
+			}
+			continue
+		}
+
+		r := rule{line: p.line}
+		if line[0] == '<' && !strings.HasPrefix(line, "<<EOF>>") {
+			i := strings.IndexByte(line, '>')
+			if i < 0 {
+				return p.errorf("unterminated start condition")
+			}
+			r.conds = strings.Split(line[1:i], ",")
+			line = line[i+1:]
+		}
+
+		n := patternLen(line)
+		r.pattern, line = line[:n], strings.TrimSpace(line[n:])
+		if r.pattern == "" {
+			return p.errorf("start condition scopes aren't supported")
+		}
+
+		action, err := p.action(line)
+		if err != nil {
+			return err
+		} else if action == "|" {
+			pending = append(pending, r)
+			continue
+		}
+
+		for _, r := range append(pending, r) {
+			if err := p.addRule(r, action); err != nil {
+				return err
+			}
+		}
+		pending = nil
+	}
+
+	if len(pending) > 0 {
+		return p.errorf("last rule's action is |")
+	}
+	return p.sc.Err()
+}
+
+// patternLen returns the length of the pattern at the start of line, which
+// ends at the first whitespace outside of quotes or a character class
+func patternLen(line string) int {
+	var quoted, class bool
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case quoted:
+			quoted = c != '"'
+		case class:
+			class = c != ']'
+		case c == '"':
+			quoted = true
+		case c == '[':
+			class = true
+			// a ] straight after the [ or [^ is part of the class
+			if strings.HasPrefix(line[i+1:], "^") {
+				i++
+			}
+			if strings.HasPrefix(line[i+1:], "]") {
+				i++
+			}
+		case c == ' ' || c == '\t':
+			return i
+		}
+	}
+	return len(line)
+}
+
+// action returns the action starting with the given text, reading any further
+// lines needed to close its braces
+func (p *parser) action(action string) (string, error) {
+	if !strings.HasPrefix(action, "{") {
+		return action, nil
+	}
+
+	depth := 0
+	var b strings.Builder
+	for {
+		var quote byte
+		for i := 0; i < len(action); i++ {
+			switch c := action[i]; {
+			case quote != 0 && c == '\\':
+				i++
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '{':
+				depth++
+			case c == '}':
+				depth--
+			}
+		}
+		b.WriteString(action + "\n")
+		if depth <= 0 {
+			return b.String(), nil
+		}
+
+		var ok bool
+		if action, ok = p.next(); !ok {
+			return "", p.errorf("unterminated action")
+		}
+	}
+}
+
+func (p *parser) addRule(r rule, action string) error {
+	p.line = r.line
+	if r.pattern == "<<EOF>>" {
+		return nil
+	} else if m := badRe.FindStringSubmatch(action); m != nil {
+		return p.errorf("%s isn't supported", m[1])
+	}
+
+	pattern, err := p.convert(r.pattern)
+	if err != nil {
+		return p.errorf("pattern %q: %s", r.pattern, err)
+	}
+
+	base := lexrule.Rule{Pattern: pattern, Name: "line " + strconv.Itoa(r.line), Skip: true}
+	if m := returnRe.FindStringSubmatch(action); m != nil {
+		base.Name, base.Skip = m[1], false
+		t, ok := p.types[base.Name]
+		if !ok {
+			t = lexgo.UserDefined + lexgo.TokenType(len(p.types))
+			p.types[base.Name] = t
+		}
+		base.Type = t
+	}
+	var begin string
+	if m := beginRe.FindStringSubmatch(action); m != nil {
+		if begin = m[1]; begin != "INITIAL" {
+			if _, ok := p.spec.Modes[begin]; !ok {
+				return p.errorf("unknown start condition %q", begin)
+			}
+		}
+	}
+
+	// Work out which modes the rule is used in. The default mode is the
+	// INITIAL start condition
+	var modes []string
+	switch {
+	case r.conds == nil:
+		modes = append(modes, "")
+		for _, cond := range p.conds {
+			if p.inclusive[cond] {
+				modes = append(modes, cond)
+			}
+		}
+	case len(r.conds) == 1 && r.conds[0] == "*":
+		modes = append([]string{""}, p.conds...)
+	default:
+		for _, cond := range r.conds {
+			if cond == "INITIAL" {
+				modes = append(modes, "")
+				continue
+			} else if _, ok := p.spec.Modes[cond]; !ok {
+				return p.errorf("unknown start condition %q", cond)
+			}
+			modes = append(modes, cond)
+		}
+	}
+
+	// BEGIN switches start condition, rather than nesting them, so it's
+	// translated to popping out of the current mode, if not the default, and
+	// then pushing the next one
+	for _, mode := range modes {
+		rule := base
+		switch {
+		case begin == "" || begin == mode || (begin == "INITIAL" && mode == ""):
+		case begin == "INITIAL":
+			rule.Pop = true
+		case mode == "":
+			rule.Push = begin
+		default:
+			rule.Pop, rule.Push = true, begin
+		}
+
+		if mode == "" {
+			p.spec.Rules = append(p.spec.Rules, rule)
+		} else {
+			p.spec.Modes[mode] = append(p.spec.Modes[mode], rule)
+		}
+	}
+	return nil
+}
+
+// convert translates a flex pattern into regexp syntax
+func (p *parser) convert(pattern string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteString(pattern[i : i+2])
+			i++
+
+		case c == '"':
+			end := i + 1
+			for ; end < len(pattern) && pattern[end] != '"'; end++ {
+				if pattern[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(pattern) {
+				return "", fmt.Errorf("unterminated string")
+			}
+			s, err := unquote(pattern[i+1 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("(?:" + regexp.QuoteMeta(s) + ")")
+			i = end
+
+		case c == '[':
+			end := i + 1
+			if strings.HasPrefix(pattern[end:], "^") {
+				end++
+			}
+			if strings.HasPrefix(pattern[end:], "]") {
+				end++
+			}
+			for ; end < len(pattern) && pattern[end] != ']'; end++ {
+				if pattern[end] == '\\' {
+					end++
+				} else if strings.HasPrefix(pattern[end:], "[:") {
+					if j := strings.Index(pattern[end:], ":]"); j > 0 {
+						end += j + 1
+					}
+				}
+			}
+			if end >= len(pattern) {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			b.WriteString(pattern[i : end+1])
+			i = end
+
+		case c == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated {")
+			}
+			inner := pattern[i+1 : i+end]
+			if strings.Trim(inner, "0123456789,") == "" {
+				b.WriteString(pattern[i : i+end+1])
+			} else if def, ok := p.defs[inner]; ok {
+				b.WriteString("(?:" + def + ")")
+			} else {
+				return "", fmt.Errorf("undefined definition %q", inner)
+			}
+			i += end
+
+		case c == '/':
+			return "", fmt.Errorf("trailing context isn't supported")
+		case (c == '^' && i == 0) || (c == '$' && i == len(pattern)-1):
+			return "", fmt.Errorf("anchors aren't supported")
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if _, err := regexp.Compile(b.String()); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// unquote returns the contents of a quoted string in a pattern, with its
+// escapes replaced
+func unquote(s string) (string, error) {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i + 1
+			for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			n, err := strconv.ParseUint(s[i:end], 8, 8)
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte(byte(n))
+			i = end - 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,312 @@
+// Package lexhttp implements an http.Handler which tokenizes source text using
+// lexgo lexers, so that a tokenization service for editors, bots and other
+// tooling can be stood up with just a few lines:
+//
+//	http.Handle("/tokenize", &lexhttp.Handler{
+//		Lexers: map[string]lexhttp.NewFunc{
+//			"markdown": func(r io.Reader) lexgo.Tokenizer { return markdown.New(r) },
+//		},
+//	})
+//
+// Visualizer serves a web page for interactively seeing how the same lexers
+// tokenize input, intended for local debugging.
+package lexhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// NewFunc describes a function which returns a Tokenizer for the given input
+type NewFunc func(io.Reader) lexgo.Tokenizer
+
+// DefaultMaxBytes is the limit on request bodies used if Handler.MaxBytes isn't
+// set
+const DefaultMaxBytes = 1 << 20
+
+// The content types which Handler can respond with
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// Handler is an http.Handler which lexes the body of POST requests, responding
+// with the resulting Tokens.
+//
+// The lexer to use is chosen using the "lexer" query parameter, which may be
+// omitted if Lexers only has one entry. If Lexers is nil then lexers are looked
+// up using lexgo.Lookup instead, or lexgo.LookupMIME with the request's
+// Content-Type if the parameter isn't given. The response format is chosen based
+// on the request's Accept header:
+//
+//   - application/json (the default) responds with a single Response object
+//     once lexing is done.
+//
+//   - application/x-ndjson streams each Token as its own JSON object, one per
+//     line, as soon as it's lexed. If lexing fails a final StreamError object
+//     is written.
+//
+// Request bodies larger than MaxBytes are rejected with 413 Request Entity Too
+// Large. Input which fails to lex results in 422 Unprocessable Entity, along
+// with whatever Tokens were lexed before the failure, when responding with
+// application/json. When streaming the status can't be changed once the first
+// Token has been written, so after that point both cases are reported using
+// the final StreamError object instead, within a 200 OK response.
+type Handler struct {
+	// Lexers maps the names which can be given in the "lexer" query parameter
+	// to the lexer which they refer to. If nil lexgo's registry is used, see
+	// lexgo.Register
+	Lexers map[string]NewFunc
+
+	// TypeNames optionally gives names for the TokenTypes of each lexer, keyed
+	// by the same names as Lexers, which are included in responses
+	TypeNames map[string]func(lexgo.TokenType) string
+
+	// MaxBytes limits the size of request bodies. DefaultMaxBytes is used if
+	// this is zero
+	MaxBytes int64
+}
+
+// Token is the JSON representation of a lexgo.Token used in responses
+type Token struct {
+	Type   lexgo.TokenType `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Value  string          `json:"value"`
+	Row    int             `json:"row"`
+	Col    int             `json:"col"`
+	Offset int             `json:"offset"`
+
+	// Set for Warning Tokens, and Err Tokens which didn't end the stream (see
+	// lexgo.ResumeAfterError)
+	Error string `json:"error,omitempty"`
+}
+
+// Response is the body of application/json responses
+type Response struct {
+	Tokens []Token `json:"tokens"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// StreamError is the final line of an application/x-ndjson response when
+// lexing fails, which can be told apart from a Token by its lack of a "type"
+type StreamError struct {
+	Error string `json:"error"`
+}
+
+func (h *Handler) lexer(r *http.Request) (NewFunc, func(lexgo.TokenType) string, error) {
+	return lookup(h.Lexers, h.TypeNames, r)
+}
+
+// lookup returns the lexer chosen by the request's "lexer" query parameter, as
+// described by Handler
+func lookup(lexers map[string]NewFunc, typeNames map[string]func(lexgo.TokenType) string, r *http.Request) (NewFunc, func(lexgo.TokenType) string, error) {
+	name := r.URL.Query().Get("lexer")
+	if lexers == nil {
+		return registered(name, r.Header.Get("Content-Type"))
+	}
+
+	if name == "" && len(lexers) == 1 {
+		for name = range lexers {
+		}
+	}
+	if newFn, ok := lexers[name]; ok {
+		return newFn, typeNames[name], nil
+	}
+	return nil, nil, fmt.Errorf("unknown lexer %q, must be one of: %s", name, strings.Join(lexerNames(lexers), ", "))
+}
+
+// lexerNames returns the names of the lexers, in order, or those of the lexers
+// in lexgo's registry if lexers is nil
+func lexerNames(lexers map[string]NewFunc) []string {
+	var names []string
+	if lexers == nil {
+		for _, reg := range lexgo.Registered() {
+			names = append(names, reg.Name)
+		}
+		return names
+	}
+	for name := range lexers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registered looks up the lexer to use in lexgo's registry, by name if one was
+// given or else by the content type of the request
+func registered(name, contentType string) (NewFunc, func(lexgo.TokenType) string, error) {
+	var reg lexgo.Registration
+	var ok bool
+	if name != "" {
+		reg, ok = lexgo.Lookup(name)
+	} else if contentType != "" {
+		reg, ok = lexgo.LookupMIME(contentType)
+	}
+	if ok {
+		return reg.New, reg.TypeName, nil
+	}
+
+	var names []string
+	for _, reg := range lexgo.Registered() {
+		names = append(names, reg.Name)
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf("no lexer registered for content type %q, give one of: %s", contentType, strings.Join(names, ", "))
+	}
+	return nil, nil, fmt.Errorf("unknown lexer %q, must be one of: %s", name, strings.Join(names, ", "))
+}
+
+// negotiate returns the content type to respond with, given the request's
+// Accept header, or "" if none are acceptable
+func negotiate(accept string) string {
+	if accept == "" {
+		return ContentTypeJSON
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mt {
+		case ContentTypeJSON, "application/*", "*/*":
+			return ContentTypeJSON
+		case ContentTypeNDJSON:
+			return ContentTypeNDJSON
+		}
+	}
+	return ""
+}
+
+// ServeHTTP implements the method for http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	newFn, names, err := h.lexer(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	contentType := negotiate(r.Header.Get("Accept"))
+	if contentType == "" {
+		http.Error(w, "can only respond with "+ContentTypeJSON+" or "+ContentTypeNDJSON, http.StatusNotAcceptable)
+		return
+	}
+
+	maxBytes := h.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	body := http.MaxBytesReader(w, r.Body, maxBytes)
+	t := newFn(body)
+
+	if contentType == ContentTypeNDJSON {
+		h.stream(w, t, names)
+		return
+	}
+
+	var res Response
+	res.Tokens = []Token{}
+	lexErr := each(t, names, func(tok Token) error {
+		res.Tokens = append(res.Tokens, tok)
+		return nil
+	})
+
+	status := http.StatusOK
+	var maxErr *http.MaxBytesError
+	if errors.As(lexErr, &maxErr) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if lexErr != nil {
+		res.Error, status = lexErr.Error(), http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) stream(w http.ResponseWriter, t lexgo.Tokenizer, names func(lexgo.TokenType) string) {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	var wrote bool
+	lexErr := each(t, names, func(tok Token) error {
+		if !wrote {
+			w.Header().Set("Content-Type", ContentTypeNDJSON)
+			wrote = true
+		}
+		if err := enc.Encode(tok); err != nil {
+			return err
+		} else if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	var maxErr *http.MaxBytesError
+	if !wrote && errors.As(lexErr, &maxErr) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if !wrote {
+		w.Header().Set("Content-Type", ContentTypeNDJSON)
+	}
+	if lexErr != nil {
+		enc.Encode(StreamError{Error: lexErr.Error()})
+	}
+}
+
+// each calls fn with every Token read from t, until the stream ends or fn
+// returns an error. It returns the error which ended the stream, if it wasn't
+// io.EOF, or fn's error
+func each(t lexgo.Tokenizer, names func(lexgo.TokenType) string, fn func(Token) error) error {
+	for {
+		tok := t.Next()
+		if tok.EndsStream() {
+			err := tok.Err
+			tok.Release()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		jt := newToken(tok, names)
+		tok.Release()
+
+		if err := fn(jt); err != nil {
+			return err
+		}
+	}
+}
+
+// newToken returns the JSON representation of the lexgo.Token
+func newToken(tok *lexgo.Token, names func(lexgo.TokenType) string) Token {
+	jt := Token{
+		Type:   tok.TokenType,
+		Value:  tok.Val,
+		Row:    tok.Row,
+		Col:    tok.Col,
+		Offset: tok.Offset,
+	}
+	if tok.Err != nil {
+		jt.Error = tok.Err.Error()
+	} else if tok.Warn != nil {
+		jt.Error = tok.Warn.Error()
+	}
+	if names != nil {
+		jt.Name = names(tok.TokenType)
+	}
+	return jt
+}
@@ -0,0 +1,253 @@
+package lexhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// DefaultMaxSteps is the limit on LexerFunc runs used if Visualizer.MaxSteps
+// isn't set
+const DefaultMaxSteps = 100000
+
+// Visualizer is an http.Handler serving a web page for visualizing lexers,
+// intended for running locally when teaching or debugging. Input typed into
+// the page is lexed as it changes, and rendered with each Token as a colored
+// span which can be hovered over for its details. Alongside is the timeline of
+// state transitions, i.e. each LexerFunc run along with the Tokens it emitted,
+// which is useful for following lexers which switch between modes.
+//
+// The timeline is only available for lexers whose Tokenizer is a lexgo.Lexer,
+// or which have a Lexer method returning the one they wrap (like the markdown
+// lexer), see lexgo.Lexer.Step.
+//
+// GET requests are served the page, which POSTs its input back to the same URL
+// to have it lexed. Lexers are chosen in the same way as by Handler, using a
+// drop-down on the page.
+type Visualizer struct {
+	// Lexers, TypeNames and MaxBytes are as for Handler
+	Lexers    map[string]NewFunc
+	TypeNames map[string]func(lexgo.TokenType) string
+	MaxBytes  int64
+
+	// MaxSteps limits how many LexerFuncs are run for each input, so that a
+	// lexer stuck in a loop doesn't tie up the server. DefaultMaxSteps is used
+	// if this is zero
+	MaxSteps int
+}
+
+// traceToken is the representation of a Token sent to the page. Length is
+// that of the Token's raw text in bytes, so the page can tell which part of
+// the input it spans
+type traceToken struct {
+	Token
+	Length int `json:"length"`
+}
+
+// traceStep describes a single LexerFunc run. Offset is the offset of the
+// next rune to be read after it, and Tokens the indices of those it emitted
+type traceStep struct {
+	State  string `json:"state"`
+	Next   string `json:"next"`
+	Offset int    `json:"offset"`
+	Tokens []int  `json:"tokens"`
+}
+
+type trace struct {
+	Tokens []traceToken `json:"tokens"`
+	Steps  []traceStep  `json:"steps"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ServeHTTP implements the method for http.Handler
+func (v *Visualizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		visualizerPage.Execute(w, lexerNames(v.Lexers))
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "only GET and POST are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	newFn, names, err := lookup(v.Lexers, v.TypeNames, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	maxBytes := v.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	maxSteps := v.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	tr, lexErr := traceLex(newFn(http.MaxBytesReader(w, r.Body, maxBytes)), names, maxSteps)
+	var maxErr *http.MaxBytesError
+	if errors.As(lexErr, &maxErr) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if lexErr != nil {
+		tr.Error = lexErr.Error()
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	json.NewEncoder(w).Encode(tr)
+}
+
+// traceLex lexes everything from t, recording each state transition if t is,
+// or wraps, a lexgo.Lexer. It returns the error which ended the stream, if it
+// wasn't io.EOF
+func traceLex(t lexgo.Tokenizer, names func(lexgo.TokenType) string, maxSteps int) (trace, error) {
+	tr := trace{Tokens: []traceToken{}, Steps: []traceStep{}}
+	add := func(tok *lexgo.Token) {
+		tr.Tokens = append(tr.Tokens, traceToken{Token: newToken(tok, names), Length: len(tok.Raw)})
+	}
+
+	var l *lexgo.Lexer
+	switch t := t.(type) {
+	case *lexgo.Lexer:
+		l = t
+	case interface{ Lexer() *lexgo.Lexer }:
+		l = t.Lexer()
+	default:
+		for n := 0; ; n++ {
+			tok := t.Next()
+			if tok.EndsStream() {
+				return tr, streamErr(tok.Err)
+			} else if n >= maxSteps {
+				return tr, fmt.Errorf("stopped after %d tokens", maxSteps)
+			}
+			add(tok)
+		}
+	}
+
+	for len(tr.Steps) < maxSteps {
+		res, ok := l.Step()
+		_, _, off := l.NextPos()
+		step := traceStep{State: res.State, Next: res.Next, Offset: off, Tokens: []int{}}
+		for i := range res.Tokens {
+			tok := &res.Tokens[i]
+			if tok.EndsStream() {
+				tr.Steps = append(tr.Steps, step)
+				return tr, streamErr(tok.Err)
+			}
+			step.Tokens = append(step.Tokens, len(tr.Tokens))
+			add(tok)
+		}
+		tr.Steps = append(tr.Steps, step)
+		if !ok {
+			return tr, nil
+		}
+	}
+	return tr, fmt.Errorf("stopped after %d steps", maxSteps)
+}
+
+func streamErr(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+var visualizerPage = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>lexgo visualizer</title>
+<style>
+body{font-family:sans-serif;margin:1em;display:grid;grid-template-columns:1fr 22em;grid-gap:1em}
+textarea,#out{font-family:monospace;font-size:14px;width:100%;box-sizing:border-box}
+textarea{height:12em}
+#out{white-space:pre-wrap;border:1px solid #ccc;padding:4px;min-height:12em}
+#out span{border-radius:2px}
+#out span.err{background:#f88;text-decoration:underline wavy red}
+#out span.hl{outline:2px solid #333}
+#error{color:#c00}
+#steps{font-family:monospace;font-size:12px;max-height:90vh;overflow:auto;margin:0;padding-left:3em}
+#steps li{cursor:pointer}
+#steps li:hover,#steps li.hl{background:#eef}
+#steps .none{color:#999}
+</style></head><body>
+<div>
+<p><label>Lexer <select id="lexer">{{range .}}<option>{{.}}</option>{{end}}</select></label></p>
+<textarea id="input" spellcheck="false" placeholder="Type input to lex here"></textarea>
+<p id="error"></p>
+<div id="out"></div>
+<p id="hover">&nbsp;</p>
+</div>
+<div><h3>State transitions</h3><ol id="steps" start="1"></ol></div>
+<script>
+const input = document.getElementById("input"), out = document.getElementById("out"),
+	steps = document.getElementById("steps"), lexer = document.getElementById("lexer"),
+	errEl = document.getElementById("error"), hover = document.getElementById("hover");
+let spans = [], timer = null, seq = 0;
+
+function color(t) { return "hsl(" + ((t * 67) % 360) + ",70%,85%)"; }
+function describe(tok) {
+	return (tok.name || tok.type) + " " + tok.row + ":" + tok.col + " " +
+		JSON.stringify(tok.value) + (tok.error ? " (" + tok.error + ")" : "");
+}
+
+function render(text, tr) {
+	const bytes = new TextEncoder().encode(text), dec = new TextDecoder();
+	out.textContent = ""; steps.textContent = ""; spans = [];
+	errEl.textContent = tr.error || "";
+	let at = 0;
+	tr.tokens.forEach(function(tok, i) {
+		if (tok.offset > at) out.append(dec.decode(bytes.slice(at, tok.offset)));
+		const span = document.createElement("span");
+		span.textContent = dec.decode(bytes.slice(tok.offset, tok.offset + tok.length));
+		span.title = describe(tok);
+		if (tok.error) span.className = "err"; else span.style.background = color(tok.type);
+		span.onmouseenter = function() { hover.textContent = "token " + (i + 1) + ": " + describe(tok); };
+		out.append(span); spans.push(span);
+		at = Math.max(at, tok.offset + tok.length);
+	});
+	if (at < bytes.length) out.append(dec.decode(bytes.slice(at)));
+
+	tr.steps.forEach(function(step) {
+		const li = document.createElement("li");
+		li.textContent = (step.state || "(end)") + " → " + (step.next || "end") + " @" + step.offset;
+		if (step.tokens.length) {
+			li.textContent += ": " + step.tokens.map(function(i) {
+				return tr.tokens[i].name || tr.tokens[i].type;
+			}).join(" ");
+		} else {
+			li.className = "none";
+		}
+		li.onmouseenter = function() {
+			step.tokens.forEach(function(i) { spans[i].classList.add("hl"); });
+		};
+		li.onmouseleave = function() {
+			step.tokens.forEach(function(i) { spans[i].classList.remove("hl"); });
+		};
+		steps.append(li);
+	});
+}
+
+function lex() {
+	const text = input.value, mine = ++seq;
+	fetch(location.pathname + "?lexer=" + encodeURIComponent(lexer.value), {method: "POST", body: text})
+		.then(function(res) {
+			if (!res.ok) return res.text().then(function(msg) { throw new Error(msg); });
+			return res.json();
+		})
+		.then(function(tr) { if (mine === seq) render(text, tr); })
+		.catch(function(err) { if (mine === seq) errEl.textContent = err.message; });
+}
+
+input.addEventListener("input", function() { clearTimeout(timer); timer = setTimeout(lex, 150); });
+lexer.addEventListener("change", lex);
+lex();
+</script>
+</body></html>
+`))
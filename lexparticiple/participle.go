@@ -0,0 +1,101 @@
+// Package lexparticiple adapts lexgo lexers so that they can be used as the
+// lexer for parsers generated by github.com/alecthomas/participle.
+package lexparticiple
+
+import (
+	"io"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/mediocregopher/lexgo"
+)
+
+// Definition implements participle's lexer.Definition using a lexgo
+// Tokenizer. TokenTypes are passed through to participle unchanged, so the
+// values used in Types should be the same ones the Tokenizer emits
+type Definition struct {
+	// New is called to construct a new Tokenizer for every input participle
+	// wants lexed
+	New func(io.Reader) lexgo.Tokenizer
+
+	// Types maps the names participle grammars will use to refer to tokens to
+	// the TokenTypes the Tokenizer emits for them. "EOF" is added
+	// automatically
+	Types map[string]lexgo.TokenType
+}
+
+var _ lexer.Definition = new(Definition)
+
+// Symbols implements the method for lexer.Definition
+func (d *Definition) Symbols() map[string]lexer.TokenType {
+	m := make(map[string]lexer.TokenType, len(d.Types)+1)
+	for name, t := range d.Types {
+		m[name] = lexer.TokenType(t)
+	}
+	m["EOF"] = lexer.EOF
+	return m
+}
+
+// Lex implements the method for lexer.Definition
+func (d *Definition) Lex(filename string, r io.Reader) (lexer.Lexer, error) {
+	return &Lexer{
+		Filename:  filename,
+		Tokenizer: d.New(r),
+	}, nil
+}
+
+// Lexer implements participle's lexer.Lexer on top of a lexgo Tokenizer
+type Lexer struct {
+	// Used in the Position of all Tokens returned
+	Filename string
+
+	lexgo.Tokenizer
+
+	// position just past the end of the most recent token, used for the EOF
+	// token. Only set once a token has been returned
+	endPos    lexer.Position
+	endPosSet bool
+}
+
+var _ lexer.Lexer = new(Lexer)
+
+// Next implements the method for lexer.Lexer. An Err Token from the underlying
+// Tokenizer gets returned as an error, except for io.EOF which gets returned
+// as participle's EOF token, positioned at the end of the last Token. Warning
+// Tokens are skipped, since participle has no notion of them.
+func (l *Lexer) Next() (lexer.Token, error) {
+	t := l.Tokenizer.Next()
+	for t.TokenType == lexgo.Warning && t.Err == nil {
+		t.Release()
+		t = l.Tokenizer.Next()
+	}
+	defer t.Release()
+
+	if t.Err == io.EOF {
+		if !l.endPosSet {
+			l.endPos = lexer.Position{Filename: l.Filename, Line: 1, Column: 1}
+		}
+		return lexer.EOFToken(l.endPos), nil
+	} else if t.Err != nil {
+		return lexer.Token{}, t.Err
+	}
+
+	pos := lexer.Position{
+		Filename: l.Filename,
+		Offset:   t.Offset,
+		Line:     t.Row,
+		Column:   t.Col,
+	}
+
+	raw := t.Raw
+	if raw == "" {
+		raw = t.Val
+	}
+	l.endPos, l.endPosSet = pos, true
+	l.endPos.Advance(raw)
+
+	return lexer.Token{
+		Type:  lexer.TokenType(t.TokenType),
+		Value: t.Val,
+		Pos:   pos,
+	}, nil
+}
@@ -0,0 +1,300 @@
+package lexrule
+
+import (
+	"fmt"
+	"io"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxDFASize limits the size of the transition table built by CompileDFA, in
+// entries. Specs whose DFAs would need more than this fail to compile
+var MaxDFASize = 1 << 22
+
+// CompileDFA is like Compile, but the Def it returns matches each mode's rules
+// using a single DFA built from the union of their patterns, rather than by
+// trying each rule in turn. The rule chosen at each position is the same as
+// it would be for a Def returned from Compile, but the input is only read
+// through once, no matter how many rules there are.
+//
+// The DFA is built in full up front, which can take time and memory for large
+// Specs, see MaxDFASize. Patterns can't use empty-width assertions, i.e. ^, $,
+// \A, \z, \b or \B, and rules can't be Lazy.
+func CompileDFA(spec Spec) (*Def, error) {
+	d, err := Compile(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	d.dfas = map[string]*dfa{}
+	for mode, rules := range d.modes {
+		if d.dfas[mode], err = newDFA(rules, spec.MaximalMunch); err != nil {
+			if mode != "" {
+				err = fmt.Errorf("mode %q: %w", mode, err)
+			}
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// dfa matches a set of rules at once. State 0 is the dead state, from which
+// nothing can match, and state 1 is the start state
+type dfa struct {
+	// classes holds the lowest rune of each class of runes, sorted. All runes
+	// in a class, i.e. from its lowest rune up to the next class's, have the
+	// same transitions. ascii maps ASCII runes directly to their class
+	classes []rune
+	ascii   [utf8.RuneSelf]int
+
+	// trans[state*len(classes)+class] is the state to go to from state on a
+	// rune in class
+	trans []int
+
+	// accepts lists, for each state, the indexes of the rules which have
+	// matched on reaching it
+	accepts [][]int
+
+	// When using MaximalMunch, best holds the index of the rule which wins
+	// out of each state's accepts, or -1 if there are none
+	best []int
+}
+
+// thread is an instruction within one rule's compiled program
+type thread struct {
+	rule, pc int
+}
+
+func newDFA(rules []rule, munch bool) (*dfa, error) {
+	progs := make([]*syntax.Prog, len(rules))
+	for i, r := range rules {
+		if r.Lazy {
+			return nil, fmt.Errorf("rule %q: Lazy rules aren't supported", r.Name)
+		}
+		re, err := syntax.Parse(r.Pattern, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		if progs[i], err = syntax.Compile(re.Simplify()); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		for _, inst := range progs[i].Inst {
+			if inst.Op == syntax.InstEmptyWidth {
+				return nil, fmt.Errorf("rule %q: empty-width assertions aren't supported", r.Name)
+			}
+		}
+	}
+
+	d := &dfa{classes: runeClasses(progs)}
+	for r := range d.ascii {
+		d.ascii[r] = d.search(rune(r))
+	}
+
+	var follow func(set, seen map[thread]bool, t thread)
+	follow = func(set, seen map[thread]bool, t thread) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch inst := progs[t.rule].Inst[t.pc]; inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			follow(set, seen, thread{t.rule, int(inst.Out)})
+			follow(set, seen, thread{t.rule, int(inst.Arg)})
+		case syntax.InstCapture, syntax.InstNop:
+			follow(set, seen, thread{t.rule, int(inst.Out)})
+		case syntax.InstFail:
+		default:
+			set[t] = true
+		}
+	}
+	// closure adds to set every thread reachable from t without reading a
+	// rune, keeping only those which read runes or match
+	closure := func(set map[thread]bool, t thread) {
+		follow(set, map[thread]bool{}, t)
+	}
+
+	// states are identified by their sorted threads
+	var sets [][]thread
+	ids := map[string]int{}
+	addState := func(set map[thread]bool) int {
+		threads := make([]thread, 0, len(set))
+		for t := range set {
+			threads = append(threads, t)
+		}
+		sort.Slice(threads, func(i, j int) bool {
+			a, b := threads[i], threads[j]
+			return a.rule < b.rule || (a.rule == b.rule && a.pc < b.pc)
+		})
+
+		var key strings.Builder
+		for _, t := range threads {
+			fmt.Fprintf(&key, "%d.%d,", t.rule, t.pc)
+		}
+		if id, ok := ids[key.String()]; ok {
+			return id
+		}
+
+		id := len(sets)
+		ids[key.String()] = id
+		sets = append(sets, threads)
+		var accepts []int
+		for _, t := range threads {
+			if progs[t.rule].Inst[t.pc].Op == syntax.InstMatch {
+				accepts = append(accepts, t.rule)
+			}
+		}
+		d.accepts = append(d.accepts, accepts)
+		return id
+	}
+
+	addState(map[thread]bool{})
+	start := map[thread]bool{}
+	for i, prog := range progs {
+		closure(start, thread{i, prog.Start})
+	}
+	addState(start)
+
+	for state := 1; state < len(sets); state++ {
+		if (state+1)*len(d.classes) > MaxDFASize {
+			return nil, fmt.Errorf("DFA is larger than MaxDFASize (%d)", MaxDFASize)
+		}
+		for _, lo := range d.classes {
+			next := map[thread]bool{}
+			for _, t := range sets[state] {
+				inst := progs[t.rule].Inst[t.pc]
+				if inst.Op != syntax.InstMatch && inst.MatchRune(lo) {
+					closure(next, thread{t.rule, int(inst.Out)})
+				}
+			}
+			d.trans = append(d.trans, addState(next))
+		}
+	}
+
+	// The dead state's transitions weren't added by the loop
+	d.trans = append(make([]int, len(d.classes)), d.trans...)
+
+	if munch {
+		d.best = make([]int, len(d.accepts))
+		for state, accepts := range d.accepts {
+			d.best[state] = -1
+			for _, i := range accepts {
+				if b := d.best[state]; b < 0 || rules[i].Priority > rules[b].Priority {
+					d.best[state] = i
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// runeClasses splits the range of all runes into classes, such that every
+// rune instruction in progs treats all runes of a class the same way
+func runeClasses(progs []*syntax.Prog) []rune {
+	bounds := map[rune]bool{0: true}
+	add := func(lo, hi rune) {
+		bounds[lo] = true
+		if hi < unicode.MaxRune {
+			bounds[hi+1] = true
+		}
+	}
+	for _, prog := range progs {
+		for _, inst := range prog.Inst {
+			switch inst.Op {
+			case syntax.InstRune:
+				if len(inst.Rune) == 1 && syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+					for r := inst.Rune[0]; ; {
+						add(r, r)
+						if r = unicode.SimpleFold(r); r == inst.Rune[0] {
+							break
+						}
+					}
+					continue
+				}
+				for i := 0; i+1 < len(inst.Rune); i += 2 {
+					add(inst.Rune[i], inst.Rune[i+1])
+				}
+				if len(inst.Rune) == 1 {
+					add(inst.Rune[0], inst.Rune[0])
+				}
+			case syntax.InstRune1:
+				add(inst.Rune[0], inst.Rune[0])
+			case syntax.InstRuneAnyNotNL:
+				add('\n', '\n')
+			}
+		}
+	}
+
+	classes := make([]rune, 0, len(bounds))
+	for r := range bounds {
+		classes = append(classes, r)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	return classes
+}
+
+// class returns the index of the class r belongs to
+func (d *dfa) class(r rune) int {
+	if r >= 0 && r < utf8.RuneSelf {
+		return d.ascii[r]
+	}
+	return d.search(r)
+}
+
+func (d *dfa) search(r rune) int {
+	return sort.Search(len(d.classes), func(i int) bool { return d.classes[i] > r }) - 1
+}
+
+// match runs the DFA over rr, until it can't match anything more, and returns
+// the index of the first rule to have matched anything, along with the length
+// in bytes of its longest match. When using MaximalMunch it's the rule with
+// the longest match which is returned instead. ends is used to track each
+// rule's longest match, and must have the same length as the number of rules.
+func (d *dfa) match(rr io.RuneReader, ends []int) (int, int) {
+	if d.best != nil {
+		return d.munch(rr)
+	}
+
+	for i := range ends {
+		ends[i] = 0
+	}
+	for state, n := 1, 0; ; {
+		r, size, err := rr.ReadRune()
+		if err != nil {
+			break
+		}
+		n += size
+		if state = d.trans[state*len(d.classes)+d.class(r)]; state == 0 {
+			break
+		}
+		for _, rule := range d.accepts[state] {
+			ends[rule] = n
+		}
+	}
+
+	for rule, n := range ends {
+		if n > 0 {
+			return rule, n
+		}
+	}
+	return -1, 0
+}
+
+func (d *dfa) munch(rr io.RuneReader) (int, int) {
+	best, bestN := -1, 0
+	for state, n := 1, 0; ; {
+		r, size, err := rr.ReadRune()
+		if err != nil {
+			break
+		}
+		n += size
+		if state = d.trans[state*len(d.classes)+d.class(r)]; state == 0 {
+			break
+		} else if b := d.best[state]; b >= 0 {
+			best, bestN = b, n
+		}
+	}
+	return best, bestN
+}
@@ -0,0 +1,179 @@
+package lexrule
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// replayReader records the runes read from an io.RuneReader, so that they can
+// be read again from the start by each of a number of regexps
+type replayReader struct {
+	rr    io.RuneReader
+	runes []rune
+	sizes []int
+	err   error
+	i     int
+}
+
+func (r *replayReader) ReadRune() (rune, int, error) {
+	if r.i == len(r.runes) {
+		if r.err != nil {
+			return 0, 0, r.err
+		}
+		c, size, err := r.rr.ReadRune()
+		if err != nil {
+			r.err = err
+			return 0, 0, err
+		}
+		r.runes, r.sizes = append(r.runes, c), append(r.sizes, size)
+	}
+	r.i++
+	return r.runes[r.i-1], r.sizes[r.i-1], nil
+}
+
+// munch matches every rule against rr and returns the index of the one with
+// the longest match, breaking ties by Priority and then order, along with the
+// length of its match in bytes
+func munch(rules []rule, rr io.RuneReader) (int, int) {
+	replay := &replayReader{rr: rr}
+	best, bestN := -1, 0
+	for i, rule := range rules {
+		replay.i = 0
+		n := rule.match(replay)
+		if n == 0 || n < bestN {
+			continue
+		} else if n == bestN && rule.Priority <= rules[best].Priority {
+			continue
+		}
+		best, bestN = i, n
+	}
+	return best, bestN
+}
+
+// Ambiguity describes text which two rules of the same mode and Priority can
+// both match in full, see Ambiguities
+type Ambiguity struct {
+	Mode string
+
+	// The names of the two rules. First is the one declared first, and so is
+	// the one which is used when MaximalMunch is set
+	First, Second string
+
+	// Example is the shortest text which both rules match
+	Example string
+}
+
+func (a Ambiguity) String() string {
+	mode := ""
+	if a.Mode != "" {
+		mode = fmt.Sprintf("mode %q: ", a.Mode)
+	}
+	return fmt.Sprintf("%srules %q and %q both match %q", mode, a.First, a.Second, a.Example)
+}
+
+// Ambiguities returns every pair of rules which are in the same mode, have the
+// same Priority, and which can both match the same text. When using
+// MaximalMunch, which of the two is used for that text depends only on the
+// order they're declared in, which is often a mistake, e.g. an identifier rule
+// declared before a keyword rule. Giving one of the rules a higher Priority
+// resolves the ambiguity.
+//
+// Finding ambiguities involves building the same DFAs as CompileDFA, and so
+// has the same restrictions on the patterns which can be used.
+func Ambiguities(spec Spec) ([]Ambiguity, error) {
+	d, err := Compile(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	modes := make([]string, 0, len(d.modes))
+	for mode := range d.modes {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	var all []Ambiguity
+	for _, mode := range modes {
+		rules := d.modes[mode]
+		dfa, err := newDFA(rules, false)
+		if err != nil {
+			if mode != "" {
+				err = fmt.Errorf("mode %q: %w", mode, err)
+			}
+			return nil, err
+		}
+
+		for _, a := range dfa.ambiguities(rules) {
+			a.Mode = mode
+			all = append(all, a)
+		}
+	}
+	return all, nil
+}
+
+// ambiguities searches the DFA breadth first, so that the example found for
+// each pair of rules is as short as possible. They're returned in the order
+// the rules were declared
+func (d *dfa) ambiguities(rules []rule) []Ambiguity {
+	type pair struct{ a, b int }
+	found := map[pair]string{}
+	var pairs []pair
+
+	examples := map[int]string{1: ""}
+	for queue := []int{1}; len(queue) > 0; queue = queue[1:] {
+		state := queue[0]
+		accepts := d.accepts[state]
+		for i, a := range accepts {
+			for _, b := range accepts[i+1:] {
+				p := pair{a, b}
+				if _, ok := found[p]; ok || rules[a].Priority != rules[b].Priority {
+					continue
+				}
+				found[p] = examples[state]
+				pairs = append(pairs, p)
+			}
+		}
+
+		for c := range d.classes {
+			next := d.trans[state*len(d.classes)+c]
+			if _, ok := examples[next]; next == 0 || ok {
+				continue
+			}
+			examples[next] = examples[state] + string(d.example(c))
+			queue = append(queue, next)
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].a != pairs[j].a {
+			return pairs[i].a < pairs[j].a
+		}
+		return pairs[i].b < pairs[j].b
+	})
+	ambs := make([]Ambiguity, len(pairs))
+	for i, p := range pairs {
+		ambs[i] = Ambiguity{
+			First:   rules[p.a].Name,
+			Second:  rules[p.b].Name,
+			Example: found[p],
+		}
+	}
+	return ambs
+}
+
+// example returns a rune of the given class, preferring printable ones
+func (d *dfa) example(class int) rune {
+	lo, hi := d.classes[class], rune(unicode.MaxRune)
+	if class+1 < len(d.classes) {
+		hi = d.classes[class+1] - 1
+	}
+	for r := lo; r <= hi && r < lo+256; r++ {
+		if unicode.IsPrint(r) && r != utf8.RuneError {
+			return r
+		}
+	}
+	return lo
+}
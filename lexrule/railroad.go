@@ -0,0 +1,410 @@
+package lexrule
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dimensions used when laying out railroad diagrams, in pixels
+const (
+	rrCharW  = 8  // width of a character of text
+	rrBoxH   = 22 // height of a box
+	rrRadius = 10 // radius of the curves joining and leaving choices and loops
+	rrGapH   = 10 // horizontal gap between the items of a sequence
+	rrGapV   = 8  // vertical gap between the rows of a choice
+	rrPad    = 10 // padding around the whole diagram
+	rrTitleH = 24 // height of the title
+	rrLabelH = 14 // height of the label beneath a loop
+
+	rrMaxText = 32 // longest text shown in a box, in characters
+)
+
+const rrStyle = `path{fill:none;stroke:#333;stroke-width:1.5}` +
+	`rect{fill:#e8f0ff;stroke:#333;stroke-width:1.5}` +
+	`rect.special{fill:#f4f4f4;stroke-dasharray:4 2}` +
+	`text{font:13px monospace;text-anchor:middle}` +
+	`text.title{font-weight:bold;text-anchor:start}` +
+	`text.label{font-size:11px;fill:#555}`
+
+// rrNode is an element of a railroad diagram. Each node is drawn between two
+// points on a horizontal line, its width apart, extending up and down from it
+type rrNode interface {
+	size() (w, up, down int)
+	draw(b *bytes.Buffer, x, y int)
+}
+
+func rrLine(b *bytes.Buffer, x1, y, x2 int) {
+	if x1 != x2 {
+		fmt.Fprintf(b, `<path d="M%d %dH%d"/>`, x1, y, x2)
+	}
+}
+
+// rrBox is a single box of text, for a literal, a character class or an
+// assertion
+type rrBox struct {
+	text    string
+	rounded bool
+	special bool
+}
+
+func (n rrBox) size() (int, int, int) {
+	return utf8.RuneCountInString(n.text)*rrCharW + 2*rrCharW, rrBoxH / 2, rrBoxH / 2
+}
+
+func (n rrBox) draw(b *bytes.Buffer, x, y int) {
+	w, up, _ := n.size()
+	rx, class := 0, ""
+	if n.rounded {
+		rx = rrBoxH / 2
+	}
+	if n.special {
+		class = ` class="special"`
+	}
+	fmt.Fprintf(b, `<rect%s x="%d" y="%d" width="%d" height="%d" rx="%d"/>`, class, x, y-up, w, rrBoxH, rx)
+	fmt.Fprintf(b, `<text x="%d" y="%d">%s</text>`, x+w/2, y+4, html.EscapeString(n.text))
+}
+
+// rrSkip is an empty path, e.g. the way around an optional node
+type rrSkip struct{}
+
+func (rrSkip) size() (int, int, int)        { return 0, 0, 0 }
+func (rrSkip) draw(*bytes.Buffer, int, int) {}
+
+type rrSeq []rrNode
+
+func (n rrSeq) size() (int, int, int) {
+	var w, up, down int
+	for i, node := range n {
+		nw, nup, ndown := node.size()
+		if i > 0 {
+			w += rrGapH
+		}
+		w += nw
+		up, down = maxInt(up, nup), maxInt(down, ndown)
+	}
+	return w, up, down
+}
+
+func (n rrSeq) draw(b *bytes.Buffer, x, y int) {
+	for i, node := range n {
+		if i > 0 {
+			rrLine(b, x, y, x+rrGapH)
+			x += rrGapH
+		}
+		node.draw(b, x, y)
+		w, _, _ := node.size()
+		x += w
+	}
+}
+
+// rrChoice is a set of alternative rows, the first of which is on the line
+// and the others of which are stacked beneath it
+type rrChoice []rrNode
+
+// rows returns the width of the widest row, and how far beneath the line each
+// row is
+func (n rrChoice) rows() (int, []int) {
+	var inner int
+	offsets := make([]int, len(n))
+	var prevDown int
+	for i, node := range n {
+		w, up, down := node.size()
+		inner = maxInt(inner, w)
+		if i > 0 {
+			offsets[i] = maxInt(offsets[i-1]+prevDown+rrGapV+up, offsets[i-1]+2*rrRadius)
+		}
+		prevDown = down
+	}
+	return inner, offsets
+}
+
+func (n rrChoice) size() (int, int, int) {
+	inner, offsets := n.rows()
+	_, up, down := n[0].size()
+	if last := len(n) - 1; last > 0 {
+		_, _, lastDown := n[last].size()
+		down = offsets[last] + lastDown
+	}
+	return inner + 4*rrRadius, up, down
+}
+
+func (n rrChoice) draw(b *bytes.Buffer, x, y int) {
+	const r = rrRadius
+	inner, offsets := n.rows()
+	left, right := x+2*r, x+2*r+inner
+	for i, node := range n {
+		w, _, _ := node.size()
+		ry := y + offsets[i]
+		if i == 0 {
+			rrLine(b, x, y, left)
+		} else {
+			fmt.Fprintf(b, `<path d="M%d %dQ%d %d %d %dV%dQ%d %d %d %d"/>`,
+				x, y, x+r, y, x+r, y+r, ry-r, x+r, ry, left, ry)
+		}
+		node.draw(b, left, ry)
+		rrLine(b, left+w, ry, right)
+		if i == 0 {
+			rrLine(b, right, y, right+2*r)
+		} else {
+			fmt.Fprintf(b, `<path d="M%d %dQ%d %d %d %dV%dQ%d %d %d %d"/>`,
+				right, ry, right+r, ry, right+r, ry-r, y+r, right+r, y, right+2*r, y)
+		}
+	}
+}
+
+// rrLoop is a node which can be repeated, by following the path back beneath
+// it, which may be labeled
+type rrLoop struct {
+	node  rrNode
+	label string
+}
+
+func (n rrLoop) loopY() int {
+	_, _, down := n.node.size()
+	return maxInt(down+rrGapV, 2*rrRadius)
+}
+
+func (n rrLoop) size() (int, int, int) {
+	w, up, _ := n.node.size()
+	down := n.loopY()
+	if n.label != "" {
+		down += rrLabelH
+	}
+	return w + 2*rrRadius, up, down
+}
+
+func (n rrLoop) draw(b *bytes.Buffer, x, y int) {
+	const r = rrRadius
+	w, _, _ := n.size()
+	nw, _, _ := n.node.size()
+	ly := y + n.loopY()
+
+	rrLine(b, x, y, x+r)
+	n.node.draw(b, x+r, y)
+	rrLine(b, x+r+nw, y, x+w)
+	fmt.Fprintf(b, `<path d="M%d %dQ%d %d %d %dV%dQ%d %d %d %dH%dQ%d %d %d %dV%dQ%d %d %d %d"/>`,
+		x+w-r, y, x+w, y, x+w, y+r, ly-r, x+w, ly, x+w-r, ly,
+		x+r, x, ly, x, ly-r, y+r, x, y, x+r, y)
+	if n.label != "" {
+		fmt.Fprintf(b, `<text class="label" x="%d" y="%d">%s</text>`, x+w/2, ly+rrLabelH-2, html.EscapeString(n.label))
+	}
+}
+
+// rrText returns text for a box, truncated if it's too long
+func rrText(s string) string {
+	if utf8.RuneCountInString(s) > rrMaxText {
+		s = string([]rune(s)[:rrMaxText-1]) + "…"
+	}
+	return s
+}
+
+var rrAssertions = map[syntax.Op]string{
+	syntax.OpBeginLine:      "start of line",
+	syntax.OpEndLine:        "end of line",
+	syntax.OpBeginText:      "start of text",
+	syntax.OpEndText:        "end of text",
+	syntax.OpWordBoundary:   "word boundary",
+	syntax.OpNoWordBoundary: "not word boundary",
+	syntax.OpAnyChar:        "any character",
+	syntax.OpAnyCharNotNL:   "any character but newline",
+	syntax.OpNoMatch:        "nothing",
+}
+
+// rrBuild converts a parsed regexp to a railroad diagram. Non-greedy
+// repetitions are only labeled as such for Lazy rules, as they're otherwise
+// no different
+func rrBuild(re *syntax.Regexp, lazy bool) rrNode {
+	sub := func() []rrNode {
+		nodes := make([]rrNode, len(re.Sub))
+		for i, s := range re.Sub {
+			nodes[i] = rrBuild(s, lazy)
+		}
+		return nodes
+	}
+	label := func(s string) string {
+		if lazy && re.Flags&syntax.NonGreedy != 0 {
+			if s != "" {
+				s += ", "
+			}
+			s += "lazy"
+		}
+		return s
+	}
+
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return rrSkip{}
+	case syntax.OpLiteral:
+		text := fmt.Sprintf("%q", string(re.Rune))
+		if re.Flags&syntax.FoldCase != 0 {
+			text += " (any case)"
+		}
+		return rrBox{text: rrText(text), rounded: true}
+	case syntax.OpCharClass:
+		return rrBox{text: rrText(re.String())}
+	case syntax.OpCapture:
+		return rrBuild(re.Sub[0], lazy)
+	case syntax.OpConcat:
+		return rrSeq(sub())
+	case syntax.OpAlternate:
+		return rrChoice(sub())
+	case syntax.OpQuest:
+		return rrChoice{rrSkip{}, rrBuild(re.Sub[0], lazy)}
+	case syntax.OpStar:
+		return rrChoice{rrSkip{}, rrLoop{rrBuild(re.Sub[0], lazy), label("")}}
+	case syntax.OpPlus:
+		return rrLoop{rrBuild(re.Sub[0], lazy), label("")}
+	case syntax.OpRepeat:
+		var times string
+		switch {
+		case re.Max == -1:
+			times = fmt.Sprintf("%d or more times", re.Min)
+		case re.Min == re.Max:
+			times = fmt.Sprintf("%d times", re.Min)
+		default:
+			times = fmt.Sprintf("%d to %d times", re.Min, re.Max)
+		}
+		var node rrNode = rrLoop{rrBuild(re.Sub[0], lazy), label(times)}
+		if re.Min == 0 {
+			node = rrChoice{rrSkip{}, node}
+		}
+		return node
+	}
+	if text, ok := rrAssertions[re.Op]; ok {
+		return rrBox{text: text, special: true}
+	}
+	return rrBox{text: rrText(re.String()), special: true}
+}
+
+// railroad writes an SVG railroad diagram of the rule's Pattern to w, titled
+// with the rule's Name if title is set
+func railroad(w io.Writer, r Rule, title bool) error {
+	re, err := syntax.Parse(r.Pattern, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	node := rrBuild(re, r.Lazy)
+	nw, up, down := node.size()
+
+	const marker = 2 * rrGapH
+	top := rrPad
+	if title {
+		top += rrTitleH
+	}
+	width := nw + 2*rrPad + 2*marker
+	height := top + up + down + rrPad
+	y := top + up
+
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	fmt.Fprintf(b, `<style>%s</style>`, rrStyle)
+	if title {
+		fmt.Fprintf(b, `<text class="title" x="%d" y="%d">%s</text>`, rrPad, rrPad+rrTitleH/2+4, html.EscapeString(r.Name))
+	}
+
+	// The start and end of the diagram are marked by short vertical bars
+	x := rrPad
+	fmt.Fprintf(b, `<path d="M%d %dV%dM%d %dV%d"/>`, x, y-rrBoxH/4, y+rrBoxH/4, x+4, y-rrBoxH/4, y+rrBoxH/4)
+	rrLine(b, x, y, x+marker)
+	node.draw(b, x+marker, y)
+	x += marker + nw
+	rrLine(b, x, y, x+marker)
+	x += marker
+	fmt.Fprintf(b, `<path d="M%d %dV%dM%d %dV%d"/>`, x, y-rrBoxH/4, y+rrBoxH/4, x-4, y-rrBoxH/4, y+rrBoxH/4)
+	b.WriteString("</svg>\n")
+
+	_, err = b.WriteTo(w)
+	return err
+}
+
+// Railroad writes an SVG railroad diagram of the rule's Pattern to w, so that
+// what the rule matches can be checked visually. The diagram is titled with
+// the rule's Name.
+func Railroad(w io.Writer, r Rule) error {
+	return railroad(w, r, true)
+}
+
+// RailroadHTML writes an HTML page to w containing a railroad diagram, as
+// produced by Railroad, of every rule in the Spec, grouped by mode, along with
+// the rule's other fields and its Keywords.
+func RailroadHTML(w io.Writer, spec Spec, title string) error {
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title>\n", html.EscapeString(title))
+	b.WriteString("<style>body{font-family:sans-serif}h3{margin-bottom:0}p{margin:4px 0}code{background:#f4f4f4}</style>\n")
+	fmt.Fprintf(b, "</head><body>\n<h1>%s</h1>\n", html.EscapeString(title))
+
+	modes := make([]string, 0, len(spec.Modes))
+	for mode := range spec.Modes {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	modes = append([]string{""}, modes...)
+
+	for _, mode := range modes {
+		rules := spec.Rules
+		if mode != "" {
+			rules = spec.Modes[mode]
+			fmt.Fprintf(b, "<h2>Mode %s</h2>\n", html.EscapeString(mode))
+		} else if len(spec.Modes) > 0 {
+			b.WriteString("<h2>Default mode</h2>\n")
+		}
+
+		for _, r := range rules {
+			fmt.Fprintf(b, "<h3 id=\"%s\">%s</h3>\n", html.EscapeString(mode+"."+r.Name), html.EscapeString(r.Name))
+			if fields := rrFields(r); len(fields) > 0 {
+				fmt.Fprintf(b, "<p>%s</p>\n", html.EscapeString(strings.Join(fields, ", ")))
+			}
+			if err := railroad(b, r, false); err != nil {
+				if mode != "" {
+					err = fmt.Errorf("mode %q: %w", mode, err)
+				}
+				return err
+			}
+			for _, kw := range r.Keywords {
+				words := make([]string, len(kw.Words))
+				for i, w := range kw.Words {
+					words[i] = "<code>" + html.EscapeString(w) + "</code>"
+				}
+				fmt.Fprintf(b, "<p>%s: %s</p>\n", html.EscapeString(kw.Name), strings.Join(words, " "))
+			}
+		}
+	}
+	b.WriteString("</body></html>\n")
+
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// rrFields describes the fields of the rule other than its Pattern
+func rrFields(r Rule) []string {
+	var fields []string
+	if r.Skip {
+		fields = append(fields, "skip")
+	}
+	if r.Lazy {
+		fields = append(fields, "lazy")
+	}
+	if r.Pop {
+		fields = append(fields, "pop")
+	}
+	if r.Push != "" {
+		fields = append(fields, "push "+r.Push)
+	}
+	if r.Priority != 0 {
+		fields = append(fields, fmt.Sprintf("priority %d", r.Priority))
+	}
+	return fields
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,283 @@
+// Package lexrule builds lexgo lexers out of declarative rules, rather than
+// hand-written LexerFuncs. Each rule is a regular expression along with the
+// TokenType to emit when it matches. Rules are plain data, so they can be
+// loaded at runtime (see the lexstar package) or generated by other tools.
+package lexrule
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// Rule describes a single kind of Token
+type Rule struct {
+	// Name identifies the rule in errors, and is the name of its TokenType
+	// for Spec.Types
+	Name string
+
+	// Pattern is a regular expression, in the syntax of the regexp package,
+	// which is matched at the current position using leftmost-longest
+	// semantics, unless Lazy is set. Empty matches are never considered a
+	// match
+	Pattern string
+
+	// If Lazy is set Pattern is matched using the regexp package's default,
+	// leftmost-first, semantics instead, under which non-greedy repetitions
+	// are honored, e.g. so that `/\*.*?\*/` matches only a single comment.
+	// Lazy rules can't be used with CompileDFA
+	Lazy bool
+
+	// Type is the TokenType emitted for the matched text
+	Type lexgo.TokenType
+
+	// If Skip is set the matched text is discarded rather than emitted, e.g.
+	// for whitespace and comments
+	Skip bool
+
+	// If Pop is set the lexer returns to the mode it was in before the
+	// current one was pushed, once this rule has matched. Popping the default
+	// mode does nothing
+	Pop bool
+
+	// If Push is set the lexer enters the named mode once this rule has
+	// matched, after any Pop. Push must name one of the Spec's Modes
+	Push string
+
+	// Priority breaks ties between rules whose matches are the same length,
+	// when the Spec uses MaximalMunch. The rule with the higher Priority wins,
+	// or the one declared first if they're equal, see Ambiguities
+	Priority int
+
+	// Keywords are words which, when they're the whole of the text the rule
+	// matches, are emitted with the Keyword's Type rather than the rule's. This
+	// is simpler and faster than a separate rule for each keyword, e.g. for
+	// keywords which would otherwise be matched as identifiers
+	Keywords []Keyword
+}
+
+// Keyword describes a set of words which are given their own TokenType, see
+// Rule.Keywords
+type Keyword struct {
+	// Name is the name of the TokenType, for Spec.Types
+	Name  string
+	Type  lexgo.TokenType
+	Words []string
+}
+
+// Spec is a complete set of rules making up a lexer
+type Spec struct {
+	// Rules are used by the default mode, which is the one the lexer starts
+	// in
+	Rules []Rule
+
+	// Modes are further sets of rules, keyed by name, which the lexer only
+	// uses once one has been entered using Rule.Push. They're useful for
+	// contexts like string literals or comments, where the input means
+	// something different than it does elsewhere
+	Modes map[string][]Rule
+
+	// If MaximalMunch is set the rule with the longest match at each position
+	// is used, as it is by lex and flex. Otherwise the first rule to match is
+	// used, however short its match is
+	MaximalMunch bool
+}
+
+// Types returns the TokenType of every non-Skip rule, keyed by the rule's
+// Name. This is useful for things like lexparticiple.Definition's Types
+func (s Spec) Types() map[string]lexgo.TokenType {
+	m := map[string]lexgo.TokenType{}
+	add := func(rules []Rule) {
+		for _, r := range rules {
+			if !r.Skip {
+				m[r.Name] = r.Type
+			}
+			for _, kw := range r.Keywords {
+				m[kw.Name] = kw.Type
+			}
+		}
+	}
+	add(s.Rules)
+	for _, rules := range s.Modes {
+		add(rules)
+	}
+	return m
+}
+
+type rule struct {
+	Rule
+
+	// anchored is the Pattern anchored at the start, and using
+	// leftmost-longest semantics unless the rule is Lazy
+	anchored *regexp.Regexp
+
+	keywords map[string]lexgo.TokenType
+}
+
+// Def is a compiled Spec, from which any number of Lexers can be created
+type Def struct {
+	spec  Spec
+	modes map[string][]rule
+
+	// set by CompileDFA
+	dfas map[string]*dfa
+}
+
+// Compile checks and compiles the rules in the given Spec
+func Compile(spec Spec) (*Def, error) {
+	d := &Def{spec: spec, modes: map[string][]rule{}}
+	compile := func(mode string, rules []Rule) error {
+		for _, r := range rules {
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("rule %q: %w", r.Name, err)
+			}
+			if _, ok := spec.Modes[r.Push]; r.Push != "" && !ok {
+				return fmt.Errorf("rule %q: unknown mode %q", r.Name, r.Push)
+			} else if r.Skip && len(r.Keywords) > 0 {
+				return fmt.Errorf("rule %q: Skip rules can't have Keywords", r.Name)
+			}
+			rule := rule{Rule: r}
+			for _, kw := range r.Keywords {
+				if rule.keywords == nil {
+					rule.keywords = map[string]lexgo.TokenType{}
+				}
+				for _, w := range kw.Words {
+					rule.keywords[w] = kw.Type
+				}
+			}
+			rule.anchored = regexp.MustCompile(`^(?:` + r.Pattern + `)`)
+			if !r.Lazy {
+				rule.anchored.Longest()
+			}
+			d.modes[mode] = append(d.modes[mode], rule)
+		}
+		return nil
+	}
+
+	if err := compile("", spec.Rules); err != nil {
+		return nil, err
+	}
+	for mode, rules := range spec.Modes {
+		if mode == "" {
+			return nil, fmt.Errorf("mode names can't be empty")
+		}
+		if err := compile(mode, rules); err != nil {
+			return nil, fmt.Errorf("mode %q: %w", mode, err)
+		}
+	}
+	return d, nil
+}
+
+// Spec returns the Spec the Def was compiled from
+func (d *Def) Spec() Spec {
+	return d.spec
+}
+
+// Func returns a LexerFunc which lexes using the Def's rules. At each position
+// the rules of the current mode are tried in order, and the first one to
+// match wins, or the one with the longest match if the Spec uses
+// MaximalMunch. A position which no rule matches results in an error, ending
+// the stream.
+//
+// Matching is done using Lexer.MatchFunc, and so has the same limitations as
+// Lexer.MatchRegexp.
+// The returned LexerFunc keeps track of the current mode, so a new one must
+// be used for every Lexer.
+func (d *Def) Func() lexgo.LexerFunc {
+	var stack []string
+	var ends []int
+	var fn lexgo.LexerFunc
+	fn = func(l *lexgo.Lexer) lexgo.LexerFunc {
+		r, err := l.PeekRune()
+		if err != nil {
+			return nil
+		}
+
+		var mode string
+		if len(stack) > 0 {
+			mode = stack[len(stack)-1]
+		}
+
+		rule, ok := d.match(l, mode, &ends)
+		if !ok {
+			l.ReadRune()
+			l.EmitErr(fmt.Errorf("unexpected character %q", r))
+			return nil
+		}
+
+		if rule.Skip {
+			l.Ignore()
+		} else if t, ok := rule.keywords[l.BufferString()]; ok {
+			l.Emit(t)
+		} else {
+			l.Emit(rule.Type)
+		}
+
+		if rule.Pop && len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+		if rule.Push != "" {
+			stack = append(stack, rule.Push)
+		}
+		return fn
+	}
+	return fn
+}
+
+// match reads and buffers the upcoming match of one of the given mode's rules,
+// returning that rule. ends is scratch space for the mode's dfa, if there is
+// one
+func (d *Def) match(l *lexgo.Lexer, mode string, ends *[]int) (rule, bool) {
+	rules := d.modes[mode]
+	if dfa := d.dfas[mode]; dfa != nil {
+		if len(*ends) < len(rules) {
+			*ends = make([]int, len(rules))
+		}
+		i := -1
+		l.MatchFunc(func(rr io.RuneReader) int {
+			var n int
+			i, n = dfa.match(rr, (*ends)[:len(rules)])
+			return n
+		})
+		if i < 0 {
+			return rule{}, false
+		}
+		return rules[i], true
+	}
+
+	if d.spec.MaximalMunch {
+		i := -1
+		l.MatchFunc(func(rr io.RuneReader) int {
+			var n int
+			i, n = munch(rules, rr)
+			return n
+		})
+		if i < 0 {
+			return rule{}, false
+		}
+		return rules[i], true
+	}
+
+	for _, rule := range rules {
+		if l.MatchFunc(rule.match) {
+			return rule, true
+		}
+	}
+	return rule{}, false
+}
+
+// match returns the length of the rule's anchored match on rr, for use with
+// Lexer.MatchFunc
+func (r rule) match(rr io.RuneReader) int {
+	if loc := r.anchored.FindReaderIndex(rr); loc != nil {
+		return loc[1]
+	}
+	return 0
+}
+
+// New returns a Lexer which reads from r using the Def's rules
+func (d *Def) New(r io.Reader, opts ...lexgo.Option) *lexgo.Lexer {
+	return lexgo.NewLexer(r, d.Func(), opts...)
+}
@@ -0,0 +1,175 @@
+package lexrule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TextMateOptions are used by TextMate to fill in the parts of a grammar which
+// a Spec doesn't describe
+type TextMateOptions struct {
+	// Name is the human readable name of the language, e.g. "Foo"
+	Name string
+
+	// ScopeName is the grammar's root scope, e.g. "source.foo"
+	ScopeName string
+
+	// FileTypes are the file extensions, without the leading dot, which the
+	// grammar applies to
+	FileTypes []string
+
+	// Scopes maps rule names to the TextMate scopes given to the text they
+	// match, e.g. "keyword.control" or "string.quoted.double". Themes only
+	// know how to color the standard scopes, so it's worth filling this in.
+	// Rules which aren't in Scopes are given their name, lower cased and
+	// suffixed with the last part of ScopeName, e.g. "ident.foo", except for
+	// Skip rules, which are given no scope.
+	Scopes map[string]string
+}
+
+type tmCapture struct {
+	Name string `json:"name"`
+}
+
+type tmPattern struct {
+	Name          string               `json:"name,omitempty"`
+	Match         string               `json:"match,omitempty"`
+	Begin         string               `json:"begin,omitempty"`
+	BeginCaptures map[string]tmCapture `json:"beginCaptures,omitempty"`
+	End           string               `json:"end,omitempty"`
+	EndCaptures   map[string]tmCapture `json:"endCaptures,omitempty"`
+	Include       string               `json:"include,omitempty"`
+	Patterns      []tmPattern          `json:"patterns,omitempty"`
+}
+
+type tmGrammar struct {
+	Name       string                            `json:"name,omitempty"`
+	ScopeName  string                            `json:"scopeName"`
+	FileTypes  []string                          `json:"fileTypes,omitempty"`
+	Patterns   []tmPattern                       `json:"patterns"`
+	Repository map[string]map[string][]tmPattern `json:"repository,omitempty"`
+}
+
+// TextMate returns a TextMate grammar, in the JSON format used by VS Code and
+// other editors, which highlights the same tokens as the Spec's lexer would.
+//
+// Each rule becomes a match pattern, preceded by one for each of its Keywords,
+// except for those which Push a mode. These become begin/end patterns instead,
+// ending at any of the pushed mode's Pop rules, with the mode's remaining rules
+// used in between. A mode which never Pops continues to the end of the
+// document.
+//
+// The grammar is only a baseline. TextMate tries patterns at the earliest
+// possible position, rather than at the current position in order, and
+// matches each line separately, so rules which rely on those things may
+// highlight differently than they lex. Patterns are also passed through mostly
+// as-is, and so should stick to syntax which Go's regexp package and Oniguruma
+// have in common.
+func TextMate(spec Spec, opts TextMateOptions) ([]byte, error) {
+	if opts.ScopeName == "" {
+		return nil, fmt.Errorf("ScopeName is required")
+	}
+	suffix := opts.ScopeName[strings.LastIndex(opts.ScopeName, ".")+1:]
+	scopeName := func(name string, skip bool) string {
+		if s, ok := opts.Scopes[name]; ok {
+			return s
+		} else if skip || name == "" {
+			return ""
+		}
+		return strings.ToLower(name) + "." + suffix
+	}
+	scope := func(r Rule) string { return scopeName(r.Name, r.Skip) }
+	pattern := func(p string) string {
+		return strings.Replace(p, "(?P<", "(?<", -1)
+	}
+
+	var patterns func(rules []Rule, popping bool) ([]tmPattern, error)
+	patterns = func(rules []Rule, popping bool) ([]tmPattern, error) {
+		pp := []tmPattern{}
+		for _, r := range rules {
+			if popping && r.Pop {
+				continue
+			}
+			for _, kw := range r.Keywords {
+				words := make([]string, len(kw.Words))
+				for i, w := range kw.Words {
+					words[i] = regexp.QuoteMeta(w)
+				}
+				pp = append(pp, tmPattern{
+					Name:  scopeName(kw.Name, false),
+					Match: `\b(?:` + strings.Join(words, "|") + `)\b`,
+				})
+			}
+			if r.Push == "" {
+				pp = append(pp, tmPattern{Name: scope(r), Match: pattern(r.Pattern)})
+				continue
+			}
+
+			mode, ok := spec.Modes[r.Push]
+			if !ok {
+				return nil, fmt.Errorf("rule %q: unknown mode %q", r.Name, r.Push)
+			}
+			p := tmPattern{
+				Begin:    pattern(r.Pattern),
+				End:      "(?!)",
+				Patterns: []tmPattern{{Include: "#" + r.Push}},
+			}
+			if s := scope(r); s != "" {
+				p.BeginCaptures = map[string]tmCapture{"0": {s}}
+			}
+
+			var ends []string
+			var endScope string
+			for _, r := range mode {
+				if !r.Pop {
+					continue
+				} else if s := scope(r); len(ends) == 0 || s == endScope {
+					endScope = s
+				} else {
+					endScope = ""
+				}
+				ends = append(ends, pattern(r.Pattern))
+			}
+			if len(ends) == 1 {
+				p.End = ends[0]
+			} else if len(ends) > 1 {
+				p.End = "(?:" + strings.Join(ends, ")|(?:") + ")"
+			}
+			if endScope != "" {
+				p.EndCaptures = map[string]tmCapture{"0": {endScope}}
+			}
+			pp = append(pp, p)
+		}
+		return pp, nil
+	}
+
+	g := tmGrammar{
+		Name:       opts.Name,
+		ScopeName:  opts.ScopeName,
+		FileTypes:  opts.FileTypes,
+		Repository: map[string]map[string][]tmPattern{},
+	}
+	var err error
+	if g.Patterns, err = patterns(spec.Rules, false); err != nil {
+		return nil, err
+	}
+	for name, rules := range spec.Modes {
+		pp, err := patterns(rules, true)
+		if err != nil {
+			return nil, fmt.Errorf("mode %q: %w", name, err)
+		}
+		g.Repository[name] = map[string][]tmPattern{"patterns": pp}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
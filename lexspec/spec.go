@@ -0,0 +1,178 @@
+// Package lexspec loads lexrule Specs from JSON or YAML files, so that
+// applications can ship their grammars as data. A spec file looks like:
+//
+//	maximal_munch: true
+//	rules:
+//	  - name: WS
+//	    pattern: '\s+'
+//	    skip: true
+//	  - name: IDENT
+//	    pattern: '[a-zA-Z_]\w*'
+//	    keywords:
+//	      KEYWORD: [if, else, while]
+//	  - name: QUOTE
+//	    pattern: '"'
+//	    push: string
+//	modes:
+//	  string:
+//	    - name: STRING
+//	      pattern: '[^"]+'
+//	    - name: QUOTE
+//	      pattern: '"'
+//	      pop: true
+//
+// The fields of each rule are those of lexrule.Rule, in lower case, and
+// keywords maps the names of Keywords to their words. The JSON form has the
+// same structure.
+//
+// Files don't give TokenTypes. Instead each distinct rule or keyword name is
+// given its own TokenType, starting at lexgo.UserDefined, in the order the
+// names first appear: the default mode's rules first, each followed by its
+// keywords in name order, and then the other modes in name order. Use the
+// Spec's Types method to find out which is which.
+package lexspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mediocregopher/lexgo"
+	"github.com/mediocregopher/lexgo/lexrule"
+	"gopkg.in/yaml.v3"
+)
+
+type rule struct {
+	Name     string              `json:"name" yaml:"name"`
+	Pattern  string              `json:"pattern" yaml:"pattern"`
+	Lazy     bool                `json:"lazy" yaml:"lazy"`
+	Skip     bool                `json:"skip" yaml:"skip"`
+	Push     string              `json:"push" yaml:"push"`
+	Pop      bool                `json:"pop" yaml:"pop"`
+	Priority int                 `json:"priority" yaml:"priority"`
+	Keywords map[string][]string `json:"keywords" yaml:"keywords"`
+}
+
+type file struct {
+	MaximalMunch bool              `json:"maximal_munch" yaml:"maximal_munch"`
+	Rules        []rule            `json:"rules" yaml:"rules"`
+	Modes        map[string][]rule `json:"modes" yaml:"modes"`
+}
+
+// ParseJSON parses a spec file in JSON form
+func ParseJSON(b []byte) (lexrule.Spec, error) {
+	var f file
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return lexrule.Spec{}, err
+	}
+	return f.spec(), nil
+}
+
+// ParseYAML parses a spec file in YAML form
+func ParseYAML(b []byte) (lexrule.Spec, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return lexrule.Spec{}, err
+	}
+	return f.spec(), nil
+}
+
+// Load reads the spec file at the given path, which is parsed as JSON if it
+// has a .json extension or YAML if it has a .yaml or .yml one
+func Load(path string) (lexrule.Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return lexrule.Spec{}, err
+	}
+
+	var spec lexrule.Spec
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		spec, err = ParseJSON(b)
+	case ".yaml", ".yml":
+		spec, err = ParseYAML(b)
+	default:
+		return lexrule.Spec{}, fmt.Errorf("unknown spec file extension %q", ext)
+	}
+	if err != nil {
+		return lexrule.Spec{}, fmt.Errorf("parsing %q: %w", path, err)
+	}
+	return spec, nil
+}
+
+// LoadDef is like Load, but also compiles the returned Spec
+func LoadDef(path string) (*lexrule.Def, error) {
+	spec, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return lexrule.Compile(spec)
+}
+
+func (f file) spec() lexrule.Spec {
+	types := map[string]lexgo.TokenType{}
+	typeOf := func(name string) lexgo.TokenType {
+		t, ok := types[name]
+		if !ok {
+			t = lexgo.UserDefined + lexgo.TokenType(len(types))
+			types[name] = t
+		}
+		return t
+	}
+
+	convert := func(rules []rule) []lexrule.Rule {
+		out := make([]lexrule.Rule, len(rules))
+		for i, r := range rules {
+			out[i] = lexrule.Rule{
+				Name:     r.Name,
+				Pattern:  r.Pattern,
+				Lazy:     r.Lazy,
+				Skip:     r.Skip,
+				Push:     r.Push,
+				Pop:      r.Pop,
+				Priority: r.Priority,
+			}
+			if !r.Skip {
+				out[i].Type = typeOf(r.Name)
+			}
+
+			names := make([]string, 0, len(r.Keywords))
+			for name := range r.Keywords {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				out[i].Keywords = append(out[i].Keywords, lexrule.Keyword{
+					Name:  name,
+					Type:  typeOf(name),
+					Words: r.Keywords[name],
+				})
+			}
+		}
+		return out
+	}
+
+	spec := lexrule.Spec{
+		Rules:        convert(f.Rules),
+		MaximalMunch: f.MaximalMunch,
+	}
+	modes := make([]string, 0, len(f.Modes))
+	for mode := range f.Modes {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		if spec.Modes == nil {
+			spec.Modes = map[string][]lexrule.Rule{}
+		}
+		spec.Modes[mode] = convert(f.Modes[mode])
+	}
+	return spec
+}
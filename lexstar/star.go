@@ -0,0 +1,112 @@
+// Package lexstar loads lexrule Specs from Starlark scripts, so that
+// applications can let users define their own tokenizers without recompiling
+// anything.
+//
+// Scripts declare rules by calling the predeclared rule function, once per
+// rule, in the order they should be tried:
+//
+//	rule("WS", r"\s+", skip=True)
+//	rule("IDENT", r"[a-zA-Z_]\w*")
+//	rule("QUOTE", r'"', push="string")
+//
+//	rule("STRING", r'[^"]+', mode="string")
+//	rule("QUOTE", r'"', mode="string", pop=True)
+//
+// rule takes a name and pattern, and optionally skip, push, pop, priority and
+// lazy as described by lexrule.Rule, and the mode the rule belongs to (the
+// default mode when not given). Calling maximal_munch() sets the Spec's
+// MaximalMunch field. Each distinct name is given its own TokenType, in the
+// order the names first appear, starting at lexgo.UserDefined; use the Spec's
+// Types method to find out which is which.
+//
+// Since scripts are full Starlark programs they can use variables, loops and
+// functions to build up their rules, e.g. to generate a rule per keyword. if
+// and for statements are allowed at the top level.
+package lexstar
+
+import (
+	"fmt"
+
+	"github.com/mediocregopher/lexgo"
+	"github.com/mediocregopher/lexgo/lexrule"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Load executes the Starlark script with the given filename and returns the
+// Spec it declares. If src is nil the script is read from the file, otherwise
+// src is the script's source and may be a string, []byte or io.Reader.
+func Load(filename string, src interface{}) (lexrule.Spec, error) {
+	spec := lexrule.Spec{Modes: map[string][]lexrule.Rule{}}
+	types := map[string]lexgo.TokenType{}
+
+	ruleFn := func(
+		thread *starlark.Thread, b *starlark.Builtin,
+		args starlark.Tuple, kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var r lexrule.Rule
+		var mode string
+		err := starlark.UnpackArgs(b.Name(), args, kwargs,
+			"name", &r.Name,
+			"pattern", &r.Pattern,
+			"skip?", &r.Skip,
+			"push?", &r.Push,
+			"pop?", &r.Pop,
+			"mode?", &mode,
+			"priority?", &r.Priority,
+			"lazy?", &r.Lazy,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !r.Skip {
+			t, ok := types[r.Name]
+			if !ok {
+				t = lexgo.UserDefined + lexgo.TokenType(len(types))
+				types[r.Name] = t
+			}
+			r.Type = t
+		}
+
+		if mode == "" {
+			spec.Rules = append(spec.Rules, r)
+		} else {
+			spec.Modes[mode] = append(spec.Modes[mode], r)
+		}
+		return starlark.None, nil
+	}
+
+	thread := &starlark.Thread{Name: filename}
+	munchFn := func(
+		thread *starlark.Thread, b *starlark.Builtin,
+		args starlark.Tuple, kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+			return nil, err
+		}
+		spec.MaximalMunch = true
+		return starlark.None, nil
+	}
+
+	predeclared := starlark.StringDict{
+		"rule":          starlark.NewBuiltin("rule", ruleFn),
+		"maximal_munch": starlark.NewBuiltin("maximal_munch", munchFn),
+	}
+	if _, err := starlark.ExecFileOptions(
+		&syntax.FileOptions{TopLevelControl: true, GlobalReassign: true},
+		thread, filename, src, predeclared,
+	); err != nil {
+		return lexrule.Spec{}, fmt.Errorf("loading %q: %w", filename, err)
+	}
+	return spec, nil
+}
+
+// LoadDef is like Load, but also compiles the returned Spec
+func LoadDef(filename string, src interface{}) (*lexrule.Def, error) {
+	spec, err := Load(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return lexrule.Compile(spec)
+}
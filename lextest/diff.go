@@ -0,0 +1,122 @@
+package lextest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// DiffContext is the number of unchanged Tokens shown either side of each
+// change by FormatDiff
+var DiffContext = 3
+
+// AssertTokens fails the test if got doesn't match want, showing where the two
+// streams differ using FormatDiff
+func AssertTokens(t testing.TB, want, got []lexgo.Token, names TypeNames) {
+	t.Helper()
+	if d := FormatDiff(want, got, names); d != "" {
+		t.Errorf("token stream mismatch\n%s", d)
+	}
+}
+
+// FormatDiff aligns the two Token streams using lexgo.DiffTokens and renders
+// the differences between them, each with DiffContext unchanged Tokens either
+// side, one Token per line as in a dump. Lines are prefixed with "-" if they
+// are only in want, "+" if they are only in got, and are numbered by their
+// index in the stream they came from:
+//
+//	first difference at token 3
+//	    1 1:1 Ident "a"
+//	    2 1:2 Space " "
+//	-   3 1:3 Ident "b"
+//	+   3 1:3 Number "1"
+//	    4 1:4 Newline "\n"
+//
+// It returns the empty string if the streams are the same.
+func FormatDiff(want, got []lexgo.Token, names TypeNames) string {
+	edits := lexgo.DiffTokens(want, got, lexgo.DiffOptions{})
+	return formatEdits(edits, func(i int) string {
+		return DumpToken(&want[i], names)
+	}, func(i int) string {
+		return DumpToken(&got[i], names)
+	})
+}
+
+// diffDumps is like FormatDiff, but for two already rendered dumps
+func diffDumps(want, got string) string {
+	wantLines := dumpLines(want)
+	gotLines := dumpLines(got)
+	edits := lexgo.DiffTokens(wantLines, gotLines, lexgo.DiffOptions{})
+	return formatEdits(edits, func(i int) string {
+		return wantLines[i].Val
+	}, func(i int) string {
+		return gotLines[i].Val
+	})
+}
+
+// dumpLines splits a dump into its lines, as Tokens so that they can be
+// aligned using lexgo.DiffTokens
+func dumpLines(dump string) []lexgo.Token {
+	lines := strings.SplitAfter(dump, "\n")
+	toks := make([]lexgo.Token, 0, len(lines))
+	for _, line := range lines {
+		if line != "" {
+			toks = append(toks, lexgo.Token{Val: strings.TrimSuffix(line, "\n")})
+		}
+	}
+	return toks
+}
+
+// formatEdits does the work for FormatDiff, given functions to render the i'th
+// Token of each stream
+func formatEdits(edits []lexgo.DiffEdit, wantLine, gotLine func(int) string) string {
+	// near[i] is set if edits[i] is within DiffContext of a change
+	near := make([]bool, len(edits))
+	first := -1
+	for i, e := range edits {
+		if e.Op == lexgo.DiffEqual {
+			continue
+		} else if first < 0 {
+			first = i
+		}
+		for j := i - DiffContext; j <= i+DiffContext; j++ {
+			if j >= 0 && j < len(edits) {
+				near[j] = true
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	e := edits[first]
+	if e.A >= 0 {
+		fmt.Fprintf(&b, "first difference at token %d\n", e.A+1)
+	} else {
+		fmt.Fprintf(&b, "first difference at token %d\n", e.B+1)
+	}
+
+	for i, e := range edits {
+		if !near[i] {
+			if i > 0 && near[i-1] {
+				b.WriteString("    ...\n")
+			}
+			continue
+		}
+		switch e.Op {
+		case lexgo.DiffEqual:
+			fmt.Fprintf(&b, "  %3d %s\n", e.A+1, wantLine(e.A))
+		case lexgo.DiffDelete:
+			fmt.Fprintf(&b, "- %3d %s\n", e.A+1, wantLine(e.A))
+		case lexgo.DiffInsert:
+			fmt.Fprintf(&b, "+ %3d %s\n", e.B+1, gotLine(e.B))
+		case lexgo.DiffChange:
+			fmt.Fprintf(&b, "- %3d %s\n", e.A+1, wantLine(e.A))
+			fmt.Fprintf(&b, "+ %3d %s\n", e.B+1, gotLine(e.B))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package lextest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// Differential lexes every input in corpus using Tokenizers from both a and b,
+// and fails the test for each input which the two lex differently, including
+// any Err Tokens. This is useful when replacing one implementation of a lexer
+// with another, e.g. a hand-written lexer with a generated one, where the
+// existing implementation is the best definition of what's correct.
+//
+// Each failure shows the first Token at which the two streams diverge. The
+// input is then shrunk, by repeatedly removing parts of it for as long as the
+// streams still diverge, and the diff (see FormatDiff) of the two streams for
+// the shrunk input is shown as well.
+func Differential(t testing.TB, a, b NewFunc, corpus []string, names TypeNames) {
+	t.Helper()
+	for i, input := range corpus {
+		first, ok := diverge(a, b, input)
+		if !ok {
+			continue
+		}
+
+		shrunk := shrink(input, func(input string) bool {
+			_, ok := diverge(a, b, input)
+			return ok
+		})
+		t.Errorf(
+			"input %d lexes differently, first at token %d:\n%s\nshrunk to %q:\n%s",
+			i, first.index+1, first.lines(names), shrunk,
+			FormatDiff(lexAll(a, shrunk), lexAll(b, shrunk), names),
+		)
+	}
+}
+
+// divergence describes the first Token at which two streams differ. Either
+// Token is nil if its stream ended before that point
+type divergence struct {
+	index int
+	a, b  *lexgo.Token
+}
+
+func (d divergence) lines(names TypeNames) string {
+	line := func(tok *lexgo.Token) string {
+		if tok == nil {
+			return "<end of stream>"
+		}
+		return DumpToken(tok, names)
+	}
+	return fmt.Sprintf("  a: %s\n  b: %s", line(d.a), line(d.b))
+}
+
+// diverge lexes the input using both a and b, and returns where the resulting
+// streams first differ, if they do
+func diverge(a, b NewFunc, input string) (divergence, bool) {
+	aToks, bToks := lexAll(a, input), lexAll(b, input)
+	for _, e := range lexgo.DiffTokens(aToks, bToks, lexgo.DiffOptions{}) {
+		if e.Op == lexgo.DiffEqual {
+			continue
+		}
+
+		var d divergence
+		if e.A >= 0 {
+			d.index, d.a = e.A, &aToks[e.A]
+		}
+		if e.B >= 0 {
+			d.index, d.b = e.B, &bToks[e.B]
+		}
+		// DiffTokens pairs up Tokens as best it can, but the first difference
+		// is at the same index in both streams
+		if d.a == nil && d.index < len(aToks) {
+			d.a = &aToks[d.index]
+		} else if d.b == nil && d.index < len(bToks) {
+			d.b = &bToks[d.index]
+		}
+		return d, true
+	}
+	return divergence{}, false
+}
+
+// shrink returns the smallest input it can find, formed by removing runs of
+// characters from the given one, for which fails still returns true. fails
+// must return true for the given input.
+func shrink(input string, fails func(string) bool) string {
+	// chars holds the input split into characters, so that removing parts of
+	// it never splits a character in two. Invalid utf8 bytes are each their
+	// own character
+	var chars []string
+	for s := input; s != ""; {
+		_, size := utf8.DecodeRuneInString(s)
+		chars, s = append(chars, s[:size]), s[size:]
+	}
+
+	// Try removing each of n chunks of the input in turn, keeping any removal
+	// for which the input still fails. When nothing can be removed the chunks
+	// are made smaller, until they are single characters
+	for n := 2; len(chars) > 0; {
+		size := (len(chars) + n - 1) / n
+		removed := false
+		for start := 0; start < len(chars); start += size {
+			end := start + size
+			if end > len(chars) {
+				end = len(chars)
+			}
+			candidate := append(append([]string{}, chars[:start]...), chars[end:]...)
+			if fails(strings.Join(candidate, "")) {
+				chars, removed = candidate, true
+				break
+			}
+		}
+
+		if removed {
+			if n > 2 {
+				n--
+			}
+		} else if size == 1 {
+			break
+		} else {
+			n *= 2
+		}
+	}
+	return strings.Join(chars, "")
+}
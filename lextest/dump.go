@@ -0,0 +1,77 @@
+package lextest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// TypeNames returns a human readable name for a TokenType, for use in dumps.
+// A nil TypeNames causes TokenTypes to be rendered as their numeric value
+type TypeNames func(lexgo.TokenType) string
+
+func (names TypeNames) name(tt lexgo.TokenType) string {
+	switch {
+	case tt == lexgo.Err:
+		return "error"
+	case tt == lexgo.Warning:
+		return "warning"
+	case names != nil:
+		return names(tt)
+	default:
+		return fmt.Sprint(int(tt))
+	}
+}
+
+// DumpToken renders a single Token as one line of a dump, in the form:
+//
+//	row:col type "value"
+//
+// For Err and Warning Tokens the quoted value is the error's message
+func DumpToken(tok *lexgo.Token, names TypeNames) string {
+	val := tok.Val
+	if tok.Err != nil {
+		val = tok.Err.Error()
+	} else if tok.Warn != nil {
+		val = tok.Warn.Error()
+	}
+	return fmt.Sprintf("%d:%d %s %q", tok.Row, tok.Col, names.name(tok.TokenType), val)
+}
+
+// Dump renders the given Tokens as a dump, one Token per line (see DumpToken).
+// The format is stable, so dumps can be stored and compared against, and
+// changes to them produce readable diffs.
+func Dump(toks []lexgo.Token, names TypeNames) string {
+	var b strings.Builder
+	for i := range toks {
+		b.WriteString(DumpToken(&toks[i], names))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// DumpInput lexes the given input using a Tokenizer from newFn and returns the
+// dump of all Tokens produced. The final io.EOF Token is not included, but any
+// other Err Token, recoverable or not, is.
+func DumpInput(newFn NewFunc, input string, names TypeNames) string {
+	return Dump(lexAll(newFn, input), names)
+}
+
+// lexAll returns all Tokens lexed from input, as described by DumpInput
+func lexAll(newFn NewFunc, input string) []lexgo.Token {
+	tz := newFn(strings.NewReader(input))
+	var toks []lexgo.Token
+	for {
+		tok := tz.Next()
+		if tok.Err != io.EOF {
+			toks = append(toks, tok.Copy())
+		}
+		done := tok.EndsStream()
+		tok.Release()
+		if done {
+			return toks
+		}
+	}
+}
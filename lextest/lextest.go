@@ -0,0 +1,93 @@
+// Package lextest contains helpers for testing lexers built using lexgo.
+package lextest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// NewFunc describes a function which returns a Tokenizer for the given input.
+// Most lexgo-based lexers will have a constructor which fits, or can be easily
+// wrapped to fit, this signature
+type NewFunc func(io.Reader) lexgo.Tokenizer
+
+// MustTokens lexes the given input using a Tokenizer from newFn and returns all
+// Tokens produced, not including the final io.EOF. If any other Err Token is
+// produced the test is failed immediately, with the error shown in the context
+// of the input (see FormatError). Recoverable Err Tokens fail the test too, but
+// lexing continues past them so that all such errors are shown.
+func MustTokens(t testing.TB, newFn NewFunc, input string) []lexgo.Token {
+	t.Helper()
+	tz := newFn(strings.NewReader(input))
+	var toks []lexgo.Token
+	for {
+		tok := tz.Next()
+		if tok.Err == io.EOF {
+			tok.Release()
+			return toks
+		} else if tok.Recoverable {
+			t.Errorf("error lexing input:\n%s", FormatError(input, tok))
+			tok.Release()
+			continue
+		} else if tok.Err != nil {
+			t.Fatalf("error lexing input:\n%s", FormatError(input, tok))
+		}
+		toks = append(toks, tok.Copy())
+		tok.Release()
+	}
+}
+
+// FormatError renders the given Err Token against the input it came from,
+// quoting the line the error occurred on with a marker under the column:
+//
+//	2:5: unexpected character '$'
+//	    foo $ bar
+//	        ^
+//
+// If the Token's position doesn't fall within the input only the error is
+// rendered
+func FormatError(input string, tok *lexgo.Token) string {
+	msg := fmt.Sprintf("%d:%d: %s", tok.Row, tok.Col, tok.Err)
+	line, ok := Line(input, tok.Row)
+	if !ok {
+		return msg
+	}
+	return msg + "\n" + Caret(line, tok.Col)
+}
+
+// Line returns the text of the given (1-based) row of the input, not including
+// the newline, or false if the input doesn't have that many rows
+func Line(input string, row int) (string, bool) {
+	if row < 1 {
+		return "", false
+	}
+	lines := strings.Split(input, "\n")
+	if row > len(lines) {
+		return "", false
+	}
+	return lines[row-1], true
+}
+
+// Caret returns the given line, indented, with a second line beneath it having
+// a ^ under the given (1-based, in runes) column. Tabs in the line are
+// reproduced in the marker line, so the ^ lines up regardless of tab width.
+// Invalid utf8 bytes in the line are displayed as U+FFFD.
+func Caret(line string, col int) string {
+	var display, marker strings.Builder
+	var i int
+	for _, r := range line {
+		display.WriteRune(r)
+		if i++; i >= col {
+			continue
+		} else if r == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	return "    " + display.String() + "\n    " + marker.String() + "^"
+}
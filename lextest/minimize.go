@@ -0,0 +1,271 @@
+package lextest
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// FailureKind describes how lexing an input failed, see Failure
+type FailureKind int
+
+// The FailureKinds
+const (
+	// The Tokenizer panicked
+	FailPanic FailureKind = iota + 1
+
+	// The Tokenizer didn't end its stream within MinimizeOptions.Timeout, or
+	// produced more than MinimizeOptions.MaxTokens Tokens
+	FailHang
+
+	// MinimizeOptions.Check returned an error
+	FailCheck
+)
+
+func (k FailureKind) String() string {
+	switch k {
+	case FailPanic:
+		return "panic"
+	case FailHang:
+		return "hang"
+	case FailCheck:
+		return "check failed"
+	default:
+		return fmt.Sprintf("FailureKind(%d)", int(k))
+	}
+}
+
+// Failure describes an input which a lexer fails on, see Try
+type Failure struct {
+	Kind FailureKind
+
+	// Message is the value panicked with, a description of the hang, or the
+	// error returned by MinimizeOptions.Check, depending on Kind
+	Message string
+
+	// Where is the file:line the panic was raised at, not counting the
+	// runtime's own frames, and Stack the stack trace of the panic. Both are
+	// only set for FailPanic.
+	Where string
+	Stack string
+
+	// Tokens are those which had been produced when the failure occurred
+	Tokens []lexgo.Token
+}
+
+// same returns whether the Failures are of the same bug, as far as can be
+// told. The Message isn't compared, as for panics it often includes details
+// of the particular input, e.g. an index
+func (f *Failure) same(g *Failure) bool {
+	return f.Kind == g.Kind && f.Where == g.Where
+}
+
+// Format renders the Failure against the input it occurred on, quoting the
+// input and showing where the last Token before the failure was lexed:
+//
+//	panic: runtime error: index out of range [2] with length 2
+//	at /src/lexer/lexer.go:87
+//	input: "a\"b"
+//	last token: 1:1 Ident "a"
+//	    a"b
+//	    ^
+//
+// The stack trace of a panic follows. If no Tokens were produced before the
+// failure that's stated instead.
+func (f *Failure) Format(input string, names TypeNames) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", f.Kind, f.Message)
+	if f.Where != "" {
+		fmt.Fprintf(&b, "at %s\n", f.Where)
+	}
+	fmt.Fprintf(&b, "input: %q\n", input)
+
+	if len(f.Tokens) == 0 {
+		b.WriteString("no tokens lexed\n")
+	} else {
+		last := &f.Tokens[len(f.Tokens)-1]
+		fmt.Fprintf(&b, "last token: %s\n", DumpToken(last, names))
+		if line, ok := Line(input, last.Row); ok {
+			b.WriteString(Caret(line, last.Col))
+			b.WriteByte('\n')
+		}
+	}
+
+	if f.Stack != "" {
+		b.WriteString("\n")
+		b.WriteString(f.Stack)
+	}
+	return b.String()
+}
+
+// MinimizeOptions are used to configure Try and Minimize. The zero value
+// looks for panics and hangs only.
+type MinimizeOptions struct {
+	// Timeout is how long a Tokenizer may take to end its stream before it's
+	// considered to hang. Defaults to one second.
+	Timeout time.Duration
+
+	// MaxTokens is the most Tokens a Tokenizer may produce before it's
+	// considered to hang. Defaults to 1 << 20.
+	MaxTokens int
+
+	// If set Check is called with each input and the Tokens lexed from it,
+	// not including the final io.EOF, and a returned error is a FailCheck
+	// Failure. It's used to look for inputs breaking some invariant of the
+	// lexer.
+	Check func(input string, toks []lexgo.Token) error
+}
+
+func (o MinimizeOptions) withDefaults() MinimizeOptions {
+	if o.Timeout == 0 {
+		o.Timeout = time.Second
+	}
+	if o.MaxTokens == 0 {
+		o.MaxTokens = 1 << 20
+	}
+	return o
+}
+
+// Try lexes the input using a Tokenizer from newFn and returns how it failed,
+// or nil if it didn't.
+//
+// The input is lexed in a separate goroutine, so that hangs can be detected.
+// Go has no way of stopping a goroutine though, so a Tokenizer which hangs
+// without producing Tokens is left running in the background. This is fine
+// for tests and one-off tools, which will exit soon enough.
+func Try(newFn NewFunc, input string, opts MinimizeOptions) *Failure {
+	opts = opts.withDefaults()
+
+	var (
+		mu   sync.Mutex
+		toks []lexgo.Token
+	)
+	tokens := func() []lexgo.Token {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]lexgo.Token(nil), toks...)
+	}
+
+	done := make(chan *Failure, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- &Failure{
+					Kind:    FailPanic,
+					Message: fmt.Sprint(r),
+					Where:   panicWhere(),
+					Stack:   string(debug.Stack()),
+				}
+			}
+		}()
+
+		tz := newFn(strings.NewReader(input))
+		for n := 0; ; n++ {
+			if n > opts.MaxTokens {
+				done <- &Failure{
+					Kind:    FailHang,
+					Message: fmt.Sprintf("more than %d tokens lexed", opts.MaxTokens),
+				}
+				return
+			}
+
+			tok := tz.Next()
+			if tok.Err != io.EOF {
+				mu.Lock()
+				toks = append(toks, tok.Copy())
+				mu.Unlock()
+			}
+			end := tok.EndsStream()
+			tok.Release()
+			if end {
+				break
+			}
+		}
+		done <- nil
+	}()
+
+	var f *Failure
+	select {
+	case f = <-done:
+	case <-time.After(opts.Timeout):
+		f = &Failure{
+			Kind:    FailHang,
+			Message: fmt.Sprintf("stream didn't end within %s", opts.Timeout),
+		}
+	}
+
+	if f == nil && opts.Check != nil {
+		if err := opts.Check(input, tokens()); err != nil {
+			f = &Failure{Kind: FailCheck, Message: err.Error()}
+		}
+	}
+	if f != nil {
+		f.Tokens = tokens()
+	}
+	return f
+}
+
+// panicWhere returns the file:line of the frame which raised the panic being
+// recovered, skipping the runtime and this package
+func panicWhere() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") &&
+			!strings.HasPrefix(frame.Function, "github.com/mediocregopher/lexgo/lextest.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		} else if !more {
+			return ""
+		}
+	}
+}
+
+// Minimize reduces an input on which lexing fails, as described by Try, to
+// the smallest input it can find which still fails in the same way: panicking
+// at the same place, hanging, or failing the Check. This is useful when a
+// fuzzer has found a large input which crashes a lexer. It returns the
+// minimized input and its Failure, which can be rendered using Failure.Format.
+// If the input doesn't fail it's returned as-is along with a nil Failure.
+//
+// Inputs are minimized by repeatedly removing parts of them, so each removal
+// which still fails by hanging costs a Timeout. Removals which only make the
+// input stop failing are cheap.
+func Minimize(newFn NewFunc, input string, opts MinimizeOptions) (string, *Failure) {
+	orig := Try(newFn, input, opts)
+	if orig == nil {
+		return input, nil
+	}
+
+	f := orig
+	shrunk := shrink(input, func(input string) bool {
+		g := Try(newFn, input, opts)
+		if g == nil || !g.same(orig) {
+			return false
+		}
+		// shrink's result is always the last input found to fail
+		f = g
+		return true
+	})
+	return shrunk, f
+}
+
+// NoFailure fails the test if lexing the input using a Tokenizer from newFn
+// fails, as described by Try. The input is first minimized (see Minimize), and
+// the Failure on the minimized input is shown. It's intended for use in fuzz
+// targets, where the inputs found can be large.
+func NoFailure(t testing.TB, newFn NewFunc, input string, opts MinimizeOptions, names TypeNames) {
+	t.Helper()
+	shrunk, f := Minimize(newFn, input, opts)
+	if f == nil {
+		return
+	}
+	t.Fatalf("lexing input of length %d failed, minimized to:\n%s", len(input), f.Format(shrunk, names))
+}
@@ -0,0 +1,354 @@
+package lextest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+// Gen generates the text of a single Token, for use in property-based tests
+// (see Vocab)
+type Gen func(r *rand.Rand) string
+
+// Literals returns a Gen which picks one of the given strings
+func Literals(ss ...string) Gen {
+	return func(r *rand.Rand) string {
+		return ss[r.Intn(len(ss))]
+	}
+}
+
+// maxRepeat is the most times Regexp repeats an unbounded sub-expression
+const maxRepeat = 4
+
+// Regexp returns a Gen which generates random strings matched by the given
+// regular expression (in the syntax of the regexp package). Unbounded
+// repetitions are repeated at most a few times, and character classes favour
+// printable ASCII characters where they include any. It panics if the pattern
+// doesn't parse.
+func Regexp(pattern string) Gen {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		panic(fmt.Sprintf("lextest: Regexp(%q): %s", pattern, err))
+	}
+	re = re.Simplify()
+	return func(r *rand.Rand) string {
+		var b strings.Builder
+		genRegexp(&b, r, re)
+		return b.String()
+	}
+}
+
+func genRegexp(b *strings.Builder, r *rand.Rand, re *syntax.Regexp) {
+	repeat := func(min, max int) {
+		n := min
+		if max > min {
+			n += r.Intn(max - min + 1)
+		}
+		for i := 0; i < n; i++ {
+			genRegexp(b, r, re.Sub[0])
+		}
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, c := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && r.Intn(2) == 0 {
+				c = unicode.SimpleFold(c)
+			}
+			b.WriteRune(c)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(genClass(r, re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		b.WriteRune(rune(' ' + r.Intn('~'-' '+1)))
+	case syntax.OpCapture:
+		genRegexp(b, r, re.Sub[0])
+	case syntax.OpStar:
+		repeat(0, maxRepeat)
+	case syntax.OpPlus:
+		repeat(1, maxRepeat)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		max := re.Max
+		if max < 0 {
+			max = re.Min + maxRepeat
+		}
+		repeat(re.Min, max)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			genRegexp(b, r, sub)
+		}
+	case syntax.OpAlternate:
+		genRegexp(b, r, re.Sub[r.Intn(len(re.Sub))])
+	}
+}
+
+// genClass picks a rune from the given character class, which is a list of
+// inclusive ranges
+func genClass(r *rand.Rand, ranges []rune) rune {
+	// Most of the time pick from the printable ASCII part of the class, if it
+	// has one, so that the generated text is readable
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) > 0 && r.Intn(4) > 0 {
+		ranges = ascii
+	}
+
+	for {
+		i := 2 * r.Intn(len(ranges)/2)
+		lo, hi := ranges[i], ranges[i+1]
+		if c := lo + rune(r.Int63n(int64(hi-lo)+1)); utf8.ValidRune(c) {
+			return c
+		}
+	}
+}
+
+// Vocab describes the Tokens a lexer produces, so that random sequences of
+// them can be generated for property-based tests, see RoundTrip and Concat.
+//
+// The Gens should only generate text which the lexer will lex as a single
+// Token of the given TokenType, and nothing else. Care needs to be taken with
+// Tokens which overlap, e.g. an identifier Gen shouldn't generate keywords.
+type Vocab struct {
+	// Types holds the Gen for each TokenType which may be generated
+	Types map[lexgo.TokenType]Gen
+
+	// Sep generates the text placed after each Token in a Seq, e.g.
+	// whitespace, so that Tokens don't run into each other. If nil Tokens are
+	// placed directly next to each other, which only works for lexers in
+	// which no Token can be a prefix of another.
+	Sep Gen
+
+	// SepType is the TokenType the lexer emits for the text generated by Sep.
+	// If zero the lexer is expected to skip it without emitting a Token.
+	SepType lexgo.TokenType
+
+	// MaxLen is the most Tokens, not counting separators, in a generated Seq.
+	// Zero means 20.
+	MaxLen int
+}
+
+// Sample is a single element of a Seq
+type Sample struct {
+	lexgo.TokenType
+	Text string
+
+	// Sep is set if the Sample was generated by Vocab.Sep
+	Sep bool
+}
+
+// Seq is a sequence of Tokens generated by a Vocab
+type Seq []Sample
+
+// Input returns the text of the Seq, i.e. that of each of its Samples
+// concatenated
+func (s Seq) Input() string {
+	var b strings.Builder
+	for _, smp := range s {
+		b.WriteString(smp.Text)
+	}
+	return b.String()
+}
+
+// Generate returns a random Seq of up to n Tokens, followed by separators if
+// v.Sep is set
+func (v Vocab) Generate(r *rand.Rand, n int) Seq {
+	types := make([]lexgo.TokenType, 0, len(v.Types))
+	for t := range v.Types {
+		types = append(types, t)
+	}
+	// sorted, so that the same random source always gives the same Seq
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	var s Seq
+	for n = r.Intn(n + 1); n > 0 && len(types) > 0; n-- {
+		t := types[r.Intn(len(types))]
+		s = append(s, Sample{TokenType: t, Text: v.Types[t](r)})
+		if v.Sep != nil {
+			s = append(s, Sample{TokenType: v.SepType, Text: v.Sep(r), Sep: true})
+		}
+	}
+	return s
+}
+
+// Config returns a copy of cfg (which may be nil) whose Values generates a Seq
+// using v for every argument of a property, so that properties of the form
+// func(a, b lextest.Seq) bool can be checked with quick.Check.
+func (v Vocab) Config(cfg *quick.Config) *quick.Config {
+	c := quick.Config{}
+	if cfg != nil {
+		c = *cfg
+	}
+	n := v.MaxLen
+	if n == 0 {
+		n = 20
+	}
+	c.Values = func(args []reflect.Value, r *rand.Rand) {
+		for i := range args {
+			args[i] = reflect.ValueOf(v.Generate(r, n))
+		}
+	}
+	return &c
+}
+
+// tokens returns the Tokens the lexer is expected to produce for the Seq
+func (v Vocab) tokens(s Seq) []lexgo.Token {
+	var toks []lexgo.Token
+	var off int
+	for _, smp := range s {
+		if !smp.Sep || v.SepType != lexgo.Err {
+			toks = append(toks, lexgo.Token{
+				TokenType: smp.TokenType,
+				Val:       smp.Text,
+				Raw:       smp.Text,
+				Offset:    off,
+			})
+		}
+		off += len(smp.Text)
+	}
+	return toks
+}
+
+// RoundTrip uses quick.Check to check that the input of any Seq generated by v
+// lexes, using a Tokenizer from newFn, to exactly the Seq's Tokens and their
+// text, with no Err Tokens. On failure the Seq is shrunk, by removing Tokens
+// from it for as long as it still fails, and the diff of the expected and
+// actual Tokens is shown.
+func RoundTrip(t testing.TB, newFn NewFunc, v Vocab, cfg *quick.Config, names TypeNames) {
+	t.Helper()
+	fails := func(s Seq) bool {
+		return !sameTokens(v.tokens(s), lexAll(newFn, s.Input()))
+	}
+	err := quick.Check(func(s Seq) bool { return !fails(s) }, v.Config(cfg))
+	if checkErr, ok := err.(*quick.CheckError); ok {
+		s := shrinkSeq(checkErr.In[0].(Seq), fails)
+		t.Errorf(
+			"lexing generated input failed after %d tests, shrunk to %q:\n%s",
+			checkErr.Count, s.Input(),
+			formatSameDiff(v.tokens(s), lexAll(newFn, s.Input()), names),
+		)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Concat uses quick.Check to check that, for any two Seqs a and b generated by
+// v, lexing the concatenation of their inputs using a Tokenizer from newFn
+// produces the concatenation of the Tokens lexed from each input on its own.
+// Unlike RoundTrip this doesn't depend on v describing the lexer exactly, only
+// on the lexer not carrying state from one Token to the next. On failure the
+// Seqs are shrunk, as by RoundTrip.
+func Concat(t testing.TB, newFn NewFunc, v Vocab, cfg *quick.Config, names TypeNames) {
+	t.Helper()
+	lexBoth := func(a, b Seq) (want, got []lexgo.Token) {
+		want = append(lexAll(newFn, a.Input()), lexAll(newFn, b.Input())...)
+		return want, lexAll(newFn, a.Input()+b.Input())
+	}
+	fails := func(a, b Seq) bool {
+		return !sameTokens(lexBoth(a, b))
+	}
+	err := quick.Check(func(a, b Seq) bool { return !fails(a, b) }, v.Config(cfg))
+	if checkErr, ok := err.(*quick.CheckError); ok {
+		a, b := checkErr.In[0].(Seq), checkErr.In[1].(Seq)
+		a = shrinkSeq(a, func(a Seq) bool { return fails(a, b) })
+		b = shrinkSeq(b, func(b Seq) bool { return fails(a, b) })
+		want, got := lexBoth(a, b)
+		t.Errorf(
+			"lexing concatenated inputs failed after %d tests, shrunk to %q + %q:\n%s",
+			checkErr.Count, a.Input(), b.Input(), formatSameDiff(want, got, names),
+		)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sameDiffOptions compare Tokens by their TokenType and Raw text only, as the
+// positions of Tokens lexed from different inputs can't be compared
+var sameDiffOptions = lexgo.DiffOptions{IgnorePositions: true}
+
+// rawTokens returns copies of the Tokens with Val set to Raw, or to the error
+// for Err and Warning Tokens
+func rawTokens(toks []lexgo.Token) []lexgo.Token {
+	raw := make([]lexgo.Token, len(toks))
+	for i, tok := range toks {
+		raw[i] = tok
+		switch {
+		case tok.Err != nil:
+			raw[i].Val = tok.Err.Error()
+		case tok.Warn != nil:
+			raw[i].Val = tok.Warn.Error()
+		default:
+			raw[i].Val = tok.Raw
+		}
+	}
+	return raw
+}
+
+func sameTokens(want, got []lexgo.Token) bool {
+	for _, e := range lexgo.DiffTokens(rawTokens(want), rawTokens(got), sameDiffOptions) {
+		if e.Op != lexgo.DiffEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// formatSameDiff is like FormatDiff, but compares as sameTokens does and
+// leaves positions out of the rendered Tokens
+func formatSameDiff(want, got []lexgo.Token, names TypeNames) string {
+	want, got = rawTokens(want), rawTokens(got)
+	line := func(tok *lexgo.Token) string {
+		return fmt.Sprintf("%s %q", names.name(tok.TokenType), tok.Val)
+	}
+	edits := lexgo.DiffTokens(want, got, sameDiffOptions)
+	return formatEdits(edits, func(i int) string {
+		return line(&want[i])
+	}, func(i int) string {
+		return line(&got[i])
+	})
+}
+
+// shrinkSeq returns the shortest Seq it can find, formed by removing Tokens
+// (along with the separator following each) from the given one, for which
+// fails still returns true. fails must return true for the given Seq.
+func shrinkSeq(s Seq, fails func(Seq) bool) Seq {
+	for removed := true; removed; {
+		removed = false
+		for i := 0; i < len(s); i++ {
+			if s[i].Sep {
+				continue
+			}
+			end := i + 1
+			if end < len(s) && s[end].Sep {
+				end++
+			}
+			candidate := append(append(Seq{}, s[:i]...), s[end:]...)
+			if fails(candidate) {
+				s, removed = candidate, true
+				i--
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package lextest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The flag is namespaced so that it doesn't collide with the -update flag which
+// many test suites (including golden file helpers) define for themselves
+var updateFlag = flag.Bool("lextest.update", false, "rewrite lextest snapshots and txtar outputs with the current results")
+
+// UpdateEnv is the environment variable which, when set to a non-empty value,
+// has the same effect as the -lextest.update flag. It's useful when running
+// the tests of multiple packages at once, since go test fails packages which
+// don't define a flag it is given.
+const UpdateEnv = "LEXTEST_UPDATE"
+
+func update() bool {
+	return *updateFlag || os.Getenv(UpdateEnv) != ""
+}
+
+// SnapshotDir is the directory, relative to the package being tested, in which
+// Snapshot stores its files
+var SnapshotDir = filepath.Join("testdata", "snapshots")
+
+// snapshotPath returns the path of the snapshot file for the given test
+func snapshotPath(t testing.TB) string {
+	name := strings.NewReplacer(" ", "_", ":", "_").Replace(t.Name())
+	return filepath.Join(SnapshotDir, filepath.FromSlash(name)+".tokens")
+}
+
+// Snapshot lexes the given input using a Tokenizer from newFn and compares its
+// dump (see DumpInput) against the snapshot stored for the test, failing the
+// test if they differ. Snapshots are stored in SnapshotDir, in a file named
+// after the test.
+//
+// When the test binary is run with the -lextest.update flag, or with UpdateEnv
+// set, the snapshot is instead (re)written with the current dump, e.g. after
+// lexer behavior was intentionally changed:
+//
+//	LEXTEST_UPDATE=1 go test ./...
+//
+// The resulting changes to the snapshot files can then be reviewed like any
+// other diff.
+func Snapshot(t testing.TB, newFn NewFunc, input string, names TypeNames) {
+	t.Helper()
+	path := snapshotPath(t)
+	got := DumpInput(newFn, input, names)
+
+	if update() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("snapshot %s does not exist, run with -lextest.update to create it", path)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("token dump does not match snapshot %s (run with -lextest.update if this is intended)\n%s", path, diffDumps(string(want), got))
+	}
+}
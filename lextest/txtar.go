@@ -0,0 +1,86 @@
+package lextest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+// Suffixes of the files within a txtar archive used by RunTxtar
+const (
+	InputSuffix  = ".in"
+	OutputSuffix = ".out"
+)
+
+// RunTxtar runs each case in the txtar archive at the given path as a subtest.
+// A case consists of a pair of files in the archive, NAME.in and NAME.out. The
+// .in file is lexed using a Tokenizer from newFn, and its dump (see DumpInput)
+// must match the contents of the .out file:
+//
+//	-- ident.in --
+//	foo
+//	-- ident.out --
+//	1:1 Ident "foo"
+//	1:4 Newline "\n"
+//
+// Note that txtar always ends each file's contents with a newline. Any text
+// before the first file in the archive is ignored, and can be used for
+// comments.
+//
+// When the test binary is run with the -lextest.update flag (or UpdateEnv set)
+// the .out files are instead rewritten with the current dumps, in the same way
+// as for Snapshot.
+func RunTxtar(t *testing.T, path string, newFn NewFunc, names TypeNames) {
+	t.Helper()
+	ar, err := txtar.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := map[string]int{}
+	for i, f := range ar.Files {
+		if name := strings.TrimSuffix(f.Name, OutputSuffix); name != f.Name {
+			outputs[name] = i
+		}
+	}
+
+	var updated bool
+
+	for _, f := range ar.Files {
+		name := strings.TrimSuffix(f.Name, InputSuffix)
+		if name == f.Name {
+			continue
+		}
+		input := string(f.Data)
+		i, ok := outputs[name]
+		if update() {
+			got := DumpInput(newFn, input, names)
+			if !ok {
+				ar.Files = append(ar.Files, txtar.File{Name: name + OutputSuffix})
+				i = len(ar.Files) - 1
+			}
+			ar.Files[i].Data = []byte(got)
+			updated = true
+			continue
+		} else if !ok {
+			t.Errorf("%s: no %s file for %s", path, name+OutputSuffix, f.Name)
+			continue
+		}
+
+		want := string(ar.Files[i].Data)
+		t.Run(name, func(t *testing.T) {
+			got := DumpInput(newFn, input, names)
+			if got != want {
+				t.Errorf("token dump mismatch (run with -lextest.update if this is intended)\n%s", diffDumps(want, got))
+			}
+		})
+	}
+
+	if updated {
+		if err := os.WriteFile(path, txtar.Format(ar), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+// Package lexvet defines an Analyzer which reports common mistakes in code
+// using lexgo. It's run by the lexvet command, either directly or using go vet
+// -vettool, see cmd/lexvet.
+//
+// The mistakes reported are:
+//
+//   - A LexerFunc returning itself on a path where it hasn't consumed any
+//     input, which makes the Lexer loop forever (or until a WithStallLimit
+//     kicks in). Only functions declared with the LexerFunc signature, and
+//     function literals assigned to a variable, are checked.
+//
+//   - Emitting a Token after calling EmitErr in the same block. The Err Token
+//     ends the stream, so whatever's emitted after it is never seen.
+//
+//   - Discarding the error returned by ReadRune, ReadByte, PeekRune or
+//     PeekByte while using the rune or byte, which is meaningless at the end
+//     of the input. Reads directly following an earlier peek are allowed.
+//
+//   - Comparing a TokenType against a literal integer below UserDefined,
+//     rather than against Err or Warning.
+//
+// Each check is a heuristic, looking at the syntax of a single function, and
+// errs on the side of not reporting: a call consuming input on any path
+// before a return counts, as does passing the Lexer to another function.
+package lexvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const lexgoPath = "github.com/mediocregopher/lexgo"
+
+// Analyzer reports common mistakes in code using lexgo, see the package docs
+var Analyzer = &analysis.Analyzer{
+	Name: "lexvet",
+	Doc:  "report common mistakes in code using lexgo",
+	Run:  run,
+}
+
+// nonConsuming are the methods of Lexer which neither consume input nor end
+// the stream, so calling them doesn't stop a LexerFunc from looping
+var nonConsuming = map[string]bool{
+	"PeekRune":           true,
+	"PeekByte":           true,
+	"TryPeekRune":        true,
+	"IsNewline":          true,
+	"Line":               true,
+	"Snippet":            true,
+	"Source":             true,
+	"NewDiagnostic":      true,
+	"BufferRune":         true,
+	"BufferByte":         true,
+	"BufferString":       true,
+	"MarkSynthetic":      true,
+	"Ignore":             true,
+	"Emit":               true,
+	"EmitHex":            true,
+	"EmitBase64":         true,
+	"EmitWarning":        true,
+	"EmitRecoverableErr": true,
+}
+
+// emits are the methods of Lexer which emit a Token
+var emits = map[string]bool{
+	"Emit":               true,
+	"EmitHex":            true,
+	"EmitBase64":         true,
+	"EmitErr":            true,
+	"EmitRecoverableErr": true,
+	"EmitWarning":        true,
+	"EmitDiagnostic":     true,
+}
+
+// reads are the methods of Lexer whose error result is checked for, mapped to
+// whether they peek
+var reads = map[string]bool{
+	"ReadRune": false,
+	"ReadByte": false,
+	"PeekRune": true,
+	"PeekByte": true,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	// lexgo itself reads and peeks in ways which the checks can't follow
+	if pass.Pkg.Path() == lexgoPath {
+		return nil, nil
+	}
+
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if n.Body != nil {
+					checkSelfLoop(pass, n.Type, pass.TypesInfo.Defs[n.Name], n.Body)
+					checkReads(pass, n.Body)
+				}
+			case *ast.AssignStmt:
+				for i, rhs := range n.Rhs {
+					lit, ok := rhs.(*ast.FuncLit)
+					if !ok || len(n.Lhs) != len(n.Rhs) {
+						continue
+					} else if id, ok := n.Lhs[i].(*ast.Ident); ok {
+						checkSelfLoop(pass, lit.Type, pass.TypesInfo.ObjectOf(id), lit.Body)
+					}
+				}
+			case *ast.FuncLit:
+				checkReads(pass, n.Body)
+			case *ast.BlockStmt:
+				checkEmitAfterErr(pass, n.List)
+			case *ast.CaseClause:
+				checkEmitAfterErr(pass, n.Body)
+			case *ast.CommClause:
+				checkEmitAfterErr(pass, n.Body)
+			case *ast.BinaryExpr:
+				if n.Op == token.EQL || n.Op == token.NEQ {
+					checkTokenTypeLit(pass, n.X, n.Y)
+					checkTokenTypeLit(pass, n.Y, n.X)
+				}
+			case *ast.SwitchStmt:
+				if n.Tag != nil {
+					for _, stmt := range n.Body.List {
+						for _, e := range stmt.(*ast.CaseClause).List {
+							checkTokenTypeLit(pass, n.Tag, e)
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// isLexgo returns whether t is the named type of the lexgo package with the
+// given name
+func isLexgo(t types.Type, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == lexgoPath && obj.Name() == name
+}
+
+func isLexer(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	return ok && isLexgo(ptr.Elem(), "Lexer")
+}
+
+// lexerCall returns the object of the Lexer variable the call's method is
+// called on, and the method's name, if it's a call of a Lexer method on a
+// variable
+func lexerCall(pass *analysis.Pass, call *ast.CallExpr) (types.Object, string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, "", false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil, "", false
+	}
+	obj := pass.TypesInfo.ObjectOf(id)
+	if obj == nil || !isLexer(obj.Type()) {
+		return nil, "", false
+	}
+	return obj, sel.Sel.Name, true
+}
+
+// checkSelfLoop reports return statements in a LexerFunc, whose object is
+// self, which return self without any input having been consumed
+func checkSelfLoop(pass *analysis.Pass, typ *ast.FuncType, self types.Object, body *ast.BlockStmt) {
+	if self == nil || typ.Params == nil || len(typ.Params.List) != 1 ||
+		len(typ.Params.List[0].Names) != 1 || typ.Results == nil ||
+		len(typ.Results.List) != 1 ||
+		!isLexgo(pass.TypesInfo.TypeOf(typ.Results.List[0].Type), "LexerFunc") {
+		return
+	}
+	lexer := pass.TypesInfo.Defs[typ.Params.List[0].Names[0]]
+	if lexer == nil || !isLexer(lexer.Type()) {
+		return
+	}
+
+	c := selfLoopChecker{pass: pass, lexer: lexer, self: self}
+	c.stmts(body.List, false)
+}
+
+type selfLoopChecker struct {
+	pass        *analysis.Pass
+	lexer, self types.Object
+}
+
+// consumes returns whether the node contains a call which may consume input
+// from the Lexer, or end its stream. Function literals aren't looked in.
+func (c selfLoopChecker) consumes(n ast.Node) bool {
+	if n == nil {
+		return false
+	}
+	var found bool
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if obj, name, ok := lexerCall(c.pass, n); ok && obj == c.lexer && !nonConsuming[name] {
+				found = true
+			}
+			// A function given the Lexer may consume from it
+			for _, arg := range n.Args {
+				if id, ok := arg.(*ast.Ident); ok && c.pass.TypesInfo.ObjectOf(id) == c.lexer {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func (c selfLoopChecker) stmts(list []ast.Stmt, consumed bool) {
+	for _, stmt := range list {
+		c.stmt(stmt, consumed)
+		consumed = consumed || c.consumes(stmt)
+	}
+}
+
+// stmt checks the statement, given whether input has been consumed by the
+// time it's reached
+func (c selfLoopChecker) stmt(stmt ast.Stmt, consumed bool) {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		if consumed || len(s.Results) != 1 {
+			return
+		}
+		if id, ok := s.Results[0].(*ast.Ident); ok && c.pass.TypesInfo.ObjectOf(id) == c.self {
+			c.pass.Reportf(s.Pos(), "%s returns itself without consuming any input, so the Lexer will loop forever", id.Name)
+		}
+	case *ast.BlockStmt:
+		c.stmts(s.List, consumed)
+	case *ast.LabeledStmt:
+		c.stmt(s.Stmt, consumed)
+	case *ast.IfStmt:
+		consumed = consumed || c.consumes(s.Init) || c.consumes(s.Cond)
+		c.stmt(s.Body, consumed)
+		if s.Else != nil {
+			c.stmt(s.Else, consumed)
+		}
+	case *ast.ForStmt:
+		c.stmt(s.Body, consumed || c.consumes(s.Init) || c.consumes(s.Cond))
+	case *ast.RangeStmt:
+		c.stmt(s.Body, consumed || c.consumes(s.X))
+	case *ast.SwitchStmt:
+		consumed = consumed || c.consumes(s.Init) || c.consumes(s.Tag)
+		for _, cc := range s.Body.List {
+			cc := cc.(*ast.CaseClause)
+			caseConsumed := consumed
+			for _, e := range cc.List {
+				caseConsumed = caseConsumed || c.consumes(e)
+			}
+			c.stmts(cc.Body, caseConsumed)
+		}
+	case *ast.TypeSwitchStmt:
+		consumed = consumed || c.consumes(s.Init) || c.consumes(s.Assign)
+		for _, cc := range s.Body.List {
+			c.stmts(cc.(*ast.CaseClause).Body, consumed)
+		}
+	case *ast.SelectStmt:
+		for _, cc := range s.Body.List {
+			cc := cc.(*ast.CommClause)
+			c.stmts(cc.Body, consumed || c.consumes(cc.Comm))
+		}
+	}
+}
+
+// checkEmitAfterErr reports calls emitting Tokens which follow a call to
+// EmitErr, on the same Lexer, within the given list of statements
+func checkEmitAfterErr(pass *analysis.Pass, list []ast.Stmt) {
+	for i, stmt := range list {
+		es, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		call, ok := es.X.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		lexer, name, ok := lexerCall(pass, call)
+		if !ok || name != "EmitErr" {
+			continue
+		}
+
+		for _, next := range list[i+1:] {
+			if _, ok := next.(*ast.ReturnStmt); ok {
+				break
+			}
+			ast.Inspect(next, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.CallExpr:
+					if obj, name, ok := lexerCall(pass, n); ok && obj == lexer && emits[name] {
+						pass.Reportf(n.Pos(), "%s called after EmitErr; the Err Token ends the stream, so nothing emitted after it is seen", name)
+					}
+				}
+				return true
+			})
+		}
+		return
+	}
+}
+
+// checkReads reports reads from a Lexer whose error is discarded, within the
+// given function body. Function literals within the body are checked
+// separately.
+func checkReads(pass *analysis.Pass, body *ast.BlockStmt) {
+	// peeked holds the Lexers which have been peeked at so far
+	peeked := map[types.Object]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		var lhs []ast.Expr
+		var rhs ast.Expr
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if len(n.Rhs) != 1 {
+				return true
+			}
+			lhs, rhs = n.Lhs, n.Rhs[0]
+		case *ast.ValueSpec:
+			if len(n.Values) != 1 {
+				return true
+			}
+			for _, name := range n.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = n.Values[0]
+		case *ast.CallExpr:
+			if obj, name, ok := lexerCall(pass, n); ok && (reads[name] || name == "TryPeekRune") {
+				peeked[obj] = true
+			}
+			return true
+		default:
+			return true
+		}
+
+		call, ok := rhs.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		lexer, name, ok := lexerCall(pass, call)
+		if peek, isRead := reads[name]; !ok || !isRead || (!peek && peeked[lexer]) {
+			return true
+		} else if len(lhs) < 2 || isBlank(lhs[0]) || !isBlank(lhs[len(lhs)-1]) {
+			return true
+		}
+		pass.Reportf(call.Pos(), "error from %s is discarded, but the result is used; at the end of the input it's meaningless", name)
+		return true
+	})
+}
+
+func isBlank(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == "_"
+}
+
+// checkTokenTypeLit reports if x is a TokenType and y a literal integer below
+// UserDefined
+func checkTokenTypeLit(pass *analysis.Pass, x, y ast.Expr) {
+	if !isLexgo(pass.TypesInfo.TypeOf(x), "TokenType") || !isIntLit(y) {
+		return
+	}
+	tv, ok := pass.TypesInfo.Types[y]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return
+	}
+	v, ok := constant.Int64Val(tv.Value)
+	if !ok || v >= 1 {
+		return
+	}
+
+	use := "a named TokenType"
+	switch v {
+	case 0:
+		use = "lexgo.Err"
+	case -1:
+		use = "lexgo.Warning"
+	}
+	pass.Reportf(y.Pos(), "TokenType compared against literal %d, which is below UserDefined; use %s", v, use)
+}
+
+// isIntLit returns whether e is an integer literal, optionally negated
+func isIntLit(e ast.Expr) bool {
+	for {
+		switch x := e.(type) {
+		case *ast.ParenExpr:
+			e = x.X
+		case *ast.UnaryExpr:
+			if x.Op != token.SUB && x.Op != token.ADD {
+				return false
+			}
+			e = x.X
+		case *ast.BasicLit:
+			return x.Kind == token.INT
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package lexvet
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// TestAnalyzer runs the Analyzer over the cases in testdata/src/a, which check
+// that each mistake is reported, and that the cases the checks deliberately
+// allow aren't
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
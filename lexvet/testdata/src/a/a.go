@@ -0,0 +1,104 @@
+package a
+
+import (
+	"errors"
+
+	"github.com/mediocregopher/lexgo"
+)
+
+func lexLoop(l *lexgo.Lexer) lexgo.LexerFunc {
+	if _, err := l.PeekRune(); err != nil {
+		return nil
+	}
+	l.Ignore()
+	return lexLoop // want `lexLoop returns itself without consuming any input`
+}
+
+func lexConsumes(l *lexgo.Lexer) lexgo.LexerFunc {
+	r, _, err := l.ReadRune()
+	if err != nil {
+		return nil
+	}
+	l.BufferRune(r)
+	l.Emit(lexgo.UserDefined)
+	return lexConsumes
+}
+
+func lexBranch(l *lexgo.Lexer) lexgo.LexerFunc {
+	r, err := l.PeekRune()
+	if err != nil {
+		return nil
+	} else if r == ' ' {
+		return lexBranch // want `lexBranch returns itself without consuming any input`
+	}
+	// Consuming on any path before a return counts, so this isn't reported
+	if r == '\t' {
+		l.SkipWhitespace()
+	}
+	return lexBranch
+}
+
+func skip(l *lexgo.Lexer) {
+	l.ReadRune()
+}
+
+// The helper may consume from the Lexer, so this isn't reported
+func lexHelper(l *lexgo.Lexer) lexgo.LexerFunc {
+	skip(l)
+	return lexHelper
+}
+
+func lits() {
+	var lexLit lexgo.LexerFunc
+	lexLit = func(l *lexgo.Lexer) lexgo.LexerFunc {
+		return lexLit // want `lexLit returns itself without consuming any input`
+	}
+	_ = lexLit
+}
+
+func emitAfterErr(l *lexgo.Lexer) lexgo.LexerFunc {
+	l.EmitErr(errors.New("bad"))
+	l.Emit(lexgo.UserDefined) // want `Emit called after EmitErr`
+	return nil
+}
+
+func emitErrThenReturn(l *lexgo.Lexer, bad bool) lexgo.LexerFunc {
+	if bad {
+		l.EmitErr(errors.New("bad"))
+		return nil
+	}
+	l.Emit(lexgo.UserDefined)
+	return nil
+}
+
+func discardedRead(l *lexgo.Lexer) {
+	r, _, _ := l.ReadRune() // want `error from ReadRune is discarded`
+	l.BufferRune(r)
+	b, _ := l.PeekByte() // want `error from PeekByte is discarded`
+	_ = b
+}
+
+// A read directly following a checked peek can't fail, so isn't reported
+func peekThenRead(l *lexgo.Lexer) {
+	if _, err := l.PeekRune(); err != nil {
+		return
+	}
+	r, _, _ := l.ReadRune()
+	l.BufferRune(r)
+}
+
+// Discarding the result along with the error is fine
+func discardBoth(l *lexgo.Lexer) {
+	_, _, _ = l.ReadRune()
+}
+
+func tokenTypes(tok *lexgo.Token) bool {
+	switch tok.TokenType {
+	case 0: // want `literal 0, which is below UserDefined; use lexgo.Err`
+		return false
+	}
+	return tok.TokenType == -1 || // want `literal -1, which is below UserDefined; use lexgo.Warning`
+		tok.TokenType != -2 || // want `literal -2, which is below UserDefined; use a named TokenType`
+		tok.TokenType == 1 ||
+		tok.TokenType == lexgo.Err
+}
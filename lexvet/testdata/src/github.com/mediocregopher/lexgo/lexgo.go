@@ -0,0 +1,32 @@
+// Package lexgo is a stub of the real package, holding just enough for the
+// lexvet test cases to type check
+package lexgo
+
+type TokenType int
+
+const (
+	Err TokenType = iota
+	UserDefined
+)
+
+const Warning TokenType = -1
+
+type Token struct {
+	TokenType
+	Val string
+}
+
+type Lexer struct{}
+
+type LexerFunc func(*Lexer) LexerFunc
+
+func (l *Lexer) ReadRune() (rune, int, error) { return 0, 0, nil }
+func (l *Lexer) ReadByte() (byte, error)      { return 0, nil }
+func (l *Lexer) PeekRune() (rune, error)      { return 0, nil }
+func (l *Lexer) PeekByte() (byte, error)      { return 0, nil }
+func (l *Lexer) BufferRune(r rune)            {}
+func (l *Lexer) Emit(t TokenType)             {}
+func (l *Lexer) EmitErr(err error)            {}
+func (l *Lexer) EmitWarning(err error)        {}
+func (l *Lexer) Ignore()                      {}
+func (l *Lexer) SkipWhitespace() int          { return 0 }
@@ -0,0 +1,225 @@
+package lexgo
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// RetainLines causes the Lexer to hold onto the text of the last n lines of
+// input it has consumed, as well as the line currently being consumed, so that
+// error reporters can quote the source around a Token using Line and Snippet
+// without separately re-reading it.
+//
+// If the io.Reader given to NewLexer is also an io.ReaderAt (e.g. an *os.File,
+// strings.Reader or bytes.Reader) then only the offset at which each line
+// starts is retained, and lines are re-read from the input as needed. In that
+// case every line consumed so far is available regardless of n, and the
+// current line is returned in full, rather than only up to what's been
+// consumed.
+func RetainLines(n int) Option {
+	return func(l *Lexer) {
+		l.lines = &lineWindow{n: n}
+	}
+}
+
+type lineRune struct {
+	r              rune
+	size           int
+	newline, isSet bool
+}
+
+// lineWindow holds the lines retained by RetainLines. Each rune consumed is
+// held as pending until the next one is, in case UnreadRune is called for it
+type lineWindow struct {
+	n  int
+	ra io.ReaderAt
+
+	// first is the internally tracked row of the oldest line retained, and
+	// begin the Cursor at the start of the very first row, which may not be
+	// at its first column (see SetStartPosition)
+	first int
+	begin Cursor
+
+	// lines is a ring of up to n complete lines, the oldest at head, and cur
+	// the line currently being consumed. Neither is used with ra
+	lines []string
+	head  int
+	cur   []byte
+
+	// used with ra. off is the offset in ra of the next rune, and starts the
+	// offset each row starts at, from first onwards
+	off    int64
+	starts []int64
+
+	pending lineRune
+}
+
+// reset discards any lines retained, and sets the Cursor at which the first
+// row will start
+func (w *lineWindow) reset(c Cursor) {
+	*w = lineWindow{n: w.n, ra: w.ra, first: c.Row, begin: c}
+	if w.ra != nil {
+		w.starts = []int64{0}
+	}
+}
+
+func (w *lineWindow) add(r rune, size int, newline bool) {
+	w.apply()
+	w.pending = lineRune{r: r, size: size, newline: newline, isSet: true}
+}
+
+func (w *lineWindow) unread() {
+	w.pending = lineRune{}
+}
+
+func (w *lineWindow) apply() {
+	p := w.pending
+	if !p.isSet {
+		return
+	}
+	w.pending = lineRune{}
+
+	if w.ra != nil {
+		w.off += int64(p.size)
+		if p.newline {
+			w.starts = append(w.starts, w.off)
+		}
+		return
+	} else if !p.newline {
+		w.cur = utf8.AppendRune(w.cur, p.r)
+		return
+	}
+
+	line := string(w.cur)
+	w.cur = w.cur[:0]
+	if w.n <= 0 {
+		w.first++
+	} else if len(w.lines) < w.n {
+		w.lines = append(w.lines, line)
+	} else {
+		w.lines[w.head] = line
+		w.head = (w.head + 1) % w.n
+		w.first++
+	}
+}
+
+// line returns the text of the given internally tracked row, without its line
+// break. The pending rune is taken into account without being applied, in case
+// it's still to be unread
+func (w *lineWindow) line(row int, isNewline func(rune) bool) (string, bool) {
+	i := row - w.first
+	p := w.pending
+	if i < 0 {
+		return "", false
+	} else if w.ra != nil {
+		starts := w.starts
+		if p.isSet && p.newline {
+			starts = append(starts[:len(starts):len(starts)], w.off+int64(p.size))
+		}
+		return w.read(starts, i, isNewline)
+	}
+
+	cur := string(w.cur)
+	if p.isSet && !p.newline {
+		cur += string(p.r)
+	}
+	switch {
+	case i < len(w.lines):
+		return strings.TrimSuffix(w.lines[(w.head+i)%len(w.lines)], "\r"), true
+	case i == len(w.lines):
+		return strings.TrimSuffix(cur, "\r"), true
+	case i == len(w.lines)+1 && p.isSet && p.newline:
+		return "", true
+	}
+	return "", false
+}
+
+// read reads the i'th of the rows starting at the given offsets from ra
+func (w *lineWindow) read(starts []int64, i int, isNewline func(rune) bool) (string, bool) {
+	if i >= len(starts) {
+		return "", false
+	}
+
+	var b []byte
+	if i+1 < len(starts) {
+		b = make([]byte, starts[i+1]-starts[i])
+		if n, err := w.ra.ReadAt(b, starts[i]); n < len(b) && err != nil {
+			return "", false
+		}
+		// drop the line break
+		_, size := utf8.DecodeLastRune(b)
+		b = b[:len(b)-size]
+	} else {
+		// The end of the current row isn't known, so read until a line break
+		// or the end of the input
+		chunk := make([]byte, 256)
+	loop:
+		for off := starts[i]; ; {
+			n, err := w.ra.ReadAt(chunk, off)
+			for rest := chunk[:n]; len(rest) > 0; {
+				r, size := utf8.DecodeRune(rest)
+				if !utf8.FullRune(rest) && err == nil {
+					// read the rest of the rune with the next chunk
+					break
+				} else if isNewline(r) {
+					break loop
+				}
+				b = append(b, rest[:size]...)
+				rest, off = rest[size:], off+int64(size)
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	return strings.TrimSuffix(string(b), "\r"), true
+}
+
+// Line returns the text of the given row of the input, without its line break,
+// using the same numbering as the rows of Tokens (see WithPositionBase). If the
+// row is the one currently being consumed only what has been consumed of it is
+// returned, unless the input is an io.ReaderAt. False is returned if the row
+// isn't available, or if the Lexer wasn't constructed using RetainLines.
+func (l *Lexer) Line(row int) (string, bool) {
+	if l.lines == nil {
+		return "", false
+	}
+	return l.lines.line(row-l.rowAdj, l.IsNewline)
+}
+
+// Snippet returns the text of the input from row/col up to, but not including,
+// endRow/endCol, using the same numbering as the positions of Tokens. This
+// matches the range of a Diagnostic. The rows are retrieved as by Line, and
+// are joined by '\n'. False is returned if any of them isn't available.
+func (l *Lexer) Snippet(row, col, endRow, endCol int) (string, bool) {
+	if l.lines == nil || endRow < row {
+		return "", false
+	}
+	row, col = row-l.rowAdj, col-l.colAdj
+	endRow, endCol = endRow-l.rowAdj, endCol-l.colAdj
+
+	var b strings.Builder
+	for r := row; r <= endRow; r++ {
+		line, ok := l.lines.line(r, l.IsNewline)
+		if !ok {
+			return "", false
+		} else if r > row {
+			b.WriteByte('\n')
+		}
+
+		// The columns of the line's runes are found by replaying them through
+		// the PositionTracker
+		c := Cursor{Row: r, NextCol: 1}
+		if r == l.lines.begin.Row {
+			c = l.lines.begin
+		}
+		for _, ch := range line {
+			c = l.tracker.Advance(c, ch, utf8.RuneLen(ch), false)
+			if (r > row || c.Col >= col) && (r < endRow || c.Col < endCol) {
+				b.WriteRune(ch)
+			}
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,299 @@
+package lexgo
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultBackupLimit = 16
+
+// readMark holds everything which makes up the Lexer's position in the stream,
+// along with the rune most recently read there, so that it can be restored
+type readMark struct {
+	cur                       Cursor
+	absOff, nextOff, inputOff int
+	lastRune                  rune
+	lastWidth                 int
+}
+
+func (l *Lexer) mark() readMark {
+	return readMark{
+		cur:    l.cur,
+		absOff: l.absOff, nextOff: l.nextOff, inputOff: l.inputOff,
+		lastRune: l.lastRune, lastWidth: l.lastWidth,
+	}
+}
+
+func (l *Lexer) setMark(m readMark) {
+	l.cur = m.cur
+	l.absOff, l.nextOff, l.inputOff = m.absOff, m.nextOff, m.inputOff
+	l.lastRune, l.lastWidth = m.lastRune, m.lastWidth
+}
+
+// aheadRune is a rune which has been read off the underlying reader, or put
+// back by Backup, but not yet consumed by the Lexer
+type aheadRune struct {
+	r    rune
+	size int
+
+	// set if the rune was put back by Backup, in which case the position has
+	// already been worked out once and is restored from after, rather than
+	// being advanced again
+	replay bool
+	after  readMark
+
+	// any line continuation bytes which were discarded directly before the
+	// rune, which the position must still be advanced over
+	skipped []byte
+
+	// set if the rune was invalid utf8, with its raw bytes if known
+	invalid      bool
+	invalidBytes []byte
+
+	// set if the underlying reader returned an error instead of a rune
+	err error
+}
+
+// aheadRing is a double-ended queue of aheadRunes, the next to be read first
+type aheadRing struct {
+	buf     []aheadRune
+	head, n int
+}
+
+func (q *aheadRing) at(i int) *aheadRune {
+	return &q.buf[(q.head+i)&(len(q.buf)-1)]
+}
+
+func (q *aheadRing) grow() {
+	if q.n < len(q.buf) {
+		return
+	}
+	size := 2 * len(q.buf)
+	if size == 0 {
+		size = 8
+	}
+	buf := make([]aheadRune, size)
+	for i := 0; i < q.n; i++ {
+		buf[i] = *q.at(i)
+	}
+	q.buf, q.head = buf, 0
+}
+
+func (q *aheadRing) pushBack(a aheadRune) {
+	q.grow()
+	q.n++
+	*q.at(q.n - 1) = a
+}
+
+func (q *aheadRing) pushFront(a aheadRune) {
+	q.grow()
+	q.head = (q.head - 1) & (len(q.buf) - 1)
+	q.n++
+	*q.at(0) = a
+}
+
+func (q *aheadRing) popFront() aheadRune {
+	a := *q.at(0)
+	*q.at(0) = aheadRune{}
+	q.head = (q.head + 1) & (len(q.buf) - 1)
+	q.n--
+	return a
+}
+
+// pastRune is a rune which has been consumed, as retained for Backup, along
+// with the position from before it was read
+type pastRune struct {
+	r      rune
+	size   int
+	before readMark
+}
+
+// pastRing holds the most recently consumed runes, up to its capacity, the
+// oldest being overwritten once it's full
+type pastRing struct {
+	buf     []pastRune
+	head, n int
+}
+
+func (q *pastRing) push(p pastRune) {
+	if len(q.buf) == 0 {
+		return
+	} else if q.n < len(q.buf) {
+		q.n++
+	} else if q.head++; q.head == len(q.buf) {
+		q.head = 0
+	}
+	i := q.head + q.n - 1
+	if i >= len(q.buf) {
+		i -= len(q.buf)
+	}
+	q.buf[i] = p
+}
+
+func (q *pastRing) popBack() pastRune {
+	q.n--
+	i := q.head + q.n
+	if i >= len(q.buf) {
+		i -= len(q.buf)
+	}
+	return q.buf[i]
+}
+
+// WithBackupLimit sets how many of the most recently read runes the Lexer
+// holds onto so that they can be put back using Backup. The default is 16. A
+// limit of zero or less causes nothing to be held onto, which saves a little
+// bookkeeping per rune for lexers which never call Backup.
+func WithBackupLimit(n int) Option {
+	return func(l *Lexer) {
+		l.backupLimit = n
+	}
+}
+
+// ErrBackupLimit is returned by Backup when asked to back up over more runes
+// than the Lexer has held onto, see WithBackupLimit
+var ErrBackupLimit = errors.New("lexgo: can't back up past the runes retained for Backup")
+
+// Backup moves the Lexer back over the k runes most recently read, so that
+// they'll be read again by the following reads, and the position is that from
+// before they were first read. Unlike UnreadRune this may be done at any time,
+// for any k up to the limit set by WithBackupLimit. Runes consumed by helpers
+// like SkipWhile and AcceptRun count the same as those read by ReadRune.
+//
+// The output buffer is not affected, so any runes which were buffered remain
+// so. Backing up over Tokens which have already been emitted is possible, but
+// will cause them to be lexed again. OnRead hooks aren't given runes a second
+// time when they're read again.
+//
+// If fewer than k runes are held onto then ErrBackupLimit is returned, and
+// nothing is done.
+func (l *Lexer) Backup(k int) error {
+	if k <= 0 {
+		return nil
+	} else if k > l.behind.n {
+		return ErrBackupLimit
+	}
+
+	l.commitRead()
+	if l.heldErr != nil {
+		// The held error comes after the runes being put back
+		l.ahead.pushFront(aheadRune{err: l.heldErr})
+		l.heldErr = nil
+	}
+
+	after := l.mark()
+	for ; k > 0; k-- {
+		p := l.behind.popBack()
+		l.ahead.pushFront(aheadRune{r: p.r, size: p.size, replay: true, after: after})
+		after = p.before
+	}
+	l.setMark(after)
+	return nil
+}
+
+// PeekRuneN returns the nth rune which will appear in the stream, counting the
+// next rune as the first, without advancing the reader, so PeekRuneN(1) is the
+// same rune as returned by PeekRune. Runes which are looked ahead at are held
+// by the Lexer itself, so any n may be used, regardless of the io.Reader given
+// to NewLexer or the options used.
+//
+// If the stream ends or an error is encountered before the nth rune then that
+// error is returned. Unlike with PeekRune it isn't emitted, since runes before
+// it remain to be read, rather it's emitted as normal once reading reaches it.
+func (l *Lexer) PeekRuneN(n int) (rune, error) {
+	if n < 1 {
+		return 0, errors.New("lexgo: PeekRuneN given n less than 1")
+	}
+	pr := peekRuneReader{l: l}
+	for ; n > 1; n-- {
+		if _, _, err := pr.ReadRune(); err != nil {
+			return 0, err
+		}
+	}
+	r, _, err := pr.ReadRune()
+	return r, err
+}
+
+// readAhead reads the next rune off the underlying reader onto the end of the
+// lookahead ring. Any error or invalid utf8 character encountered is held in
+// the ring in place of the rune
+func (l *Lexer) readAhead() {
+	a := aheadRune{skipped: l.takeContinuations()}
+	if l.binary {
+		b, err := l.bs.ReadByte()
+		a.r, a.size, a.err = rune(b), 1, err
+	} else {
+		a.r, a.size, a.err = l.r.ReadRune()
+		if a.err == nil && a.r == unicode.ReplacementChar && a.size == 1 {
+			a.invalid, a.invalidBytes = true, l.invalidBytes()
+			if l.resume {
+				a.size = l.discardInvalid(a.invalidBytes)
+			}
+		}
+	}
+	l.lastByte = false
+	l.ahead.pushBack(a)
+}
+
+// aheadAt returns the rune at index i of the lookahead ring, reading more in
+// if necessary. If the ring holds an error or, without ResumeAfterError, an
+// invalid utf8 character at i then the error is returned. An invalid character
+// with ResumeAfterError is skipped over, since reading will skip it too, and
+// so the index of the rune returned is also returned
+func (l *Lexer) aheadAt(i int) (int, rune, int, error) {
+	l.commitRead()
+	if l.heldErr != nil {
+		return i, 0, 0, l.heldErr
+	}
+	for ; ; i++ {
+		if i == l.ahead.n {
+			l.readAhead()
+		}
+		a := l.ahead.at(i)
+		switch {
+		case a.err != nil:
+			return i, 0, 0, a.err
+		case a.invalid && l.resume:
+			continue
+		case a.invalid:
+			return i, 0, 0, l.invalidUTF8Err(a.invalidBytes)
+		}
+		return i, a.r, a.size, nil
+	}
+}
+
+// decodeAhead is decodeRune for when there are runes in the lookahead ring
+func (l *Lexer) decodeAhead() (rune, int, error) {
+	a := l.ahead.popFront()
+	l.skipBytes(a.skipped)
+	if a.err != nil {
+		return 0, 0, a.err
+	} else if a.invalid {
+		return l.decodeInvalid(a.invalidBytes, a.size)
+	}
+
+	a.skipped = nil
+	l.lastAhead, l.lastRead = true, a
+	return a.r, a.size, nil
+}
+
+// peekAheadBytes returns the encoding of as many of the upcoming runes as
+// make up no more than n bytes, looking ahead using the lookahead ring. The
+// returned slice is only valid until the next call.
+func (l *Lexer) peekAheadBytes(n int) []byte {
+	b := l.aheadBytes[:0]
+	pr := peekRuneReader{l: l}
+	for len(b) < n {
+		r, _, err := pr.ReadRune()
+		if err != nil {
+			break
+		} else if l.binary {
+			b = append(b, byte(r))
+		} else if b = utf8.AppendRune(b, r); len(b) > n {
+			b = b[:len(b)-utf8.RuneLen(r)]
+			break
+		}
+	}
+	l.aheadBytes = b
+	return b
+}
@@ -0,0 +1,94 @@
+package lexgo
+
+import "unicode/utf8"
+
+// Match checks if the upcoming input starts with s. If it does s is read and
+// buffered, and true is returned. Otherwise the stream is left as it was and
+// false is returned, even if some of s did match. This replaces sequences of
+// nested peeks for fixed delimiters like "<!--" or ":=".
+//
+// The check is done by looking ahead in the Lexer's internal buffer where
+// possible, or otherwise using its lookahead ring (see PeekRuneN), so nothing
+// is read unless all of s matches.
+//
+// Follows the same error semantics as Accept().
+func (l *Lexer) Match(s string) bool {
+	return l.match(s, false, nil)
+}
+
+// MatchFold is like Match, except that runes are compared using unicode case
+// folding (see FoldCase), e.g. for case-insensitive keywords. The input's
+// original spelling is what gets buffered.
+func (l *Lexer) MatchFold(s string) bool {
+	return l.match(s, true, nil)
+}
+
+// AcceptFold is like Accept, except that runes are compared against those in
+// valid using unicode case folding, e.g. AcceptFold("x") accepts either "x" or
+// "X". The input's original spelling is what gets buffered.
+func (l *Lexer) AcceptFold(valid string) bool {
+	r, err := l.peekRune()
+	if err != nil {
+		return false
+	}
+	fr := foldRune(r)
+	for _, v := range valid {
+		if v == r || foldRune(v) == fr {
+			l.ReadRune()
+			l.BufferRune(r)
+			return true
+		}
+	}
+	return false
+}
+
+// MatchKeyword checks if the upcoming input is the keyword s, followed by a
+// rune which can't continue an identifier (see IsIdentContinue) or the end of
+// the stream. If it is the keyword is read and buffered, and true is returned.
+// Otherwise the stream is left as it was and false is returned. This prevents
+// the classic bug where the start of "iffy" is taken to be the keyword "if".
+// The same caveats as for Match apply.
+func (l *Lexer) MatchKeyword(s string) bool {
+	return l.match(s, false, IsIdentContinue)
+}
+
+// match checks if the upcoming input is s, followed by a rune which notAfter
+// returns false for or the end of the stream (notAfter may be nil), and reads
+// and buffers s if so. If fold is set runes are compared under unicode case
+// folding
+func (l *Lexer) match(s string, fold bool, notAfter func(rune) bool) bool {
+	if s == "" {
+		return false
+	}
+
+	eq := func(a, b rune) bool { return a == b }
+	if fold {
+		eq = func(a, b rune) bool { return a == b || foldRune(a) == foldRune(b) }
+	}
+
+	// Runes which fold to each other may have different encoded lengths, so
+	// the input can't be compared to s byte-wise when folding
+	pr := l.peekReader()
+	var n int
+	for i := 0; i < len(s); n++ {
+		// In BinaryMode each byte of s is a rune of its own
+		want, size := rune(s[i]), 1
+		if !l.binary && want >= utf8.RuneSelf {
+			want, size = utf8.DecodeRuneInString(s[i:])
+		}
+		i += size
+		if r, _, err := pr.ReadRune(); err != nil || !eq(r, want) {
+			return false
+		}
+	}
+	if notAfter != nil {
+		if r, _, err := pr.ReadRune(); err == nil && notAfter(r) {
+			return false
+		}
+	}
+	for ; n > 0; n-- {
+		r, _, _ := l.ReadRune()
+		l.BufferRune(r)
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+//go:build !tinygo
+
+package lexgo
+
+import (
+	"io"
+	"sync"
+)
+
+// LabeledToken is a Token along with the label of the source it came from
+type LabeledToken struct {
+	Source string
+	*Token
+}
+
+// MuxSource is a Tokenizer to be read from by a TokenMux, along with the label
+// its Tokens should be given
+type MuxSource struct {
+	Label string
+	Tokenizer
+}
+
+// TokenMux reads Tokens from multiple Tokenizers concurrently, and merges them
+// into a single stream in which each Token is labeled with the source it came
+// from. This is useful for tools like indexers which lex many inputs at once
+// and want a single point of consumption.
+type TokenMux struct {
+	ch        chan LabeledToken
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	remaining int
+}
+
+// NewTokenMux returns a TokenMux which immediately begins reading from all of
+// the given sources, each in its own go-routine
+func NewTokenMux(srcs ...MuxSource) *TokenMux {
+	m := &TokenMux{
+		ch:        make(chan LabeledToken, len(srcs)),
+		stopCh:    make(chan struct{}),
+		remaining: len(srcs),
+	}
+	for _, src := range srcs {
+		go m.spin(src)
+	}
+	return m
+}
+
+func (m *TokenMux) spin(src MuxSource) {
+	for {
+		tok := src.Next()
+		select {
+		case m.ch <- LabeledToken{Source: src.Label, Token: tok}:
+		case <-m.stopCh:
+			tok.Release()
+			return
+		}
+		if tok.EndsStream() {
+			return
+		}
+	}
+}
+
+// Next returns the next Token read from any of the sources. Tokens from the
+// same source are returned in the order that source produced them, but there
+// are no guarantees about ordering between sources.
+//
+// The Err Token which ends each source (including io.EOF), as well as any
+// Recoverable ones, are passed through labeled like any other Token, and don't
+// end the TokenMux's stream. Once all sources have ended an io.EOF Token with
+// an empty Source is returned, and will continue to be returned for all
+// subsequent calls.
+func (m *TokenMux) Next() LabeledToken {
+	if m.remaining == 0 {
+		return LabeledToken{Token: &Token{TokenType: Err, Err: io.EOF}}
+	}
+	lt := <-m.ch
+	if lt.EndsStream() {
+		m.remaining--
+	}
+	return lt
+}
+
+// Stop causes all go-routines reading from sources to exit without reading any
+// further. It only needs to be called if Next won't be called until the end of
+// the stream. Next must not be called after Stop.
+func (m *TokenMux) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
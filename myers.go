@@ -0,0 +1,86 @@
+package lexgo
+
+// edit is a single step of an edit script, with the indices into the two
+// sequences it applies to (only the relevant one is meaningful for DiffDelete
+// and DiffInsert)
+type edit struct {
+	op   DiffOp
+	a, b int
+}
+
+// myers returns the shortest edit script turning a sequence of length n into
+// one of length m, using Myers' algorithm. Elements are compared using eq. The
+// script only consists of DiffEqual, DiffDelete and DiffInsert.
+//
+// Only the diagonals which the next round reads are kept from each round, so
+// memory use is O(D^2) rather than O((n+m)·D), D being the edit distance.
+func myers(n, m int, eq func(i, j int) bool) []edit {
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		// Round d only reads diagonals -(d-1) through d-1, which are all that
+		// backtrack will need from it
+		var snap []int
+		if d > 0 {
+			snap = append(snap, v[off-d+1:off+d]...)
+		}
+		trace = append(trace, snap)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && eq(x, y) {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks back through the trace produced by myers, where trace[d] is
+// the diagonals -(d-1) through d-1 of v prior to round d, to produce the edit
+// script ending at (x, y)
+func backtrack(trace [][]int, x, y, d int) []edit {
+	var edits []edit
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[d+k-2] < v[d+k]) {
+			prevK = k + 1
+		}
+		prevX := v[d-1+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			edits = append(edits, edit{DiffEqual, x, y})
+		}
+		if x == prevX {
+			edits = append(edits, edit{DiffInsert, x, prevY})
+		} else {
+			edits = append(edits, edit{DiffDelete, prevX, y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		edits = append(edits, edit{DiffEqual, x, y})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
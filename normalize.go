@@ -0,0 +1,73 @@
+package lexgo
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalizer describes something which can put a string into a normalized
+// form. The unicode normalization forms in golang.org/x/text/unicode/norm
+// (norm.NFC, norm.NFKC, etc...) all implement it
+type Normalizer interface {
+	String(string) string
+}
+
+// NormalizeValues causes the Val of every Token Emit()'d to be normalized
+// using the given Normalizer, for example:
+//
+//	lexgo.NewLexer(r, lexStart, lexgo.NormalizeValues(norm.NFC))
+//
+// This way identifiers which look identical, but which differ in their usage of
+// combining characters, will compare equal in downstream symbol tables. The
+// original text remains available as the Token's Raw field.
+func NormalizeValues(n Normalizer) Option {
+	return func(l *Lexer) {
+		l.normalizer = n
+	}
+}
+
+// FoldValues causes the Val of every Token Emit()'d to be case-folded using
+// FoldCase, for case-insensitive languages such as SQL or Pascal. The original
+// text remains available as the Token's Raw field. If used along with
+// NormalizeValues the value is normalized first, and then folded.
+func FoldValues() Option {
+	return func(l *Lexer) {
+		l.fold = true
+	}
+}
+
+// FoldCase returns s with each rune mapped to a canonical case, such that two
+// strings which are equal under unicode case folding (see strings.EqualFold)
+// will have equal FoldCase results. The canonical case is usually lower case.
+// If s is already folded it is returned as-is, without allocating.
+func FoldCase(s string) string {
+	for i, r := range s {
+		if foldRune(r) != r {
+			return s[:i] + strings.Map(foldRune, s[i:])
+		}
+	}
+	return s
+}
+
+// foldRune maps all runes in a unicode case folding orbit (see
+// unicode.SimpleFold) to the same rune, preferring the smallest lower case rune
+// in the orbit
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'A' <= r && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+		return r
+	}
+
+	best := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if fLower, bestLower := unicode.IsLower(f), unicode.IsLower(best); fLower && !bestLower {
+			best = f
+		} else if fLower == bestLower && f < best {
+			best = f
+		}
+	}
+	return best
+}
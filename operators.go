@@ -0,0 +1,66 @@
+package lexgo
+
+import (
+	"unicode/utf8"
+)
+
+// Operators returns a LexerFunc which reads the longest of the given operators
+// which appears next in the stream, emits it with its associated TokenType,
+// and returns next. If none of the operators appear next an error is emitted
+// and lexing ends. This makes the operator portion of a language's lexer
+// declarative, rather than a series of nested peeks:
+//
+//	lexOperator := lexgo.Operators(map[string]lexgo.TokenType{
+//		"=":   Assign,
+//		"==":  Equal,
+//		"===": StrictEqual,
+//		"!=":  NotEqual,
+//		"...": Ellipsis,
+//		".":   Dot,
+//	}, lexMain)
+//
+// Operators may consist of any runes, and must not be empty. The returned
+// LexerFunc expects nothing to be buffered when it is run.
+//
+// The operators are matched by looking ahead in the same way as for Match, so
+// input which starts with a longer operator but turns into a shorter one part
+// way through (e.g. ".." given only "." and "...") is handled correctly.
+func Operators(ops map[string]TokenType, next LexerFunc) LexerFunc {
+	var maxLen int
+	for op := range ops {
+		if op == "" {
+			panic("lexgo: Operators given an empty operator")
+		} else if len(op) > maxLen {
+			maxLen = len(op)
+		}
+	}
+
+	return func(l *Lexer) LexerFunc {
+		b := l.peekBytes(maxLen)
+		if b == nil {
+			b = l.peekAheadBytes(maxLen)
+		}
+		for i := len(b); i > 0; i-- {
+			tt, ok := ops[string(b[:i])]
+			if !ok {
+				continue
+			}
+			n := i
+			if !l.binary {
+				n = utf8.RuneCount(b[:i])
+			}
+			for ; n > 0; n-- {
+				r, _, _ := l.ReadRune()
+				l.BufferRune(r)
+			}
+			l.Emit(tt)
+			return next
+		}
+
+		if r, err := l.PeekRune(); err == nil {
+			row, col, off := l.nextPos()
+			l.unexpected(r, row, col, off)
+		}
+		return nil
+	}
+}
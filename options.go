@@ -0,0 +1,21 @@
+package lexgo
+
+// Option is used to configure optional behavior of a Lexer. Options are passed
+// into NewLexer
+type Option func(*Lexer)
+
+// WithQueueSize sets the initial capacity of the queue holding Tokens which
+// have been Emit()'d but not yet read by Next(). The queue grows as needed (a
+// LexerFunc may Emit() any number of Tokens in one invocation) and its memory
+// is reused once drained, so this only serves to avoid the first few
+// allocations for lexers which routinely emit bursts of Tokens, such as
+// sequences of INDENT/DEDENT Tokens. The default is 1, and values less than 1
+// are treated as 1.
+func WithQueueSize(n int) Option {
+	if n < 1 {
+		n = 1
+	}
+	return func(l *Lexer) {
+		l.queueSize = n
+	}
+}
@@ -0,0 +1,98 @@
+package lexgo
+
+// Patterns is a compiled set of literal strings, which MatchAny can check the
+// upcoming input against all at once. Rather than trying each string in turn
+// the input is walked just once, no matter how many strings there are, which
+// makes it a good fit for dispatching between dozens of directives, keywords
+// or delimiters.
+//
+// A Patterns is immutable once created, and may be shared between Lexers and
+// go-routines.
+type Patterns struct {
+	root     patNode
+	priority bool
+
+	// pats holds the strings p was created with, for Suggest
+	pats []string
+}
+
+type patNode struct {
+	next map[rune]*patNode
+
+	// index of the pattern ending at this node, or -1
+	index int
+}
+
+func newPatterns(priority bool, pats []string) *Patterns {
+	p := &Patterns{
+		root:     patNode{index: -1},
+		priority: priority,
+		pats:     append([]string(nil), pats...),
+	}
+	for i, pat := range pats {
+		if pat == "" {
+			panic("lexgo: Patterns given an empty pattern")
+		}
+		n := &p.root
+		for _, r := range pat {
+			if n.next == nil {
+				n.next = map[rune]*patNode{}
+			}
+			child, ok := n.next[r]
+			if !ok {
+				child = &patNode{index: -1}
+				n.next[r] = child
+			}
+			n = child
+		}
+		// Duplicates keep the index of their first occurrence
+		if n.index < 0 {
+			n.index = i
+		}
+	}
+	return p
+}
+
+// NewPatterns returns a Patterns for the given strings, which must not be
+// empty. When more than one of the strings matches the upcoming input MatchAny
+// picks the longest.
+func NewPatterns(pats ...string) *Patterns {
+	return newPatterns(false, pats)
+}
+
+// NewPriorityPatterns is like NewPatterns, except that when more than one of
+// the strings matches the upcoming input MatchAny picks whichever was given
+// first, regardless of length.
+func NewPriorityPatterns(pats ...string) *Patterns {
+	return newPatterns(true, pats)
+}
+
+// MatchAny checks the upcoming input against all strings in p. If any match,
+// the one chosen (see NewPatterns and NewPriorityPatterns) is read and
+// buffered, and its index in the list p was created with is returned.
+// Otherwise the stream is left as it was and -1 is returned.
+//
+// The check is done by looking ahead in the same way as for Match.
+//
+// Follows the same error semantics as Accept().
+func (l *Lexer) MatchAny(p *Patterns) int {
+	pr := l.peekReader()
+	index, runes := -1, 0
+	n := &p.root
+	for depth := 1; n.next != nil; depth++ {
+		r, _, err := pr.ReadRune()
+		if err != nil {
+			break
+		} else if n = n.next[r]; n == nil {
+			break
+		} else if n.index >= 0 && (index < 0 || !p.priority || n.index < index) {
+			index, runes = n.index, depth
+		}
+	}
+
+	for ; runes > 0; runes-- {
+		r, _, _ := l.ReadRune()
+		l.BufferRune(r)
+	}
+	return index
+}
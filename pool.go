@@ -0,0 +1,60 @@
+package lexgo
+
+import (
+	"sync"
+)
+
+var tokenPool = sync.Pool{
+	New: func() interface{} { return new(Token) },
+}
+
+// WithTokenPool causes the Lexer to take the Tokens it returns from Next()
+// from an internal pool, rather than allocating a new one for each. This can
+// greatly reduce garbage collector pressure for pipelines which process a
+// large number of short-lived Tokens.
+//
+// When this option is used each Token returned from Next() must have Release()
+// called on it once the caller is done with it, and must not be used at all
+// after that. Any Token which needs to be kept around longer should be copied
+// using Copy before being released.
+func WithTokenPool() Option {
+	return func(l *Lexer) {
+		l.pool = true
+	}
+}
+
+// Release returns the Token to the pool it was taken from, if the Lexer which
+// returned it was constructed using WithTokenPool. The Token must not be used
+// after Release has been called. Release is a no-op for Tokens which don't come
+// from a pool, so it is always safe to call
+func (t *Token) Release() {
+	if !t.pooled {
+		return
+	}
+	*t = Token{}
+	tokenPool.Put(t)
+}
+
+// tokenPtr returns a pointer to a copy of the given Token. The copy will be
+// taken from tokenPool if the Lexer is using it, or from the Lexer's Arena if it
+// has one
+func (l *Lexer) tokenPtr(t Token) *Token {
+	if l.arena != nil {
+		return l.arena.token(t)
+	} else if !l.pool {
+		return &t
+	}
+	tp := tokenPool.Get().(*Token)
+	*tp = t
+	tp.pooled = true
+	return tp
+}
+
+// Copy returns a copy of the Token which is independent of any pool the Token
+// came from, so that it may be kept around after the Token is released.
+// Calling Release on the copy is a no-op.
+func (t *Token) Copy() Token {
+	cp := *t
+	cp.pooled = false
+	return cp
+}
@@ -0,0 +1,190 @@
+package lexgo
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NoPositions disables all position tracking in the Lexer. The Row, Col and
+// Offset fields of all Tokens emitted will be left as zero. This is useful for
+// pipelines which don't care about positions (searching, counting, etc...) and
+// want to shave off the per-rune bookkeeping. Consumers which still need byte
+// offsets, e.g. to slice into the original input, should use
+// WithPositionTracker(OffsetsOnly()) instead.
+func NoPositions() Option {
+	return func(l *Lexer) {
+		l.noPos = true
+		l.row, l.col = 0, 0
+		l.cur = Cursor{}
+	}
+}
+
+// UnicodeNewlines causes the Lexer to treat U+0085 (NEL), U+2028 (LS) and
+// U+2029 (PS) as line breaks for the purpose of position tracking, in addition
+// to '\n'. Some formats (javascript, yaml) follow the unicode line breaking
+// rules in this way.
+func UnicodeNewlines() Option {
+	return func(l *Lexer) {
+		l.uniNL = true
+	}
+}
+
+// IsNewline returns whether the Lexer considers the given rune to be a line
+// break. This is always true for '\n', and also for the other unicode line
+// separators when UnicodeNewlines is used. LexerFuncs which emit newline
+// Tokens can use this to remain consistent with the Lexer's position tracking
+func (l *Lexer) IsNewline(r rune) bool {
+	switch r {
+	case '\n':
+		return true
+	case '\u0085', '\u2028', '\u2029':
+		return l.uniNL
+	default:
+		return false
+	}
+}
+
+// WithPositionBase sets the numbers which the first row and the first column of
+// a line are given in the positions the Lexer reports. The default is 1 for
+// both, which is the convention used by most compilers. Using 0 for both gives
+// positions which match the Language Server Protocol, for example.
+//
+// Line break runes are reported as being at column colBase-1 of the row they
+// start, as they are by default.
+func WithPositionBase(rowBase, colBase int) Option {
+	return func(l *Lexer) {
+		l.rowAdj, l.colAdj = rowBase-1, colBase-1
+	}
+}
+
+// reportPos converts the given row/col, as tracked internally, into the form
+// they should be reported in
+func (l *Lexer) reportPos(row, col int) (int, int) {
+	if l.noPos {
+		return row, col
+	}
+	return row + l.rowAdj, col + l.colAdj
+}
+
+// SetStartPosition seeds the Lexer's position, such that the first rune of the
+// input is reported as being at the given row, column and byte offset, using
+// the same conventions as the positions the Lexer reports (see
+// WithPositionBase). source is the name of the enclosing document, as returned
+// by Source. This is useful when the input is a fragment extracted from a larger
+// document, e.g. a snippet embedded in yaml or a template, so that positions
+// are reported relative to the enclosing document.
+//
+// SetStartPosition must be called before anything has been read from the Lexer
+func (l *Lexer) SetStartPosition(source string, row, col, offset int) {
+	l.source = source
+	if l.noPos {
+		return
+	}
+	nextCol := col - l.colAdj
+	l.cur = Cursor{Row: row - l.rowAdj, Col: nextCol - 1, NextCol: nextCol}
+	l.absOff, l.nextOff = offset, offset
+	if l.lines != nil {
+		l.lines.reset(l.cur)
+	}
+}
+
+// Source returns the name of the document being lexed, as given to
+// SetStartPosition, or the empty string if it was never called
+func (l *Lexer) Source() string {
+	return l.source
+}
+
+// Position is a location within a named document, e.g. that of a Token along
+// with the Lexer's Source
+type Position struct {
+	Source           string
+	Row, Col, Offset int
+}
+
+// Position returns the position of the Token within the document with the
+// given name, which is generally the Source of the Lexer which emitted it
+func (t *Token) Position(source string) Position {
+	return Position{Source: source, Row: t.Row, Col: t.Col, Offset: t.Offset}
+}
+
+// String returns the conventional "source:row:col" form of the Position, or
+// "row:col" if Source is empty. Offset isn't included.
+func (p Position) String() string {
+	rc := strconv.Itoa(p.Row) + ":" + strconv.Itoa(p.Col)
+	if p.Source == "" {
+		return rc
+	}
+	return p.Source + ":" + rc
+}
+
+// ParsePosition parses a Position from the form returned by String. The row
+// and column are taken from the end of s, so the source may itself contain
+// colons, e.g. a windows path. Offset is always left as zero.
+func ParsePosition(s string) (Position, error) {
+	rest, col, ok := cutLastInt(s)
+	if rest = strings.TrimSuffix(rest, ":"); ok && rest != "" {
+		var source string
+		var row int
+		if source, row, ok = cutLastInt(rest); ok {
+			return Position{Source: strings.TrimSuffix(source, ":"), Row: row, Col: col}, nil
+		}
+	}
+	return Position{}, fmt.Errorf("lexgo: invalid position %q, expected source:row:col", s)
+}
+
+// cutLastInt parses the integer following the last colon in s,
+// or making up the whole of s if there's no colon, and returns the part of s
+// preceding it
+func cutLastInt(s string) (string, int, bool) {
+	i := strings.LastIndexByte(s, ':') + 1
+	if i == len(s) || s[i] == '+' {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(s[i:])
+	return s[:i], n, err == nil
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding the Position in its
+// String form
+func (p Position) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, and is the inverse of
+// MarshalText
+func (p *Position) UnmarshalText(b []byte) error {
+	pp, err := ParsePosition(string(b))
+	if err != nil {
+		return err
+	}
+	*p = pp
+	return nil
+}
+
+// Compare returns -1 if p comes before o, 1 if it comes after, and 0 if they
+// are the same. Positions are ordered by Source, then by Row and Col, and
+// finally by Offset, so that positions which only have an Offset (see
+// OffsetsOnly) are also ordered correctly.
+func (p Position) Compare(o Position) int {
+	switch {
+	case p.Source != o.Source:
+		return cmp.Compare(p.Source, o.Source)
+	case p.Row != o.Row:
+		return cmp.Compare(p.Row, o.Row)
+	case p.Col != o.Col:
+		return cmp.Compare(p.Col, o.Col)
+	}
+	return cmp.Compare(p.Offset, o.Offset)
+}
+
+// Before returns whether p comes before o, see Compare
+func (p Position) Before(o Position) bool {
+	return p.Compare(o) < 0
+}
+
+// After returns whether p comes after o, see Compare
+func (p Position) After(o Position) bool {
+	return p.Compare(o) > 0
+}
@@ -0,0 +1,48 @@
+//go:build !tinygo
+
+package lexgo
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// StateLabel is the pprof label under which WithProfileLabels records the name
+// of the LexerFunc being run
+const StateLabel = "lexgo_state"
+
+// WithProfileLabels causes each LexerFunc to be run with a pprof label,
+// StateLabel, set to its name (e.g. "mylexer.lexString"), as pprof.Do would.
+// CPU profiles of programs which do a lot of lexing can then be broken down by
+// the states the time was spent in, using the -tagfocus or -tagshow flags of
+// go tool pprof, rather than it all being attributed to Next.
+//
+// Any labels on ctx are kept alongside StateLabel, and once the LexerFunc
+// returns the go-routine's labels are set back to those on ctx. Labels are
+// per go-routine, so ctx would normally be the one the surrounding code runs
+// with.
+func WithProfileLabels(ctx context.Context) Option {
+	return func(l *Lexer) {
+		l.profCtx = ctx
+		l.profStates = map[uintptr]context.Context{}
+	}
+}
+
+// runState runs the LexerFunc, labeled for pprof if WithProfileLabels was
+// given. The labeled context of each state is kept, so it only needs creating
+// once.
+func (l *Lexer) runState(fn LexerFunc) LexerFunc {
+	if l.profCtx == nil {
+		return fn(l)
+	}
+
+	pc := funcPC(fn)
+	ctx, ok := l.profStates[pc]
+	if !ok {
+		ctx = pprof.WithLabels(l.profCtx, pprof.Labels(StateLabel, pcName(pc)))
+		l.profStates[pc] = ctx
+	}
+	pprof.SetGoroutineLabels(ctx)
+	defer pprof.SetGoroutineLabels(l.profCtx)
+	return fn(l)
+}
@@ -0,0 +1,9 @@
+//go:build tinygo
+
+package lexgo
+
+// runState runs the LexerFunc. TinyGo doesn't support runtime/pprof, so
+// WithProfileLabels isn't available.
+func (l *Lexer) runState(fn LexerFunc) LexerFunc {
+	return fn(l)
+}
@@ -0,0 +1,131 @@
+package lexgo
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxAnchored is the most regexps anchored will hold at once
+const maxAnchored = 256
+
+// anchored caches the anchored versions of regexps given to MatchRegexp. Once
+// it holds maxAnchored of them it's emptied, so that regexps compiled on the
+// fly aren't held onto forever
+var anchored struct {
+	sync.Mutex
+	m map[*regexp.Regexp]*regexp.Regexp
+}
+
+func anchor(re *regexp.Regexp) *regexp.Regexp {
+	anchored.Lock()
+	defer anchored.Unlock()
+	if a, ok := anchored.m[re]; ok {
+		return a
+	} else if anchored.m == nil || len(anchored.m) >= maxAnchored {
+		anchored.m = map[*regexp.Regexp]*regexp.Regexp{}
+	}
+	a := regexp.MustCompile(`^(?:` + re.String() + `)`)
+	a.Longest()
+	anchored.m[re] = a
+	return a
+}
+
+// MatchRegexp checks if the upcoming input matches re. If it does the matched
+// text is read and buffered, and true is returned. Otherwise the stream is left
+// as it was and false is returned. The match is always anchored at the current
+// position and uses leftmost-longest semantics, regardless of how re was
+// written, so the occasional complex token shape (dates, version strings,
+// etc...) can be handled without converting the rest of the lexer. Empty
+// matches aren't considered a match.
+//
+// The match is done by looking ahead in the Lexer's internal buffer, so it can
+// be no longer than the buffer (4096 bytes, unless NewLexer was given a
+// bufio.Reader with a different size). When using LineContinuation or
+// BinaryMode, or if NewLexer was given an io.RuneScanner which isn't also an
+// io.Reader, the Lexer's lookahead ring is used instead (see PeekRuneN), which
+// has no such limit.
+//
+// The anchored copy of re is cached, but only for the most recently used few
+// hundred regexps. Lexers which compile many regexps at runtime, e.g. from
+// user supplied grammars, should anchor them themselves and match using
+// MatchFunc instead, as lexrule does.
+//
+// Follows the same error semantics as Accept().
+func (l *Lexer) MatchRegexp(re *regexp.Regexp) bool {
+	return l.MatchFunc(func(rr io.RuneReader) int {
+		if loc := anchor(re).FindReaderIndex(rr); loc != nil {
+			return loc[1]
+		}
+		return 0
+	})
+}
+
+// MatchFunc calls match with an io.RuneReader which reads ahead from the
+// current position, without consuming anything, and from which match can read
+// as much as it needs to decide how long the upcoming match is, in bytes. That
+// many bytes are then read and buffered, and true is returned. If match
+// returns zero or less the stream is left as it was and false is returned.
+//
+// This allows custom matchers (e.g. precompiled automata) to be used in the
+// same way as MatchRegexp, with the same limitations on how far ahead they
+// can look.
+func (l *Lexer) MatchFunc(match func(io.RuneReader) int) bool {
+	n := match(l.peekReader())
+	if n <= 0 {
+		return false
+	}
+
+	for n > 0 {
+		r, size, err := l.ReadRune()
+		if err != nil {
+			break
+		}
+		l.BufferRune(r)
+		n -= size
+	}
+	return true
+}
+
+// peekRuneReader implements io.RuneReader by looking further and further
+// ahead in the stream, without consuming anything. If br is set this is done
+// by peeking into the bufio.Reader, otherwise by using the Lexer's lookahead
+// ring
+type peekRuneReader struct {
+	br  *bufio.Reader
+	l   *Lexer
+	off int
+}
+
+// peekReader returns a peekRuneReader for the upcoming input, which peeks into
+// the bufio.Reader if canPeek allows it
+func (l *Lexer) peekReader() *peekRuneReader {
+	if l.canPeek() {
+		return &peekRuneReader{br: l.br}
+	}
+	return &peekRuneReader{l: l}
+}
+
+func (p *peekRuneReader) ReadRune() (rune, int, error) {
+	if p.br == nil {
+		i, r, size, err := p.l.aheadAt(p.off)
+		if err != nil {
+			return 0, 0, err
+		}
+		p.off = i + 1
+		return r, size, nil
+	}
+
+	b, err := p.br.Peek(p.off + utf8.UTFMax)
+	if len(b) <= p.off {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, 0, err
+	}
+	r, size := utf8.DecodeRune(b[p.off:])
+	p.off += size
+	return r, size, nil
+}
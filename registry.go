@@ -0,0 +1,120 @@
+package lexgo
+
+import (
+	"io"
+	"mime"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registration describes a lexer being registered with Register, so that
+// generic tools can find it by name, or by the file or content type it lexes
+type Registration struct {
+	// Name uniquely identifies the lexer, e.g. "markdown"
+	Name string
+
+	// Extensions are the file extensions, including the leading dot, of files
+	// the lexer handles, e.g. ".md". Whole file names which don't have an
+	// extension, e.g. "Makefile", may also be given. Matching is case
+	// insensitive
+	Extensions []string
+
+	// MIMETypes are the media types of content the lexer handles, e.g.
+	// "text/markdown"
+	MIMETypes []string
+
+	// New returns a Tokenizer which lexes r
+	New func(r io.Reader) Tokenizer
+
+	// TypeName optionally returns the name of each TokenType the lexer
+	// produces
+	TypeName func(TokenType) string
+}
+
+var registry = struct {
+	sync.RWMutex
+	byName, byExt, byMIME map[string]*Registration
+}{
+	byName: map[string]*Registration{},
+	byExt:  map[string]*Registration{},
+	byMIME: map[string]*Registration{},
+}
+
+// Register makes a lexer available to Lookup, LookupFilename and LookupMIME.
+// It's intended to be called from the init function of packages implementing
+// lexers. If an extension or MIME type has already been claimed by another
+// lexer then the one registered last gets it, so applications can override
+// the choices made by packages they import.
+//
+// Register panics if the Registration has no Name or New function, or if its
+// Name is already registered.
+func Register(reg Registration) {
+	if reg.Name == "" || reg.New == nil {
+		panic("lexgo: Register called with no Name or New")
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.byName[reg.Name]; ok {
+		panic("lexgo: Register called twice for lexer " + reg.Name)
+	}
+	r := &reg
+	registry.byName[reg.Name] = r
+	for _, ext := range reg.Extensions {
+		registry.byExt[strings.ToLower(ext)] = r
+	}
+	for _, mt := range reg.MIMETypes {
+		registry.byMIME[strings.ToLower(mt)] = r
+	}
+}
+
+// Lookup returns the registered lexer with the given name
+func Lookup(name string) (Registration, bool) {
+	return lookup(registry.byName, name)
+}
+
+// LookupFilename returns the registered lexer which handles the given file,
+// based on its extension or, failing that, its whole name. Only the last
+// element of the path is considered.
+func LookupFilename(path string) (Registration, bool) {
+	base := strings.ToLower(filepath.Base(path))
+	if ext := filepath.Ext(base); ext != "" {
+		if reg, ok := lookup(registry.byExt, ext); ok {
+			return reg, true
+		}
+	}
+	return lookup(registry.byExt, base)
+}
+
+// LookupMIME returns the registered lexer which handles the given media type.
+// Parameters, e.g. "; charset=utf-8", are ignored.
+func LookupMIME(mimeType string) (Registration, bool) {
+	mt, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return Registration{}, false
+	}
+	return lookup(registry.byMIME, mt)
+}
+
+func lookup(m map[string]*Registration, key string) (Registration, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if r, ok := m[key]; ok {
+		return *r, true
+	}
+	return Registration{}, false
+}
+
+// Registered returns all registered lexers, sorted by Name
+func Registered() []Registration {
+	registry.RLock()
+	defer registry.RUnlock()
+	regs := make([]Registration, 0, len(registry.byName))
+	for _, r := range registry.byName {
+		regs = append(regs, *r)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].Name < regs[j].Name })
+	return regs
+}
@@ -0,0 +1,135 @@
+package lexgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MarshalText implements encoding.TextMarshaler, so that Severity is encoded as
+// its String form
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, and is the inverse of
+// MarshalText
+func (s *Severity) UnmarshalText(b []byte) error {
+	for _, sev := range []Severity{SeverityError, SeverityWarning, SeverityInfo, SeverityHint} {
+		if sev.String() == string(b) {
+			*s = sev
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", b)
+}
+
+// ReportPos is a position within a file, as used in a Report
+type ReportPos struct {
+	Row    int `json:"row"`
+	Col    int `json:"col"`
+	Offset int `json:"offset"`
+}
+
+// ReportRange is the range of a file a ReportEntry applies to. End is
+// exclusive
+type ReportRange struct {
+	Start ReportPos `json:"start"`
+	End   ReportPos `json:"end"`
+}
+
+// ReportEntry describes a single Diagnostic within a Report
+type ReportEntry struct {
+	File       string      `json:"file"`
+	Severity   Severity    `json:"severity"`
+	Code       string      `json:"code,omitempty"`
+	Message    string      `json:"message"`
+	Range      ReportRange `json:"range"`
+	Suggestion string      `json:"suggestion,omitempty"`
+}
+
+// Report gathers the diagnostics produced by one or more lex runs into a
+// single machine-readable form, which can be written out as JSON using Encode.
+// This allows tools such as CI systems to consume lexer findings without
+// scraping formatted error strings.
+type Report struct {
+	Entries []ReportEntry `json:"diagnostics"`
+}
+
+// Add adds an entry to the Report for the given Err or Warning Token, which was
+// produced from the given file. If the Token's error is a *Diagnostic then the
+// entry is taken from it, otherwise an entry with no code is created using the
+// error's message and the Token's position. Add does nothing for any other
+// kind of Token, or for an Err Token of io.EOF.
+func (r *Report) Add(file string, tok *Token) {
+	err := tok.Err
+	sev := SeverityError
+	if err == nil {
+		err, sev = tok.Warn, SeverityWarning
+	}
+	if err == nil || err == io.EOF {
+		return
+	}
+
+	var diag *Diagnostic
+	if !errors.As(err, &diag) {
+		pos := ReportPos{Row: tok.Row, Col: tok.Col, Offset: tok.Offset}
+		r.Entries = append(r.Entries, ReportEntry{
+			File:     file,
+			Severity: sev,
+			Message:  err.Error(),
+			Range:    ReportRange{Start: pos, End: pos},
+		})
+		return
+	}
+
+	r.Entries = append(r.Entries, ReportEntry{
+		File:     file,
+		Severity: diag.Severity,
+		Code:     diag.Code,
+		Message:  diag.Message,
+		Range: ReportRange{
+			Start: ReportPos{Row: diag.Row, Col: diag.Col, Offset: diag.Offset},
+			End:   ReportPos{Row: diag.EndRow, Col: diag.EndCol, Offset: diag.EndOffset},
+		},
+		Suggestion: diag.Suggestion,
+	})
+}
+
+// Collect reads Tokens from t until the stream ends, adding an entry to the
+// Report for every Err and Warning Token encountered (see Add). file is the
+// name of the file being lexed. It returns the number of entries added.
+func (r *Report) Collect(file string, t Tokenizer) int {
+	before := len(r.Entries)
+	for {
+		tok := t.Next()
+		r.Add(file, tok)
+		done := tok.EndsStream()
+		tok.Release()
+		if done {
+			return len(r.Entries) - before
+		}
+	}
+}
+
+// HasErrors returns whether any entry in the Report has SeverityError
+func (r *Report) HasErrors() bool {
+	for _, e := range r.Entries {
+		if e.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode writes the Report to w as indented JSON
+func (r *Report) Encode(w io.Writer) error {
+	if r.Entries == nil {
+		// encode as an empty list rather than null
+		r = &Report{Entries: []ReportEntry{}}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,37 @@
+package lexgo
+
+// ResumeAfterError causes recoverable errors encountered while reading, such as
+// invalid utf8, to not be returned from ReadRune() and PeekRune(). Instead the
+// offending input is skipped over and the next rune is returned, with the
+// LexerFunc's state left intact, so that a single bad character doesn't end the
+// stream.
+//
+// The errors are still emitted as Err Tokens, in order with the rest of the
+// Tokens, but with Recoverable set. Since the stream is not over when one of
+// these is returned, consumers should continue calling Next() after receiving
+// one, up until io.EOF or some other non-recoverable error. The helpers in this
+// package (Tokens, Run, Scanner, etc...) all do so.
+//
+// Errors which LexerFuncs encounter themselves (an invalid character, for
+// example) can be handled in the same way by calling EmitRecoverableErr() and
+// returning the next LexerFunc as usual, rather than returning nil.
+func ResumeAfterError() Option {
+	return func(l *Lexer) {
+		l.resume = true
+	}
+}
+
+// EmitRecoverableErr is like EmitErr, except that the Err Token has Recoverable
+// set, indicating to consumers that the stream continues after it. The
+// LexerFunc should carry on lexing after calling this, rather than returning
+// nil.
+func (l *Lexer) EmitRecoverableErr(err error) {
+	l.EmitErr(err)
+	l.queue[len(l.queue)-1].Recoverable = true
+}
+
+// EndsStream returns true if the Token is an Err Token which ends the stream,
+// i.e. one which isn't Recoverable.
+func (t *Token) EndsStream() bool {
+	return t.Err != nil && !t.Recoverable
+}
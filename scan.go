@@ -0,0 +1,214 @@
+package lexgo
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// buffered returns whatever bytes are currently sitting in the bufio.Reader's
+// buffer, filling it first if it's empty. If the buffer can't be filled the
+// error is held onto for the next read. If the Lexer isn't reading from a
+// bufio.Reader, is using LineContinuation or BinaryMode, or has runes in its
+// lookahead ring, then this returns nil
+func (l *Lexer) buffered() []byte {
+	l.commitRead()
+	if l.heldErr != nil || l.br == nil || l.cont != "" || l.binary || l.ahead.n > 0 {
+		return nil
+	}
+	if l.br.Buffered() == 0 {
+		if _, err := l.br.Peek(1); err != nil {
+			l.heldErr = err
+			return nil
+		}
+	}
+	b, _ := l.br.Peek(l.br.Buffered())
+	return b
+}
+
+// canPeek returns whether it's possible to look ahead in the stream by peeking
+// at l.br, which requires that the Lexer can read from a bufio.Reader (see
+// bufferReader), isn't using LineContinuation or BinaryMode, and has nothing in
+// its lookahead ring
+func (l *Lexer) canPeek() bool {
+	l.commitRead()
+	if l.heldErr != nil || l.cont != "" || l.binary || l.ahead.n > 0 {
+		return false
+	}
+	return l.br != nil || l.bufferReader()
+}
+
+// peekBytes returns up to the next n bytes of the stream without reading them,
+// or nil if canPeek is false or nothing more can be read. Any error is left for
+// the next read to encounter
+func (l *Lexer) peekBytes(n int) []byte {
+	if !l.canPeek() {
+		return nil
+	}
+	b, _ := l.br.Peek(n)
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+// bufferReader switches the Lexer over to reading through a bufio.Reader, for
+// when NewLexer was given an io.RuneScanner (e.g. a strings.Reader) but more
+// than one rune of lookahead is needed. This is only possible if the
+// io.RuneScanner is also an io.Reader, false is returned if it isn't.
+// commitRead must have been called beforehand, since the new reader can't
+// unread anything read before it.
+func (l *Lexer) bufferReader() bool {
+	r, ok := l.r.(io.Reader)
+	if !ok {
+		return false
+	}
+	l.br = bufio.NewReader(r)
+	l.r, l.bs = l.br, l.br
+	return true
+}
+
+// validPrefix returns the longest prefix of b which consists of only complete,
+// valid utf8 characters
+func validPrefix(b []byte) []byte {
+	if utf8.Valid(b) {
+		return b
+	}
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return b[:i]
+		}
+		i += size
+	}
+	return b
+}
+
+// SkipWhile reads and discards runes for as long as pred returns true for
+// them, returning the number of runes skipped. Rather than going rune-by-rune
+// through ReadRune() this works directly on the Lexer's internal buffer, which
+// makes it considerably cheaper for long runs (this only applies if the Lexer
+// is reading from a bufio.Reader, which is the case unless NewLexer was given
+// some other io.RuneScanner). Follows the same error semantics
+// as Accept().
+func (l *Lexer) SkipWhile(pred func(rune) bool) int {
+	var n int
+	for {
+		b := validPrefix(l.buffered())
+		if len(b) == 0 {
+			break
+		}
+
+		var i int
+		for i < len(b) {
+			r, size := rune(b[i]), 1
+			if r >= utf8.RuneSelf {
+				r, size = utf8.DecodeRune(b[i:])
+			}
+			if !pred(r) {
+				break
+			}
+			l.advance(r, size)
+			i += size
+			n++
+		}
+		l.br.Discard(i)
+
+		if i < len(b) {
+			break
+		}
+	}
+
+	// If the buffer didn't end with a complete, valid rune then it needs to be
+	// handled the slow way
+	for {
+		r, err := l.peekRune()
+		if err != nil || !pred(r) {
+			return n
+		}
+		l.ReadRune()
+		n++
+	}
+}
+
+// asciiSpace is a lookup table for the ASCII characters unicode.IsSpace
+// considers whitespace
+var asciiSpace = [utf8.RuneSelf]bool{
+	'\t': true, '\n': true, '\v': true, '\f': true, '\r': true, ' ': true,
+}
+
+func isSpace(r rune) bool {
+	if r < utf8.RuneSelf {
+		return asciiSpace[r]
+	}
+	return unicode.IsSpace(r)
+}
+
+// SkipWhitespace is equivalent to SkipWhile(unicode.IsSpace), but with a faster
+// check for ASCII whitespace
+func (l *Lexer) SkipWhitespace() int {
+	return l.SkipWhile(isSpace)
+}
+
+// ReadUntil reads and buffers runes up until, but not including, the first one
+// which is in delims, returning the number of runes buffered. The search for
+// the delimiter is done directly on the Lexer's internal buffer using the
+// stdlib's optimized byte searching functions, which makes this much faster
+// than a loop of ReadRune() calls for long runs (with the same caveat as in
+// SkipWhile). Follows the same error
+// semantics as Accept().
+func (l *Lexer) ReadUntil(delims string) int {
+	var n int
+
+	// The first rune is done the normal way so that the starting position of
+	// the buffered data is set correctly
+	r, err := l.peekRune()
+	if err != nil || strings.ContainsRune(delims, r) {
+		return 0
+	}
+	l.ReadRune()
+	l.BufferRune(r)
+	n++
+
+	for {
+		b := validPrefix(l.buffered())
+		if len(b) == 0 {
+			break
+		}
+
+		var i int
+		if len(delims) == 1 && delims[0] < utf8.RuneSelf {
+			i = bytes.IndexByte(b, delims[0])
+		} else {
+			i = bytes.IndexAny(b, delims)
+		}
+
+		if i < 0 {
+			i = len(b)
+		}
+		l.outbuf = append(l.outbuf, b[:i]...)
+		for _, r := range string(b[:i]) {
+			l.advance(r, utf8.RuneLen(r))
+			n++
+		}
+		l.br.Discard(i)
+
+		if i < len(b) {
+			return n
+		}
+	}
+
+	// Buffer didn't end with a complete, valid rune, do the rest the slow way
+	for {
+		r, err := l.peekRune()
+		if err != nil || strings.ContainsRune(delims, r) {
+			return n
+		}
+		l.ReadRune()
+		l.BufferRune(r)
+		n++
+	}
+}
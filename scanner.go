@@ -0,0 +1,80 @@
+package lexgo
+
+import (
+	"errors"
+	"io"
+)
+
+// Scanner wraps a Tokenizer with an interface like that of bufio.Scanner, for
+// those who would rather not deal with errors being embedded in Tokens:
+//
+//	s := lexgo.NewScanner(l)
+//	for s.Scan() {
+//		t := s.Token()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	t    Tokenizer
+	tok  *Token
+	errs []error
+	done bool
+}
+
+// NewScanner returns a Scanner which will read Tokens from the given Tokenizer
+func NewScanner(t Tokenizer) *Scanner {
+	return &Scanner{t: t}
+}
+
+// Scan advances the Scanner to the next Token, which will then be available
+// through the Token method. It returns false once the stream has ended, either
+// by reaching io.EOF or by an error, after which Err should be checked.
+//
+// If the underlying Tokenizer is a Lexer using WithTokenPool then the Token
+// from the previous call is released, so Tokens should be copied if they're
+// needed beyond the next call to Scan
+func (s *Scanner) Scan() bool {
+	if s.tok != nil {
+		s.tok.Release()
+		s.tok = nil
+	}
+	if s.done {
+		return false
+	}
+
+	tok := s.t.Next()
+	for tok.Recoverable {
+		s.errs = append(s.errs, tok.Err)
+		tok.Release()
+		tok = s.t.Next()
+	}
+	if tok.Err != nil {
+		s.done = true
+		if tok.Err != io.EOF {
+			s.errs = append(s.errs, tok.Err)
+		}
+		tok.Release()
+		return false
+	}
+
+	s.tok = tok
+	return true
+}
+
+// Token returns the most recent Token read by Scan. It will never be an Err
+// Token
+func (s *Scanner) Token() *Token {
+	return s.tok
+}
+
+// Err returns the error which ended the stream, or nil if it ended with
+// io.EOF. Any Recoverable errors which were skipped over by Scan (see
+// ResumeAfterError) are joined into it.
+func (s *Scanner) Err() error {
+	if len(s.errs) == 1 {
+		return s.errs[0]
+	}
+	return errors.Join(s.errs...)
+}
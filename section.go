@@ -0,0 +1,43 @@
+package lexgo
+
+import (
+	"bufio"
+	"io"
+)
+
+// NewSectionLexer is like NewLexer, but lexes only the bytes in the range
+// [start, end) of the given io.ReaderAt. This allows tools to re-tokenize a
+// single region of a large file (e.g. one function which has been edited)
+// without lexing everything before it.
+//
+// The Lexer's position is seeded such that Tokens are reported at their
+// positions within the whole of ra. Doing so requires scanning the bytes
+// preceding start for line breaks, though this is much cheaper than lexing
+// them. If the row and column at start are already known, or positions aren't
+// needed, then it's cheaper to use NewLexer with an io.SectionReader and
+// SetStartPosition instead.
+func NewSectionLexer(ra io.ReaderAt, start, end int64, firstFunc LexerFunc, opts ...Option) *Lexer {
+	l := NewLexer(io.NewSectionReader(ra, start, end-start), firstFunc, opts...)
+	if !l.noPos {
+		l.seekPos(io.NewSectionReader(ra, 0, start))
+	}
+	return l
+}
+
+// seekPos advances the Lexer's position past everything in r, without
+// otherwise reading it
+func (l *Lexer) seekPos(r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		r, size, err := br.ReadRune()
+		if err != nil {
+			if l.lines != nil {
+				l.lines.reset(l.cur)
+			}
+			return
+		}
+		l.absOff = l.nextOff
+		l.nextOff += size
+		l.cur = l.tracker.Advance(l.cur, r, size, l.IsNewline(r))
+	}
+}
@@ -0,0 +1,71 @@
+package lexgo
+
+import (
+	"io"
+)
+
+// Splitter groups the Tokens of a stream into logical units, such as
+// statements separated by semicolon or newline Tokens, or records separated by
+// a record separator Token.
+type Splitter struct {
+	t      Tokenizer
+	delims map[TokenType]bool
+	err    error
+
+	// KeepDelims causes the delimiting Token to be included as the last Token
+	// of each unit, rather than being discarded
+	KeepDelims bool
+
+	// KeepEmpty causes empty units (i.e. two delimiters in a row) to be
+	// returned, rather than skipped
+	KeepEmpty bool
+}
+
+// NewSplitter returns a Splitter which reads from t, and splits units on any
+// of the given TokenTypes
+func NewSplitter(t Tokenizer, delims ...TokenType) *Splitter {
+	s := &Splitter{
+		t:      t,
+		delims: make(map[TokenType]bool, len(delims)),
+	}
+	for _, d := range delims {
+		s.delims[d] = true
+	}
+	return s
+}
+
+// Next returns the Tokens making up the next unit in the stream. When the
+// stream ends whatever Tokens have been read since the last delimiter make up
+// the final unit. After that nil is returned along with the error which ended
+// the stream, which is io.EOF if it ended normally. Recoverable Err Tokens are
+// included in units like any other non-delimiter Token.
+func (s *Splitter) Next() ([]Token, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var unit []Token
+	for {
+		tok := s.t.Next()
+		cp := tok.Copy()
+		tok.Release()
+
+		if cp.EndsStream() {
+			s.err = cp.Err
+			if len(unit) > 0 && cp.Err == io.EOF {
+				return unit, nil
+			}
+			return nil, s.err
+		} else if !s.delims[cp.TokenType] {
+			unit = append(unit, cp)
+			continue
+		}
+
+		if s.KeepDelims {
+			unit = append(unit, cp)
+		}
+		if len(unit) > 0 || s.KeepEmpty {
+			return unit, nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package lexgo
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// StateProfile accumulates how often each LexerFunc is run and how long it
+// takes, across all Lexers it's given to (see WithStateProfile), so that the
+// states worth optimizing can be found. Like Validator, a single StateProfile
+// may be shared by many Lexers concurrently, and states are identified by their
+// underlying function.
+//
+// Go has no way of measuring CPU time per go-routine, so the times recorded are
+// wall times. These match CPU time as long as the input is in memory, as it
+// normally is when benchmarking, but include time spent waiting on the
+// io.Reader otherwise. For a CPU based breakdown see WithProfileLabels.
+type StateProfile struct {
+	l      sync.Mutex
+	states map[uintptr]*StateTiming
+}
+
+// StateTiming describes the runs of a single LexerFunc recorded by a
+// StateProfile
+type StateTiming struct {
+	// Name is the name of the LexerFunc, as for StepResult
+	Name string
+
+	// Calls is the number of times the LexerFunc was run, Time the total time
+	// spent running it, and Tokens the number of Tokens it emitted
+	Calls  int
+	Time   time.Duration
+	Tokens int
+}
+
+// Avg returns the average time the LexerFunc took per call
+func (s StateTiming) Avg() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Time / time.Duration(s.Calls)
+}
+
+// NewStateProfile returns an empty StateProfile
+func NewStateProfile() *StateProfile {
+	return &StateProfile{states: map[uintptr]*StateTiming{}}
+}
+
+// WithStateProfile causes the Lexer to record the time taken by every state it
+// runs to the given StateProfile. This costs a couple of calls to time.Now on
+// every state transition, which is small compared to most states, but makes it
+// intended for profiling rather than production use.
+func WithStateProfile(p *StateProfile) Option {
+	return func(l *Lexer) {
+		l.stateProf = p
+	}
+}
+
+func (p *StateProfile) record(pc uintptr, d time.Duration, tokens int) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	s, ok := p.states[pc]
+	if !ok {
+		s = &StateTiming{}
+		p.states[pc] = s
+	}
+	s.Calls++
+	s.Time += d
+	s.Tokens += tokens
+}
+
+// States returns the timing of every state run so far, the one which took the
+// most time in total first
+func (p *StateProfile) States() []StateTiming {
+	p.l.Lock()
+	defer p.l.Unlock()
+	states := make([]StateTiming, 0, len(p.states))
+	for pc, s := range p.states {
+		st := *s
+		st.Name = pcName(pc)
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Time != states[j].Time {
+			return states[i].Time > states[j].Time
+		}
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// Reset discards everything recorded so far, e.g. to leave out a warm-up
+func (p *StateProfile) Reset() {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.states = map[uintptr]*StateTiming{}
+}
+
+// WriteReport writes the timing of every state run so far to w as a table,
+// ranked as by States, followed by the totals:
+//
+//	RANK  STATE              CALLS  TIME    SHARE  AVG      TOKENS
+//	1     mylexer.lexString  1200   3.21ms  61.0%  2.675µs  1200
+//	2     mylexer.lexAny     5230   2.05ms  39.0%  391ns    4030
+//
+//	total 5.26ms in 6430 calls
+func (p *StateProfile) WriteReport(w io.Writer) error {
+	states := p.States()
+	var total time.Duration
+	var calls int
+	for _, s := range states {
+		total += s.Time
+		calls += s.Calls
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tSTATE\tCALLS\tTIME\tSHARE\tAVG\tTOKENS")
+	for i, s := range states {
+		var share float64
+		if total > 0 {
+			share = 100 * float64(s.Time) / float64(total)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%.1f%%\t%s\t%d\n",
+			i+1, s.Name, s.Calls, s.Time, share, s.Avg(), s.Tokens)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\ntotal %s in %d calls\n", total, calls)
+	return err
+}
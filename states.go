@@ -0,0 +1,154 @@
+package lexgo
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// StateID identifies a state within a StateTable
+type StateID int
+
+// StateEnd is returned by a StateFunc to indicate that lexing is done, like a
+// LexerFunc returning nil. It's never used to identify a state itself
+const StateEnd StateID = 0
+
+// A StateFunc is the StateTable equivalent of a LexerFunc, it returns the
+// StateID of the state to be run next rather than the state itself
+type StateFunc func(*Lexer) StateID
+
+// stateBatch is the most StateFuncs which runStates will run in one go before
+// returning, so that WithStallLimit and WithMaxSteps still get a chance to stop
+// a lexer which is stuck in a loop
+const stateBatch = 64
+
+// StateTable is an alternative to chaining LexerFuncs, for use in the hottest
+// lexers. States are identified by small integer StateIDs, generally declared
+// as constants, and rather than each state returning the next LexerFunc,
+// which the Lexer then calls indirectly, it returns the next StateID, which
+// the Lexer looks up in the table. Runs of states which don't emit Tokens are
+// also run in a single loop, rather than each going through Next.
+//
+// If Switch is set it's used to run states instead of Funcs, which allows for
+// the states to be called directly from a switch statement, and so inlined by
+// the compiler. Such a function can be written by hand or generated using
+// WriteSwitch.
+//
+// A StateTable isn't modified by the Lexers using it, and so it can be shared
+// between any number of them. Since StateFuncs return IDs rather than
+// referencing each other the table can be a package-level variable without
+// causing an initialization cycle:
+//
+//	const (
+//		stateAny lexgo.StateID = iota + 1
+//		stateNumber
+//	)
+//
+//	var states = &lexgo.StateTable{
+//		Funcs: []lexgo.StateFunc{stateAny: lexAny, stateNumber: lexNumber},
+//		Names: []string{stateAny: "lexAny", stateNumber: "lexNumber"},
+//	}
+//
+// LexerFuncs remain the default and more flexible way of writing lexers, e.g.
+// combinators like Then and closures holding state can't be used with a
+// StateTable. Tooling which works per LexerFunc, such as Validator and
+// StateProfile, sees a StateTable as a single state.
+type StateTable struct {
+	// Funcs holds the StateFunc for each StateID, indexed by the StateID.
+	// Index zero, StateEnd, is never used
+	Funcs []StateFunc
+
+	// Names optionally holds a name for each StateID, indexed by the StateID,
+	// which is used by Step and State, and which WriteSwitch uses as the
+	// expression to call for each state. The StateFunc's function name is
+	// used for any StateID not given one
+	Names []string
+
+	// Switch, if set, runs the state with the given StateID and returns the
+	// one to be run next, instead of Funcs being used
+	Switch func(*Lexer, StateID) StateID
+}
+
+// NewStateLexer returns a Lexer which reads from r and runs the states of the
+// StateTable, starting with the one identified by start. It's otherwise the
+// same as a Lexer returned from NewLexer, and takes the same Options.
+func NewStateLexer(r io.Reader, t *StateTable, start StateID, opts ...Option) *Lexer {
+	l := NewLexer(r, runStates, opts...)
+	l.stateTable, l.stateID = t, start
+	return l
+}
+
+// run runs the state identified by id and returns the next one
+func (t *StateTable) run(l *Lexer, id StateID) StateID {
+	if t.Switch != nil {
+		return t.Switch(l, id)
+	} else if id < 0 || int(id) >= len(t.Funcs) || t.Funcs[id] == nil {
+		l.EmitErr(fmt.Errorf("lexgo: no state in StateTable for StateID %d", id))
+		return StateEnd
+	}
+	return t.Funcs[id](l)
+}
+
+// name returns the name of the state identified by id
+func (t *StateTable) name(id StateID) string {
+	if int(id) < len(t.Names) && id > 0 && t.Names[id] != "" {
+		return t.Names[id]
+	} else if int(id) < len(t.Funcs) && id > 0 && t.Funcs[id] != nil {
+		return pcName(stateFuncPC(t.Funcs[id]))
+	}
+	return "state" + strconv.Itoa(int(id))
+}
+
+// runStates is the LexerFunc used by Lexers created by NewStateLexer. It runs
+// states from the Lexer's StateTable until one emits a Token, and returns
+// itself until they're done
+func runStates(l *Lexer) LexerFunc {
+	t, queued := l.stateTable, len(l.queue)
+	batch := stateBatch
+	if l.stepOne {
+		batch = 1
+	}
+	for n := 0; n < batch; n++ {
+		if l.stateID = t.run(l, l.stateID); l.stateID == StateEnd {
+			return nil
+		} else if len(l.queue) != queued {
+			break
+		}
+	}
+	return runStates
+}
+
+// WriteSwitch writes the source of a function called funcName to w, which can
+// be used as the StateTable's Switch. Names must be set for every StateID
+// which has a StateFunc, and each is used as the Go expression for the
+// function to call for that state, e.g. "lexNumber". The result looks like:
+//
+//	func switchStates(l *lexgo.Lexer, id lexgo.StateID) lexgo.StateID {
+//		switch id {
+//		case 1:
+//			return lexAny(l)
+//		case 2:
+//			return lexNumber(l)
+//		}
+//		return lexgo.StateEnd
+//	}
+//
+// The generated function ends lexing if given a StateID it doesn't know, so it
+// must be regenerated whenever states are added.
+func (t *StateTable) WriteSwitch(w io.Writer, funcName string) error {
+	if _, err := fmt.Fprintf(w, "func %s(l *lexgo.Lexer, id lexgo.StateID) lexgo.StateID {\n\tswitch id {\n", funcName); err != nil {
+		return err
+	}
+	for id, fn := range t.Funcs {
+		if id == int(StateEnd) || fn == nil {
+			continue
+		} else if id >= len(t.Names) || t.Names[id] == "" {
+			return fmt.Errorf("lexgo: no name given for StateID %d", id)
+		}
+		if _, err := fmt.Fprintf(w, "\tcase %d:\n\t\treturn %s(l)\n", id, t.Names[id]); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprint(w, "\t}\n\treturn lexgo.StateEnd\n}\n")
+	return err
+}
@@ -0,0 +1,181 @@
+package lexgo
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TypeStats describes the Tokens of a single TokenType gathered by Stats
+type TypeStats struct {
+	Count int
+
+	// Bytes is the total length of the Tokens' Raw values, and MinLen and
+	// MaxLen the shortest and longest of them
+	Bytes          int
+	MinLen, MaxLen int
+
+	// Lengths is a histogram of the lengths of the Tokens' Raw values, in
+	// powers of two. Lengths[0] counts empty Tokens, and Lengths[i] those
+	// whose length is at least 2^(i-1) and less than 2^i
+	Lengths []int
+}
+
+// AvgLen returns the average length of the Tokens' Raw values
+func (s *TypeStats) AvgLen() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Bytes) / float64(s.Count)
+}
+
+// Stats gathers statistics about the Tokens produced by one or more lex runs,
+// such as how many of each TokenType there are and how long they are. This is
+// useful for analyzing a corpus, and for spotting which parts of a grammar are
+// hit the hardest.
+type Stats struct {
+	// Types holds the stats of each TokenType seen, other than Err and
+	// Warning
+	Types map[TokenType]*TypeStats
+
+	// Errors is the number of Err Tokens seen, not counting those of io.EOF
+	// which end streams, and Warnings the number of Warning Tokens seen
+	Errors, Warnings int
+
+	// Duration is the time spent waiting for Tokens by Collect
+	Duration time.Duration
+}
+
+// Add adds the given Token to the Stats
+func (s *Stats) Add(tok *Token) {
+	switch tok.TokenType {
+	case Err:
+		if tok.Err != io.EOF {
+			s.Errors++
+		}
+		return
+	case Warning:
+		s.Warnings++
+		return
+	}
+
+	if s.Types == nil {
+		s.Types = map[TokenType]*TypeStats{}
+	}
+	ts := s.Types[tok.TokenType]
+	if ts == nil {
+		ts = &TypeStats{MinLen: len(tok.Raw)}
+		s.Types[tok.TokenType] = ts
+	}
+
+	n := len(tok.Raw)
+	ts.Count++
+	ts.Bytes += n
+	if n < ts.MinLen {
+		ts.MinLen = n
+	}
+	if n > ts.MaxLen {
+		ts.MaxLen = n
+	}
+	bucket := bits.Len(uint(n))
+	for len(ts.Lengths) <= bucket {
+		ts.Lengths = append(ts.Lengths, 0)
+	}
+	ts.Lengths[bucket]++
+}
+
+// Collect reads Tokens from t until the stream ends, adding each to the Stats
+// (see Add), and adding the time spent waiting on t to Duration. It returns
+// the number of Tokens read.
+func (s *Stats) Collect(t Tokenizer) int {
+	var n int
+	for {
+		start := time.Now()
+		tok := t.Next()
+		s.Duration += time.Since(start)
+
+		s.Add(tok)
+		n++
+		done := tok.EndsStream()
+		tok.Release()
+		if done {
+			return n
+		}
+	}
+}
+
+// Tokens returns the total number of Tokens seen, other than Err and Warning
+// Tokens
+func (s *Stats) Tokens() int {
+	var n int
+	for _, ts := range s.Types {
+		n += ts.Count
+	}
+	return n
+}
+
+// sparks are used to draw the Lengths histograms of TypeStats
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// WriteTable writes the Stats to w as a table, with a row for each TokenType
+// in descending order of Count, followed by a summary line. typeName is used
+// to name the TokenTypes, and may be nil. The LENGTHS column draws each
+// TokenType's Lengths histogram, with each character standing for one power of
+// two.
+func (s *Stats) WriteTable(w io.Writer, typeName func(TokenType) string) error {
+	types := make([]TokenType, 0, len(s.Types))
+	for t := range s.Types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		a, b := s.Types[types[i]], s.Types[types[j]]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return types[i] < types[j]
+	})
+
+	total := s.Tokens()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tCOUNT\tSHARE\tAVG LEN\tMIN\tMAX\tLENGTHS")
+	for _, t := range types {
+		ts := s.Types[t]
+		var name string
+		if typeName != nil {
+			name = typeName(t)
+		}
+		if name == "" {
+			name = fmt.Sprint(int(t))
+		}
+
+		var maxBucket int
+		for _, n := range ts.Lengths {
+			if n > maxBucket {
+				maxBucket = n
+			}
+		}
+		var hist strings.Builder
+		for _, n := range ts.Lengths {
+			if n == 0 {
+				hist.WriteRune(' ')
+				continue
+			}
+			hist.WriteRune(sparks[(n*len(sparks)-1)/maxBucket])
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%.1f\t%d\t%d\t%s\n",
+			name, ts.Count, 100*float64(ts.Count)/float64(total),
+			ts.AvgLen(), ts.MinLen, ts.MaxLen, hist.String())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d tokens, %d errors, %d warnings, in %s\n",
+		total, s.Errors, s.Warnings, s.Duration)
+	return err
+}
@@ -0,0 +1,81 @@
+package lexgo
+
+import "io"
+
+// StepResult describes a single run of a LexerFunc, see Step
+type StepResult struct {
+	// State is the name of the LexerFunc which was run, and Next the name of
+	// the one it returned, or "" if it returned nil. Names are as for
+	// Validator, e.g. "mylexer.lexString"
+	State, Next string
+
+	// Tokens are the Tokens emitted while State ran, in order
+	Tokens []Token
+}
+
+// Step runs the Lexer's current LexerFunc and returns what it did. It's an
+// alternative to Next, intended for debuggers and tracing tools, which lets
+// state transitions be followed one at a time. The Tokens emitted are taken
+// off the Lexer's queue and returned as part of the StepResult, so Step and
+// Next shouldn't be mixed. For a Lexer created by NewStateLexer each Step runs
+// a single state of its StateTable.
+//
+// Once the Lexer has no state left to run a StepResult holding only the final
+// io.EOF Token is returned, along with false. Stepping past an Err Token which
+// ends the stream (see Token.EndsStream) is possible, but not useful.
+func (l *Lexer) Step() (StepResult, bool) {
+	l.nextL.Lock()
+	defer l.nextL.Unlock()
+
+	var res StepResult
+	ok := l.state != nil
+	if !ok {
+		l.commitRead()
+		l.EmitErr(io.EOF)
+	} else {
+		res.State = l.State()
+		l.stepOne = true
+		if l.state = l.step(l.state); l.state == nil {
+			l.async.stop()
+		} else {
+			res.Next = l.State()
+		}
+		l.stepOne = false
+	}
+
+	res.Tokens = append(res.Tokens, l.queue[l.queueHead:]...)
+	for i := range l.queue {
+		l.queue[i] = Token{}
+	}
+	l.queue, l.queueHead = l.queue[:0], 0
+	return res, ok
+}
+
+// State returns the name of the LexerFunc which the Lexer will run next, as
+// for StepResult, or "" if it has none left to run. For a Lexer created by
+// NewStateLexer this is the name of the state within its StateTable.
+func (l *Lexer) State() string {
+	if l.state == nil {
+		return ""
+	} else if l.stateTable != nil {
+		return l.stateTable.name(l.stateID)
+	}
+	return pcName(funcPC(l.state))
+}
+
+// NextPos returns the position of the next rune to be read, in the same terms
+// as the positions of Tokens
+func (l *Lexer) NextPos() (row, col, offset int) {
+	return l.nextPos()
+}
+
+// BufferPos returns the position at which the contents of the output buffer
+// (see BufferString) started, i.e. that which the next Token emitted will
+// have, or false if nothing is buffered
+func (l *Lexer) BufferPos() (row, col, offset int, ok bool) {
+	if l.row < 0 || l.col < 0 {
+		return 0, 0, 0, false
+	}
+	row, col = l.reportPos(l.row, l.col)
+	return row, col, l.off, true
+}
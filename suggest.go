@@ -0,0 +1,96 @@
+package lexgo
+
+import "fmt"
+
+// Suggest returns whichever of candidates is closest to word, for use in "did
+// you mean" messages about unknown keywords, directives and the like.
+// Closeness is measured by edit distance, counting insertions, deletions,
+// substitutions and transpositions of runes, where runes which differ only in
+// case are the same. Only candidates within a third of word's length (at
+// least 1) are considered, and if more than one is equally close the first is
+// returned. False is returned if no candidate is close enough.
+func Suggest(word string, candidates []string) (string, bool) {
+	limit := len([]rune(word)) / 3
+	if limit < 1 {
+		limit = 1
+	}
+
+	best, bestDist := "", limit+1
+	for _, c := range candidates {
+		if d := editDistance(word, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best, bestDist <= limit
+}
+
+// Suggest is like the Suggest function, using the strings p was created with
+// as the candidates
+func (p *Patterns) Suggest(word string) (string, bool) {
+	return Suggest(word, p.pats)
+}
+
+// DidYouMean returns the candidate found by Suggest formatted as `did you mean
+// "return"?`, ready to be appended to an error message, or the empty string if
+// there is none.
+func DidYouMean(word string, candidates []string) string {
+	if s, ok := Suggest(word, candidates); ok {
+		return fmt.Sprintf("did you mean %q?", s)
+	}
+	return ""
+}
+
+// Suggest sets the Diagnostic's Suggestion to the candidate found by Suggest,
+// and appends `did you mean "return"?` to its Message. If there's no
+// candidate the Diagnostic is left as it was. The Diagnostic is returned, so
+// that it can be passed straight to EmitDiagnostic.
+func (d *Diagnostic) Suggest(word string, candidates []string) *Diagnostic {
+	if s, ok := Suggest(word, candidates); ok {
+		d.Suggestion = s
+		d.Message += fmt.Sprintf(", did you mean %q?", s)
+	}
+	return d
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// comparing runes using foldRune
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	for i := range ra {
+		ra[i] = foldRune(ra[i])
+	}
+	for i := range rb {
+		rb[i] = foldRune(rb[i])
+	}
+
+	// Only the last three rows of the table are needed
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d := minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d = minInt(d, prev2[j-2]+1)
+			}
+			cur[j] = d
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
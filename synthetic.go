@@ -0,0 +1,78 @@
+package lexgo
+
+import (
+	"sort"
+)
+
+// offRange is a range of byte offsets, [start, end)
+type offRange struct {
+	start, end int
+}
+
+// MarkSynthetic marks the bytes of the input in the range [start, end) as
+// synthetic, meaning that they were injected by tooling (a prelude, wrapper
+// braces, etc...) rather than being part of the original document. Synthetic
+// bytes are read and lexed as normal, but are skipped over for the purpose of
+// position tracking, so that the rows, columns and offsets reported for
+// everything else line up with the original document. Tokens which consist
+// only of synthetic bytes are reported at the position of the rune preceding
+// them.
+//
+// start and end are the true offsets of the bytes in the input, regardless of
+// SetStartPosition or other ranges being marked synthetic. Ranges may not
+// overlap. MarkSynthetic must be called before the bytes in the range have
+// been read.
+func (l *Lexer) MarkSynthetic(start, end int) {
+	if end <= start {
+		return
+	}
+	i := sort.Search(len(l.synth), func(i int) bool {
+		return l.synth[i].start >= start
+	})
+	l.synth = append(l.synth, offRange{})
+	copy(l.synth[i+1:], l.synth[i:])
+	l.synth[i] = offRange{start, end}
+}
+
+// skipSynthetic is called when a rune of the given size is read. It returns
+// true if the rune falls within a range marked synthetic, in which case the
+// position shouldn't be advanced for it. It must be called for every rune
+// read, so that the true offset of the input is always known.
+func (l *Lexer) skipSynthetic(size int) bool {
+	off := l.inputOff
+	l.inputOff += size
+	if len(l.synth) == 0 {
+		return false
+	}
+	i := sort.Search(len(l.synth), func(i int) bool {
+		return l.synth[i].end > off
+	})
+	return i < len(l.synth) && l.synth[i].start <= off
+}
+
+// EmitSynthetic emits a Token with the given type and value which doesn't
+// consume any input, and has Synthetic set. It's intended for Tokens which the
+// input implies without containing, e.g. semicolons inserted at the end of a
+// line or the terminator of a block closed by dedenting.
+//
+// The output buffer is not affected. If anything is currently buffered the
+// synthetic Token is positioned at its start, since it will be emitted before
+// whatever is buffered, otherwise it's positioned at the next rune to be read.
+// Either way the Tokens in the stream remain in order of position. OnEmit
+// hooks are run on the Token as on any other, but the value isn't normalized
+// or folded.
+func (l *Lexer) EmitSynthetic(t TokenType, val string) {
+	tok := Token{TokenType: t, Val: l.internString(val), Synthetic: true}
+	if len(l.outbuf) > 0 {
+		tok.Row, tok.Col = l.reportPos(l.row, l.col)
+		tok.Offset = l.off
+	} else {
+		tok.Row, tok.Col, tok.Offset = l.nextPos()
+	}
+	for _, fn := range l.onEmit {
+		if !fn(&tok) {
+			return
+		}
+	}
+	l.queue = append(l.queue, tok)
+}
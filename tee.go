@@ -0,0 +1,99 @@
+//go:build !tinygo
+
+package lexgo
+
+import (
+	"sync"
+)
+
+// TokenTee duplicates the Tokens from a single Tokenizer to some number of
+// independent consumers, so that, for example, a highlighter and a parser can
+// consume the same lexing pass without lexing the input twice.
+//
+// Each consumer has its own bounded buffer of Tokens. When any consumer's
+// buffer is full reading from the source pauses until that consumer catches
+// up, so all consumers must be read from until the end of the stream to avoid
+// the others stalling. If they are to be abandoned all together instead then
+// Stop must be called.
+type TokenTee struct {
+	src       Tokenizer
+	startOnce sync.Once
+	consumers []*teeConsumer
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+type teeConsumer struct {
+	tee  *TokenTee
+	ch   chan Token
+	last *Token
+}
+
+// NewTokenTee returns a TokenTee which will duplicate the Tokens of src to n
+// consumers, each having a buffer which can hold bufSize Tokens. Reading from
+// src doesn't begin until the first consumer's Next method is called
+func NewTokenTee(src Tokenizer, n, bufSize int) *TokenTee {
+	t := &TokenTee{
+		src:       src,
+		consumers: make([]*teeConsumer, n),
+		stopCh:    make(chan struct{}),
+	}
+	for i := range t.consumers {
+		t.consumers[i] = &teeConsumer{
+			tee: t,
+			ch:  make(chan Token, bufSize),
+		}
+	}
+	return t
+}
+
+// Consumer returns the ith of the TokenTee's consumers, which will produce its
+// own copy of every Token produced by the source. i must be less than the n
+// given to NewTokenTee
+func (t *TokenTee) Consumer(i int) Tokenizer {
+	return t.consumers[i]
+}
+
+func (t *TokenTee) spin() {
+	for {
+		tok := t.src.Next()
+		cp := tok.Copy()
+		tok.Release()
+
+		for _, c := range t.consumers {
+			select {
+			case c.ch <- cp:
+			case <-t.stopCh:
+				return
+			}
+		}
+		if cp.EndsStream() {
+			return
+		}
+	}
+}
+
+// Stop causes the go-routine reading from the source to exit without reading
+// any further. It only needs to be called if the consumers won't all be read
+// from until the end of the stream. No consumer's Next may be called after
+// Stop.
+func (t *TokenTee) Stop() {
+	t.stopOnce.Do(func() { close(t.stopCh) })
+}
+
+// Next implements the method for Tokenizer. Once an Err Token has been
+// returned which ends the stream it will continue to be returned for all
+// subsequent calls
+func (c *teeConsumer) Next() *Token {
+	c.tee.startOnce.Do(func() { go c.tee.spin() })
+	if c.last != nil {
+		last := *c.last
+		return &last
+	}
+
+	tok := <-c.ch
+	if tok.EndsStream() {
+		c.last = &tok
+	}
+	return &tok
+}
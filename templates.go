@@ -0,0 +1,123 @@
+package lexgo
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WithErrorTemplate sets a message to be used in place of the generic one when
+// Expect fails while lexing a Token of the given TokenType, so that end users
+// see something like "unterminated string literal started at 1:5" rather than
+// `1:9: expected "\"", found end of input`. It may be given multiple times, for
+// different TokenTypes. Within the template:
+//
+//	{pos}       is replaced by the row:col the Token started at
+//	{text}      is replaced by the text buffered so far, quoted
+//	{expected}  is replaced by what was expected, e.g. a quoted string
+//	{found}     is replaced by what was found instead, a quoted rune or
+//	            "end of input"
+//
+// The resulting error is a *TemplateError, wrapped in a *PosError giving the
+// position at which the failure occurred.
+func WithErrorTemplate(t TokenType, tmpl string) Option {
+	return func(l *Lexer) {
+		if l.typeTemplates == nil {
+			l.typeTemplates = map[TokenType]string{}
+		}
+		l.typeTemplates[t] = tmpl
+	}
+}
+
+// WithStateErrorTemplate is like WithErrorTemplate, but sets the message used
+// for failures within the given LexerFunc: calls to Expect whose TokenType has
+// no template of its own, unexpected characters found by DispatchTable.Lex and
+// Operators, and ReadN reaching the end of the stream early.
+func WithStateErrorTemplate(state LexerFunc, tmpl string) Option {
+	return func(l *Lexer) {
+		if l.stateTemplates == nil {
+			l.stateTemplates = map[uintptr]string{}
+		}
+		l.stateTemplates[funcPC(state)] = tmpl
+	}
+}
+
+// TemplateError is emitted in place of the error a helper would otherwise have
+// emitted when an error template applies, see WithErrorTemplate
+type TemplateError struct {
+	// Message is the rendered template
+	Message string
+
+	// Err is the error which would otherwise have been emitted
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns Err
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Expect is like Match, except that if the upcoming input isn't s an error is
+// emitted, describing the failure in terms of the Token of the given TokenType
+// which was being lexed. The error is rendered using the template set for the
+// TokenType or current LexerFunc, if any, see WithErrorTemplate. Otherwise it
+// is of the form `expected "*/", found end of input`.
+func (l *Lexer) Expect(t TokenType, s string) bool {
+	if l.Match(s) {
+		return true
+	}
+
+	found := "end of input"
+	if r, err := l.peekRune(); err == io.EOF {
+		l.heldErr = nil
+	} else if err != nil {
+		// ReadRune will emit the error
+		l.ReadRune()
+		return false
+	} else {
+		found = strconv.QuoteRune(r)
+	}
+
+	expected := strconv.Quote(s)
+	row, col, off := l.nextPos()
+	l.EmitErr(&PosError{
+		Row:    row,
+		Col:    col,
+		Offset: off,
+		Err:    l.templated(&t, expected, found, fmt.Errorf("expected %s, found %s", expected, found)),
+	})
+	return false
+}
+
+// templated returns a TemplateError wrapping err, if there's a template for
+// the given TokenType (which may be nil) or current LexerFunc, or err itself
+// otherwise
+func (l *Lexer) templated(t *TokenType, expected, found string, err error) error {
+	tmpl, ok := "", false
+	if t != nil {
+		tmpl, ok = l.typeTemplates[*t]
+	}
+	if !ok && l.stateTemplates != nil && l.state != nil {
+		tmpl, ok = l.stateTemplates[funcPC(l.state)]
+	}
+	if !ok {
+		return err
+	}
+
+	row, col, _ := l.nextPos()
+	if l.row >= 0 && l.col >= 0 {
+		row, col = l.reportPos(l.row, l.col)
+	}
+	msg := strings.NewReplacer(
+		"{pos}", fmt.Sprintf("%d:%d", row, col),
+		"{text}", strconv.Quote(string(l.outbuf)),
+		"{expected}", expected,
+		"{found}", found,
+	).Replace(tmpl)
+	return &TemplateError{Message: msg, Err: err}
+}
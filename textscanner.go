@@ -0,0 +1,92 @@
+package lexgo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/scanner"
+)
+
+// TextScanner wraps a Tokenizer with an interface mimicking that of the
+// stdlib's text/scanner.Scanner, so that code written against text/scanner can
+// be migrated to a lexgo-based tokenizer incrementally.
+//
+// Scan returns the TokenType of each Token as a rune, rather than one of
+// text/scanner's token classes, with the exception of scanner.EOF which is
+// returned once the stream has ended
+type TextScanner struct {
+	// Start position of the most recently scanned token, as set by Scan. The
+	// Filename field is left as whatever it was set to by NewTextScanner. Only
+	// Line and Column are filled in.
+	scanner.Position
+
+	// Incremented for each error encountered, not including io.EOF
+	ErrorCount int
+
+	// Called for each error encountered, not including io.EOF. If nil the
+	// error is printed to os.Stderr, as with text/scanner
+	Error func(s *TextScanner, msg string)
+
+	t    Tokenizer
+	text string
+	done bool
+}
+
+// NewTextScanner returns a TextScanner reading Tokens from the given Tokenizer.
+// filename is used as the Filename field of Position
+func NewTextScanner(t Tokenizer, filename string) *TextScanner {
+	return &TextScanner{
+		Position: scanner.Position{Filename: filename},
+		t:        t,
+	}
+}
+
+// Scan reads the next Token from the Tokenizer and returns its TokenType as a
+// rune. If the stream has ended scanner.EOF is returned. If the stream ended
+// due to an error other than io.EOF then the error is reported via Error
+// first. Warning Tokens are skipped, and Recoverable Err Tokens are reported
+// via Error and then skipped.
+func (s *TextScanner) Scan() rune {
+	s.text = ""
+	if s.done {
+		return scanner.EOF
+	}
+
+	t := s.t.Next()
+	for (t.TokenType == Warning && t.Err == nil) || t.Recoverable {
+		// Warning's value would be mistaken for scanner.EOF
+		if t.Recoverable {
+			s.Line, s.Column = t.Row, t.Col
+			s.error(t.Err.Error())
+		}
+		t.Release()
+		t = s.t.Next()
+	}
+	defer t.Release()
+
+	s.Line, s.Column = t.Row, t.Col
+	if t.Err != nil {
+		s.done = true
+		if t.Err != io.EOF {
+			s.error(t.Err.Error())
+		}
+		return scanner.EOF
+	}
+
+	s.text = t.Val
+	return rune(t.TokenType)
+}
+
+func (s *TextScanner) error(msg string) {
+	s.ErrorCount++
+	if s.Error != nil {
+		s.Error(s, msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", s.Position, msg)
+}
+
+// TokenText returns the value of the most recently scanned Token
+func (s *TextScanner) TokenText() string {
+	return s.text
+}
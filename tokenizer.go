@@ -0,0 +1,101 @@
+package lexgo
+
+import (
+	"errors"
+	"io"
+)
+
+// Tokenizer describes anything which produces a stream of Tokens, such as a
+// Lexer. Parsers and tools should accept a Tokenizer rather than a *Lexer so
+// that any implementation can be used interchangeably.
+//
+// Next follows the same semantics as Lexer's Next method: the stream is
+// finished once a Token with a non-nil Err is returned, with io.EOF indicating
+// the stream ended normally. The exception is Err Tokens with Recoverable set,
+// after which the stream continues (see EndsStream)
+type Tokenizer interface {
+	Next() *Token
+}
+
+var _ Tokenizer = new(Lexer)
+
+// Tokens reads all Tokens from the given Tokenizer until an Err Token which
+// ends the stream is hit, and returns them. If the Err Token's error is io.EOF
+// then nil is returned as the error, otherwise the error is returned along with
+// whatever Tokens were read before it.
+//
+// Recoverable Err Tokens (see ResumeAfterError) are not included in the
+// returned Tokens, but their errors are joined into the returned error.
+func Tokens(t Tokenizer) ([]Token, error) {
+	var toks []Token
+	var errs []error
+	for {
+		tok := t.Next()
+		if tok.Recoverable {
+			errs = append(errs, tok.Err)
+			tok.Release()
+			continue
+		} else if tok.Err != nil {
+			if tok.Err != io.EOF {
+				errs = append(errs, tok.Err)
+			}
+			tok.Release()
+			if len(errs) == 1 {
+				// return the error as-is, so it can still be compared against
+				return toks, errs[0]
+			}
+			return toks, errors.Join(errs...)
+		}
+		cp := tok.Copy()
+		toks = append(toks, cp)
+		tok.Release()
+	}
+}
+
+// Run drives the Lexer to completion, calling fn with each Token it produces
+// until fn returns false or an Err Token which ends the stream is hit. The
+// error of the Err Token is returned, or nil if it was io.EOF or fn stopped the
+// run. Recoverable Err Tokens are passed to fn like any other Token.
+//
+// If the Lexer is using WithTokenPool then each Token is released once fn
+// returns, so fn must copy any Tokens it wishes to keep
+func (l *Lexer) Run(fn func(*Token) bool) error {
+	for {
+		tok := l.Next()
+		if tok.EndsStream() {
+			err := tok.Err
+			tok.Release()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		ok := fn(tok)
+		tok.Release()
+		if !ok {
+			return nil
+		}
+	}
+}
+
+type replay struct {
+	toks []Token
+}
+
+// Replay returns a Tokenizer which returns a copy of each of the given Tokens
+// in order, and then io.EOF Tokens forever after. This is useful for feeding a
+// stream which was previously collected (e.g. by Tokens) back into something
+// which wants a Tokenizer.
+func Replay(toks []Token) Tokenizer {
+	return &replay{toks: toks}
+}
+
+func (r *replay) Next() *Token {
+	if len(r.toks) == 0 {
+		return &Token{TokenType: Err, Err: io.EOF}
+	}
+	tok := r.toks[0].Copy()
+	r.toks = r.toks[1:]
+	return &tok
+}
@@ -0,0 +1,160 @@
+package lexgo
+
+// Cursor is the row/column state which a PositionTracker maintains on behalf
+// of a Lexer. Byte offsets are always tracked by the Lexer itself, and so
+// aren't included
+type Cursor struct {
+	// Row and Col are the position of the rune most recently read
+	Row, Col int
+
+	// NextCol is the column which the next rune read will be at, unless the
+	// most recent rune was a line break
+	NextCol int
+}
+
+// PositionTracker determines how a Lexer assigns rows and columns to the runes
+// it reads. Advance is called for every rune read, with the Cursor as it was
+// beforehand, the rune and its encoded size in bytes, and whether the Lexer
+// considers the rune to be a line break (see IsNewline). It returns the new
+// Cursor.
+//
+// Since all state is held in the Cursor, a PositionTracker may be shared
+// between any number of Lexers.
+type PositionTracker interface {
+	Advance(c Cursor, r rune, size int, newline bool) Cursor
+}
+
+// WithPositionTracker causes the Lexer to use the given PositionTracker,
+// rather than RuneColumns, to determine the rows and columns of Tokens
+func WithPositionTracker(t PositionTracker) Option {
+	return func(l *Lexer) {
+		l.tracker = t
+	}
+}
+
+// startCursor is the Cursor a Lexer starts out with, prior to any reading
+var startCursor = Cursor{Row: 1, Col: 0, NextCol: 1}
+
+type runeColumns struct{}
+
+// RuneColumns returns the default PositionTracker, which counts each rune as
+// one column, and each line break as the start of a new row
+func RuneColumns() PositionTracker {
+	return runeColumns{}
+}
+
+func (runeColumns) Advance(c Cursor, r rune, size int, newline bool) Cursor {
+	if newline {
+		return Cursor{Row: c.Row + 1, Col: 0, NextCol: 1}
+	}
+	return Cursor{Row: c.Row, Col: c.NextCol, NextCol: c.NextCol + 1}
+}
+
+type offsetsOnly struct{}
+
+// OffsetsOnly returns a PositionTracker which doesn't track rows or columns at
+// all, leaving them as zero, while still tracking the byte offset of each
+// Token. This is cheaper than the other PositionTrackers, and more useful than
+// NoPositions for consumers which only need to slice into the original input
+func OffsetsOnly() PositionTracker {
+	return offsetsOnly{}
+}
+
+func (offsetsOnly) Advance(c Cursor, r rune, size int, newline bool) Cursor {
+	return Cursor{}
+}
+
+type utf16Columns struct{}
+
+// UTF16Columns returns a PositionTracker which counts columns in UTF-16 code
+// units, such that runes outside the basic multilingual plane take up two
+// columns. This is the convention used by the Language Server Protocol and by
+// javascript
+func UTF16Columns() PositionTracker {
+	return utf16Columns{}
+}
+
+func (utf16Columns) Advance(c Cursor, r rune, size int, newline bool) Cursor {
+	if newline {
+		return Cursor{Row: c.Row + 1, Col: 0, NextCol: 1}
+	}
+	n := 1
+	if r > 0xffff {
+		// encoded as a surrogate pair
+		n = 2
+	}
+	return Cursor{Row: c.Row, Col: c.NextCol, NextCol: c.NextCol + n}
+}
+
+type tabColumns int
+
+// TabColumns returns a PositionTracker which is like RuneColumns, except that
+// a tab advances the column to the next tab stop, where tab stops are every
+// width columns. This matches the columns shown by most editors. width must
+// be greater than zero
+func TabColumns(width int) PositionTracker {
+	if width < 1 {
+		panic("lexgo: TabColumns width must be greater than zero")
+	}
+	return tabColumns(width)
+}
+
+func (w tabColumns) Advance(c Cursor, r rune, size int, newline bool) Cursor {
+	if newline {
+		return Cursor{Row: c.Row + 1, Col: 0, NextCol: 1}
+	}
+	next := c.NextCol + 1
+	if r == '\t' {
+		next = ((c.NextCol-1)/int(w)+1)*int(w) + 1
+	}
+	return Cursor{Row: c.Row, Col: c.NextCol, NextCol: next}
+}
+
+type byteColumns struct{}
+
+// ByteColumns returns a PositionTracker which counts columns in bytes, such
+// that each rune takes up as many columns as its encoded size. This is the
+// convention used by grep-style tools and many compilers
+func ByteColumns() PositionTracker {
+	return byteColumns{}
+}
+
+func (byteColumns) Advance(c Cursor, r rune, size int, newline bool) Cursor {
+	if newline {
+		return Cursor{Row: c.Row + 1, Col: 0, NextCol: 1}
+	}
+	return Cursor{Row: c.Row, Col: c.NextCol, NextCol: c.NextCol + size}
+}
+
+// ColumnUnit is a unit which columns can be counted in, see WithColumnUnit
+type ColumnUnit int
+
+// The ColumnUnits which can be given to WithColumnUnit
+const (
+	// ColumnRunes counts each rune as one column, see RuneColumns
+	ColumnRunes ColumnUnit = iota
+
+	// ColumnBytes counts each byte as one column, see ByteColumns
+	ColumnBytes
+
+	// ColumnUTF16 counts each UTF-16 code unit as one column, see
+	// UTF16Columns
+	ColumnUTF16
+)
+
+// WithColumnUnit sets the unit which the Lexer counts columns in, for the
+// positions of Tokens and errors alike, as well as for Snippet. It's shorthand
+// for WithPositionTracker with the corresponding PositionTracker, and so
+// replaces any given by an earlier option. The default is ColumnRunes.
+func WithColumnUnit(u ColumnUnit) Option {
+	return func(l *Lexer) {
+		switch u {
+		case ColumnBytes:
+			l.tracker = ByteColumns()
+		case ColumnUTF16:
+			l.tracker = UTF16Columns()
+		default:
+			l.tracker = RuneColumns()
+		}
+	}
+}
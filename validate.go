@@ -0,0 +1,139 @@
+package lexgo
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Validator collects information about how LexerFuncs transition between each
+// other while lexing, in order to surface problems in a lexer's state machine
+// which a test corpus might not otherwise reveal:
+//
+//   - States which are never reached, indicating either dead code or gaps in
+//     the corpus.
+//
+//   - Cycles of states which re-enter a state without having read any input,
+//     buffered anything or emitted any Tokens along the way. Such a cycle will
+//     repeat forever if nothing changes, and tends to indicate a livelock which
+//     the corpus only avoided by luck.
+//
+// A single Validator is intended to be shared by all Lexers created while
+// running a test corpus (see WithValidator), and is safe to do so
+// concurrently. States are identified by their underlying function, so all
+// closures created by the same function literal are considered the same state,
+// and states wrapped by combinators such as Then are seen as the combinator.
+type Validator struct {
+	l       sync.Mutex
+	states  map[uintptr]bool
+	reached map[uintptr]bool
+	stalls  map[string]bool
+}
+
+// NewValidator returns a Validator which will report on whether each of the
+// given states is reached
+func NewValidator(states ...LexerFunc) *Validator {
+	v := &Validator{
+		states:  map[uintptr]bool{},
+		reached: map[uintptr]bool{},
+		stalls:  map[string]bool{},
+	}
+	for _, fn := range states {
+		v.states[funcPC(fn)] = true
+	}
+	return v
+}
+
+// WithValidator causes the Lexer to report every state it runs to the given
+// Validator. This has a cost on every state transition, and so is intended for
+// tests rather than production use.
+func WithValidator(v *Validator) Option {
+	return func(l *Lexer) {
+		l.validator = v
+	}
+}
+
+// Unreached returns the names of all states given to NewValidator which haven't
+// been run by any Lexer so far, sorted
+func (v *Validator) Unreached() []string {
+	v.l.Lock()
+	defer v.l.Unlock()
+	var names []string
+	for pc := range v.states {
+		if !v.reached[pc] {
+			names = append(names, pcName(pc))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stalls returns a description of each distinct cycle of states seen which
+// re-entered a state without any progress being made, e.g. "lexA -> lexB ->
+// lexA", sorted
+func (v *Validator) Stalls() []string {
+	v.l.Lock()
+	defer v.l.Unlock()
+	stalls := make([]string, 0, len(v.stalls))
+	for s := range v.stalls {
+		stalls = append(stalls, s)
+	}
+	sort.Strings(stalls)
+	return stalls
+}
+
+// record is called by the Lexer with the trail of states started since it last
+// made progress, the last of which is about to be run
+func (v *Validator) record(trail []uintptr) {
+	last := trail[len(trail)-1]
+	v.l.Lock()
+	defer v.l.Unlock()
+	v.reached[last] = true
+	for i := len(trail) - 2; i >= 0; i-- {
+		if trail[i] != last {
+			continue
+		}
+		names := make([]string, 0, len(trail)-i)
+		for _, pc := range trail[i:] {
+			names = append(names, pcName(pc))
+		}
+		v.stalls[strings.Join(names, " -> ")] = true
+		return
+	}
+}
+
+// step runs the given state, reporting on it to the Validator if there is one,
+// and returns the next state. If the state is found to have stalled (see
+// WithStallLimit) then an error is emitted and nil is returned
+func (l *Lexer) step(fn LexerFunc) LexerFunc {
+	before := l.progress()
+	if l.validator != nil {
+		if before != l.trailAt || len(l.trail) == 0 {
+			l.trail, l.trailAt = l.trail[:0], before
+		}
+		l.trail = append(l.trail, funcPC(fn))
+		l.validator.record(l.trail)
+	}
+
+	var start time.Time
+	queued := len(l.queue)
+	if l.stateProf != nil {
+		start = time.Now()
+	}
+	next := l.runState(fn)
+	if l.stateProf != nil {
+		l.stateProf.record(funcPC(fn), time.Since(start), len(l.queue)-queued)
+	}
+	if l.stallLimit <= 0 {
+		return next
+	} else if l.progress() != before {
+		l.stalled = 0
+		return next
+	} else if l.stalled++; l.stalled < l.stallLimit {
+		return next
+	}
+
+	l.EmitErr(&StallError{State: pcName(funcPC(fn)), Steps: l.stalled})
+	return nil
+}
@@ -0,0 +1,56 @@
+package lexgo
+
+// EmitWarnings causes calls to EmitWarning to actually emit Warning Tokens.
+// Without this option warnings are discarded, so that consumers which don't
+// know about Warning Tokens never see them.
+func EmitWarnings() Option {
+	return func(l *Lexer) {
+		l.warnings = true
+	}
+}
+
+// EmitWarning is used to flag input which is suspicious, but not invalid, such
+// as a deprecated syntax or an ambiguous escape sequence. Unlike EmitErr this
+// doesn't end the stream: a Warning Token is emitted with its Warn field set to
+// the given error, and lexing continues as normal. This will not affect the
+// output buffer. The Token's position follows the same rules as for EmitErr.
+//
+// Warning Tokens are only emitted if the Lexer was constructed using
+// EmitWarnings, otherwise this does nothing
+func (l *Lexer) EmitWarning(err error) {
+	if !l.warnings {
+		return
+	}
+
+	row, col, off := l.errPos(err)
+	l.queue = append(l.queue, Token{
+		TokenType: Warning,
+		Row:       row,
+		Col:       col,
+		Offset:    off,
+		Warn:      err,
+	})
+}
+
+// warningFilter implements SplitWarnings
+type warningFilter struct {
+	Tokenizer
+	fn func(*Token)
+}
+
+// SplitWarnings returns a Tokenizer which returns all Tokens from t except for
+// Warning Tokens, which are instead passed to fn as they are encountered. This
+// allows consumers to handle warnings separately from the main Token stream.
+func SplitWarnings(t Tokenizer, fn func(*Token)) Tokenizer {
+	return &warningFilter{Tokenizer: t, fn: fn}
+}
+
+func (f *warningFilter) Next() *Token {
+	for {
+		tok := f.Tokenizer.Next()
+		if tok.TokenType != Warning || tok.Err != nil {
+			return tok
+		}
+		f.fn(tok)
+	}
+}
@@ -0,0 +1,55 @@
+package lexgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+const defaultStallLimit = 10000
+
+// StallError is emitted when a Lexer's states have run too many times in a
+// row without making any progress, see WithStallLimit
+type StallError struct {
+	// The name of the state which was run last
+	State string
+
+	// The number of consecutive states which were run without progress
+	Steps int
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("lexgo: %s made no progress after %d consecutive steps", e.State, e.Steps)
+}
+
+// WithStallLimit sets how many LexerFuncs may be run in a row without any of
+// them reading input, buffering anything or emitting a Token, before the Lexer
+// gives up. Without this a LexerFunc which keeps returning itself (or a cycle
+// of LexerFuncs which keep returning each other) without doing anything would
+// cause Next to spin forever. When the limit is hit a StallError naming the
+// LexerFunc run last is emitted, and lexing ends.
+//
+// The default is 10000, which no reasonable lexer should come close to. A limit
+// of zero or less disables the check.
+func WithStallLimit(n int) Option {
+	return func(l *Lexer) {
+		l.stallLimit = n
+	}
+}
+
+// ErrMaxSteps is emitted when a Lexer runs more LexerFuncs while producing a
+// single Token than was allowed by WithMaxSteps
+var ErrMaxSteps = errors.New("lexgo: too many steps taken to produce a token")
+
+// WithMaxSteps limits the number of LexerFuncs which may be run during a single
+// call to Next (or any of its variants). Since each call returns one Token this
+// bounds the CPU time which can be spent per Token, which is important when
+// the lexer isn't fully trusted, e.g. one built from rules supplied by users of
+// a service. When the limit is hit ErrMaxSteps is emitted, and lexing ends.
+//
+// Unlike WithStallLimit this counts every LexerFunc run, whether it made
+// progress or not. The default is no limit.
+func WithMaxSteps(n int) Option {
+	return func(l *Lexer) {
+		l.maxSteps = n
+	}
+}